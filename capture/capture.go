@@ -2,17 +2,17 @@ package capture
 
 import (
 	"context"
-	"encoding/json"
-	"encoding/xml"
 	"fmt"
 	"io"
 	"os/exec"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/p-vbordei/GoShark/packet"
-	"github.com/p-vbordei/GoShark/packet/layers"
+	"github.com/p-vbordei/GoShark/packet/consts"
 	"github.com/p-vbordei/GoShark/tshark"
+	"github.com/p-vbordei/GoShark/tshark/ek_field_mapping"
 )
 
 // Capture represents a base for different tshark capture types.
@@ -30,12 +30,48 @@ type Capture struct {
 	Promiscuous         bool
 	MonitorMode         bool
 	OutputFile          string
-	UseEK               bool // Use tshark's Elastic Common Schema (-T ek) output.
-	KeepPackets         bool // Retain packets passed through LoadPackets (pyshark keep_packets).
-	additionalArgs      []string
-
-	packets []*packet.Packet // Buffer populated by LoadPackets.
-	debug   bool             // When true, tshark stderr is logged.
+	TwoPass             bool   // Two-pass analysis (-2); see WithTwoPass.
+	ReadFilter          string // Read filter (-R), applied in the first pass.
+	UseEK               bool   // Use tshark's Elastic Common Schema (-T ek) output.
+	KeepPackets         bool   // Retain packets passed through LoadPackets (pyshark keep_packets).
+	// LazyLayers defers decoding JSON layer fields until a layer is accessed
+	// (see packet.DecoderOptions). Lazily decoded layers carry no JSONLayer.
+	LazyLayers bool
+	// DecodeWorkers is the number of goroutines decoding tshark output;
+	// <= 1 decodes on the reading goroutine. See WithDecodeWorkers.
+	DecodeWorkers int
+	// EKFieldMappings casts EK field values to their tshark-declared types;
+	// nil leaves them as emitted. See WithEKFieldTypes.
+	EKFieldMappings *ek_field_mapping.FieldMappings
+	// OnlyProtocols restricts JSON/PDML/EK output to these protocols and
+	// their fields (-J). OnlyFields restricts it to individual fields (-j).
+	OnlyProtocols []string
+	OnlyFields    []string
+	// EnableProtocols/DisableProtocols and EnableHeuristics/DisableHeuristics
+	// switch dissectors on or off, validated against the protocol registry.
+	EnableProtocols   []string
+	DisableProtocols  []string
+	EnableHeuristics  []string
+	DisableHeuristics []string
+	// DecodeAsRules are typed decode-as rules, validated against the protocol
+	// registry before tshark starts; see WithDecodeAs.
+	DecodeAsRules  []DecodeAs
+	additionalArgs []string
+
+	strictFilters bool             // Validate filters before starting tshark.
+	registry      *consts.Registry // Validates dissector names and decode-as rules; loaded from tshark if nil.
+
+	ekFieldTypes       bool     // Load EKFieldMappings from tshark on first use.
+	ekMappingProtocols []string // Protocols passed to --elastic-mapping-filter.
+
+	fields       []string     // -T fields columns; set by FieldsCapture while it starts.
+	fieldsFormat FieldsFormat // -E options for -T fields output.
+
+	store      PacketStore          // Retains packets passed through LoadPackets; nil until first use.
+	packetHook func(*packet.Packet) // Applied to each decoded packet, e.g. to renumber frames.
+	inputErr   func() error         // Reports what cut tshark's input short, checked once its output ends.
+	expert     *expertTally         // Expert info counts; see WithExpertSummary.
+	debug      bool                 // When true, tshark stderr is logged.
 
 	cmd        *exec.Cmd
 	dumpcapCmd *exec.Cmd // Upstream dumpcap process feeding tshark in a live capture; nil otherwise.
@@ -52,6 +88,8 @@ func getCapture(v interface{}) *Capture {
 	switch cap := v.(type) {
 	case *FileCapture:
 		return &cap.Capture
+	case *IndexedFileCapture:
+		return &cap.FileCapture.Capture
 	case *LiveCapture:
 		return cap.Capture
 	case *RemoteCapture:
@@ -64,6 +102,10 @@ func getCapture(v interface{}) *Capture {
 		}
 	case *InMemCapture:
 		return &cap.Capture
+	case *PipeCapture:
+		return cap.Capture
+	case *FieldsCapture:
+		return cap.Capture
 	}
 	return nil
 }
@@ -130,6 +172,92 @@ func WithUseEK(useEK bool) Option {
 	}
 }
 
+// WithEKFieldMappings casts every EK field value using the given mappings,
+// e.g. ones obtained from tshark.GetEKFieldMappings.
+func WithEKFieldMappings(mappings *ek_field_mapping.FieldMappings) Option {
+	return func(v interface{}) {
+		if c := getCapture(v); c != nil {
+			c.EKFieldMappings = mappings
+		}
+	}
+}
+
+// WithEKFieldTypes makes EK captures cast every field to the type tshark
+// declares for it in "tshark -G elastic-mapping" (ports become ints, flags
+// bools, and so on). The mapping is generated once per tshark version and
+// cached; listing protocols restricts it via --elastic-mapping-filter, which
+// keeps it small.
+func WithEKFieldTypes(protocols ...string) Option {
+	return func(v interface{}) {
+		if c := getCapture(v); c != nil {
+			c.ekFieldTypes = true
+			c.ekMappingProtocols = append(c.ekMappingProtocols, protocols...)
+		}
+	}
+}
+
+// WithOnlyProtocols restricts dissection output to the given protocols and
+// all of their fields (tshark's -J), shrinking output volume and parsing
+// cost when only a few layers are inspected. The frame layer is always kept
+// so FrameNumber and SniffTime keep working. For EK captures with
+// WithEKFieldTypes the protocols also restrict the elastic mapping.
+func WithOnlyProtocols(protocols ...string) Option {
+	return func(v interface{}) {
+		if c := getCapture(v); c != nil {
+			c.OnlyProtocols = append(c.OnlyProtocols, protocols...)
+		}
+	}
+}
+
+// WithOnlyFields restricts dissection output to individual fields (e.g.
+// "ip.src", "dns.qry.name") and the layers containing them (tshark's -j).
+// The frame metadata fields are always kept. As -j only keeps a layer whose
+// own name it is given, the frame layer and each field's protocol (the text
+// before its first '.') are added too. It cannot be combined with
+// WithOnlyProtocols, as tshark accepts only one protocol match filter.
+func WithOnlyFields(fields ...string) Option {
+	return func(v interface{}) {
+		if c := getCapture(v); c != nil {
+			c.OnlyFields = append(c.OnlyFields, fields...)
+		}
+	}
+}
+
+// frameMetaFields are the frame fields packets derive their metadata from.
+var frameMetaFields = []string{
+	"frame.number", "frame.len", "frame.cap_len", "frame.time_epoch", "frame.time", "frame.protocols",
+}
+
+// protocolMatchArgs returns the -j/-J arguments for OnlyFields/OnlyProtocols.
+func (c *Capture) protocolMatchArgs() ([]string, error) {
+	join := func(always, names []string) string {
+		seen := make(map[string]bool)
+		var out []string
+		for _, n := range append(append([]string(nil), always...), names...) {
+			if n != "" && !seen[n] {
+				seen[n] = true
+				out = append(out, n)
+			}
+		}
+		return strings.Join(out, " ")
+	}
+
+	switch {
+	case len(c.OnlyProtocols) > 0 && len(c.OnlyFields) > 0:
+		return nil, fmt.Errorf("WithOnlyProtocols and WithOnlyFields cannot be combined")
+	case len(c.OnlyProtocols) > 0:
+		return []string{"-J", join([]string{"frame"}, c.OnlyProtocols)}, nil
+	case len(c.OnlyFields) > 0:
+		var fields []string
+		for _, f := range c.OnlyFields {
+			protocol, _, _ := strings.Cut(f, ".")
+			fields = append(fields, protocol, f)
+		}
+		return []string{"-j", join(append([]string{"frame"}, frameMetaFields...), fields)}, nil
+	}
+	return nil, nil
+}
+
 // WithKeepPackets controls whether LoadPackets retains packets in memory for
 // later indexed access. Corresponds to pyshark's keep_packets (default true).
 func WithKeepPackets(keep bool) Option {
@@ -140,6 +268,17 @@ func WithKeepPackets(keep bool) Option {
 	}
 }
 
+// WithLazyLayers makes JSON captures decode each layer's fields only when
+// the layer is first accessed, so untouched layers cost almost nothing.
+// Callers reading Layer.Fields directly must call Packet.DecodeAll first.
+func WithLazyLayers(lazy bool) Option {
+	return func(v interface{}) {
+		if c := getCapture(v); c != nil {
+			c.LazyLayers = lazy
+		}
+	}
+}
+
 // WithIncludeRaw sets whether to include raw packet data in the output. (Note: tshark JSON often includes raw data by default).
 func WithIncludeRaw(includeRaw bool) Option {
 	return func(v interface{}) {
@@ -150,7 +289,8 @@ func WithIncludeRaw(includeRaw bool) Option {
 }
 
 // WithDecodes adds decode-as rules (e.g., "tcp.port==8888,http").
-// Corresponds to tshark's -d flag.
+// Corresponds to tshark's -d flag. Rules are passed to tshark unchecked; use
+// WithDecodeAs to have them validated before tshark starts.
 func WithDecodes(decodes ...string) Option {
 	return func(v interface{}) {
 		if c := getCapture(v); c != nil {
@@ -236,6 +376,10 @@ func (c *Capture) SetCommandLineArgs(args ...string) {
 
 // getTSharkArgs constructs the tshark command arguments based on the Capture configuration.
 func (c *Capture) getTSharkArgs() ([]string, error) {
+	if err := c.checkFilters(); err != nil {
+		return nil, err
+	}
+
 	args := []string{"-l", "-n"}
 
 	// Add any additional arguments
@@ -273,6 +417,9 @@ func (c *Capture) getTSharkArgs() ([]string, error) {
 	}
 
 	switch {
+	case len(c.fields) > 0:
+		// Only the requested fields, one row per packet (FieldsCapture).
+		args = append(args, c.fieldsFormat.args(c.fields)...)
 	case c.UseEK:
 		// Elastic Common Schema: newline-delimited JSON.
 		args = append(args, "-T", "ek")
@@ -284,10 +431,49 @@ func (c *Capture) getTSharkArgs() ([]string, error) {
 		args = append(args, "-T", "pdml")
 	}
 
-	for _, decode := range c.Decodes {
-		args = append(args, "-d", decode)
+	if len(c.fields) == 0 {
+		matchArgs, err := c.protocolMatchArgs()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, matchArgs...)
 	}
 
+	dissectionArgs, err := c.DissectionArgs()
+	if err != nil {
+		return nil, err
+	}
+	return append(args, dissectionArgs...), nil
+}
+
+// DissectionArgs returns the tshark arguments that control how packets are
+// dissected rather than captured or printed: two-pass mode and the read
+// filter, enabled and disabled dissectors, decode-as rules, decryption keys
+// and preferences. Tools that run tshark themselves, such as the stats
+// package, use it to share a capture's options.
+func (c *Capture) DissectionArgs() ([]string, error) {
+	var args []string
+
+	// tshark only accepts a read filter in two-pass mode.
+	if c.twoPass() {
+		args = append(args, "-2")
+	}
+	if c.ReadFilter != "" {
+		args = append(args, "-R", c.ReadFilter)
+	}
+
+	dissectorArgs, err := c.dissectorArgs()
+	if err != nil {
+		return nil, err
+	}
+	args = append(args, dissectorArgs...)
+
+	decodeArgs, err := c.decodeAsArgs()
+	if err != nil {
+		return nil, err
+	}
+	args = append(args, decodeArgs...)
+
 	for _, key := range c.EncryptionKeys {
 		args = append(args, "-o", "wlan.enable_decryption:TRUE", "-o", "wlan.wep_keys:"+key)
 	}
@@ -363,39 +549,77 @@ func (c *Capture) SetDebug(on bool) {
 }
 
 // LoadPackets eagerly captures up to count packets (count <= 0 means all) and,
-// when KeepPackets is set, buffers them for indexed access via Get/Len/Packets.
+// when KeepPackets is set, buffers them in the packet store for indexed access
+// via Get/Len/Packets. The returned slice holds the packets when the store is
+// the default MemoryStore; with other stores it is nil, so a bounded store
+// never has to hold the whole capture in memory.
 // startFunc launches the underlying tshark process (each capture type provides
 // its own); the concrete capture types expose a no-argument LoadPackets wrapper.
 func (c *Capture) LoadPackets(ctx context.Context, count int,
 	startFunc func() (io.ReadCloser, io.ReadCloser, error)) ([]*packet.Packet, error) {
-	c.packets = nil
+	store := c.Store()
+	if err := store.Reset(); err != nil {
+		return nil, err
+	}
 	n := 0
+	var storeErr error
 	err := c.ApplyOnPackets(func(p *packet.Packet) bool {
 		if c.KeepPackets {
-			c.packets = append(c.packets, p)
+			if storeErr = store.Add(p); storeErr != nil {
+				return true
+			}
 		}
 		n++
 		return count > 0 && n >= count
 	}, ctx, startFunc)
-	return c.packets, err
+	if err == nil && storeErr != nil {
+		err = fmt.Errorf("failed to store packet: %w", storeErr)
+	}
+	if mem, ok := store.(*MemoryStore); ok {
+		return mem.Packets(), err
+	}
+	return nil, err
+}
+
+// Store returns the packet store LoadPackets fills (see WithPacketStore).
+func (c *Capture) Store() PacketStore {
+	if c.store == nil {
+		c.store = NewMemoryStore()
+	}
+	return c.store
 }
 
 // Len returns the number of buffered packets (after LoadPackets).
 func (c *Capture) Len() int {
-	return len(c.packets)
+	return c.Store().Len()
 }
 
-// Get returns the i-th buffered packet, or nil if the index is out of range.
+// Get returns the i-th buffered packet, or nil if the index is out of range,
+// the packet was evicted or could not be reloaded. Use Store().Get for the
+// error.
 func (c *Capture) Get(i int) *packet.Packet {
-	if i < 0 || i >= len(c.packets) {
+	p, err := c.Store().Get(i)
+	if err != nil {
 		return nil
 	}
-	return c.packets[i]
+	return p
 }
 
-// Packets returns all buffered packets (after LoadPackets).
+// Packets returns all buffered packets still held by the store (after
+// LoadPackets). With a DiskStore this reloads every packet into memory;
+// prefer Get for large captures.
 func (c *Capture) Packets() []*packet.Packet {
-	return c.packets
+	store := c.Store()
+	if mem, ok := store.(*MemoryStore); ok {
+		return mem.Packets()
+	}
+	var pkts []*packet.Packet
+	for i := 0; i < store.Len(); i++ {
+		if p, err := store.Get(i); err == nil {
+			pkts = append(pkts, p)
+		}
+	}
+	return pkts
 }
 
 // Wait waits for the tshark command to finish.
@@ -406,8 +630,35 @@ func (c *Capture) Wait() error {
 	return c.cmd.Wait()
 }
 
+// ekMappings returns the mappings EK field values are cast with, loading
+// them from tshark on first use when WithEKFieldTypes was requested.
+func (c *Capture) ekMappings() (*ek_field_mapping.FieldMappings, error) {
+	if c.EKFieldMappings == nil && c.ekFieldTypes {
+		protocols := c.ekMappingProtocols
+		if len(protocols) == 0 && len(c.OnlyProtocols) > 0 {
+			protocols = append([]string{"frame"}, c.OnlyProtocols...)
+		}
+		mappings, err := tshark.GetEKFieldMappings(c.TSharkPath, protocols...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load EK field mappings: %w", err)
+		}
+		c.EKFieldMappings = mappings
+	}
+	return c.EKFieldMappings, nil
+}
+
 // sniffStream reads packets from stdout in a streaming fashion.
 func (c *Capture) sniffStream(ctx context.Context, stdout io.ReadCloser, stderr io.ReadCloser) (<-chan *packet.Packet, error) {
+	var ekMappings *ek_field_mapping.FieldMappings
+	if c.UseEK {
+		var err error
+		if ekMappings, err = c.ekMappings(); err != nil {
+			stdout.Close()
+			stderr.Close()
+			return nil, err
+		}
+	}
+
 	outChan := make(chan *packet.Packet, 100)
 	done := make(chan struct{})
 
@@ -426,106 +677,15 @@ func (c *Capture) sniffStream(ctx context.Context, stdout io.ReadCloser, stderr
 		defer close(outChan)
 		defer close(done)
 
-		if c.UseEK {
-			// EK output is newline-delimited JSON: one record per line,
-			// alternating {"index":...} metadata and packet records.
-			decoder := json.NewDecoder(stdout)
-			parser := tshark.NewEKParser(tshark.WithEKIncludeRaw(c.IncludeRaw))
-			for decoder.More() {
-				select {
-				case <-ctx.Done():
-					return
-				default:
-				}
-
-				var raw json.RawMessage
-				if err := decoder.Decode(&raw); err != nil {
-					return
-				}
-				pkt, ok, err := parser.ParseRecord(raw)
-				if err != nil {
-					return
-				}
-				if !ok {
-					continue // an {"index":...} metadata line
-				}
-
-				select {
-				case <-ctx.Done():
-					return
-				case outChan <- pkt:
-				}
-			}
-		} else if c.UseJSON {
-			decoder := json.NewDecoder(stdout)
-			// Read the first token which must be '['
-			t, err := decoder.Token()
-			if err != nil {
-				return
-			}
-			delim, ok := t.(json.Delim)
-			if !ok || delim != '[' {
-				return
-			}
-
-			// Read each packet object as it becomes available
-			for decoder.More() {
-				// Check for context cancellation
-				select {
-				case <-ctx.Done():
-					return
-				default:
-				}
-
-				var pkt packet.Packet
-				if err := decoder.Decode(&pkt); err != nil {
-					return
-				}
-				// Populate JSON layers
-				for i := range pkt.Layers {
-					pkt.Layers[i].JSONLayer = layers.NewJSONLayer(pkt.Layers[i].Name, pkt.Layers[i].Fields, pkt.Layers[i].Name, false)
-				}
-
-				select {
-				case <-ctx.Done():
-					return
-				case outChan <- &pkt:
-				}
-			}
-		} else {
-			// XML / PDML stream parsing
-			decoder := xml.NewDecoder(stdout)
-			for {
-				select {
-				case <-ctx.Done():
-					return
-				default:
-				}
-
-				t, err := decoder.Token()
-				if err != nil {
-					return
-				}
-
-				if se, ok := t.(xml.StartElement); ok && se.Name.Local == "packet" {
-					var pdmlPacket tshark.PDMLPacket
-					if err := decoder.DecodeElement(&pdmlPacket, &se); err != nil {
-						return
-					}
-
-					// Convert PDMLPacket to packet.Packet using XMLParser's logic
-					parser := tshark.NewXMLParser(tshark.WithXMLIncludeRaw(c.IncludeRaw))
-					pkt, err := parser.ConvertPDMLPacket(&pdmlPacket)
-					if err == nil {
-						select {
-						case <-ctx.Done():
-							return
-						case outChan <- pkt:
-						}
-					}
-				}
-			}
+		next, newDecoder, reusesChunks := c.streamFormat(stdout, ekMappings)
+		if c.packetHook != nil {
+			newDecoder = withPacketHook(newDecoder, c.packetHook)
+		}
+		if c.expert != nil {
+			c.expert.reset()
+			newDecoder = withPacketHook(newDecoder, c.expert.add)
 		}
+		decodeStream(ctx, next, newDecoder, reusesChunks, c.DecodeWorkers, outChan)
 	}()
 
 	return outChan, nil
@@ -558,6 +718,9 @@ func (c *Capture) ApplyOnPackets(callback func(*packet.Packet) bool, ctx context
 			return ctx.Err()
 		case pkt, ok := <-packets:
 			if !ok {
+				if c.inputErr != nil && ctx.Err() == nil {
+					return c.inputErr()
+				}
 				return nil
 			}
 			if callback(pkt) {
@@ -120,3 +120,29 @@ func TestEKTSharkArgs(t *testing.T) {
 	assert.NoError(t, err)
 	assert.True(t, containsPair(args, "-T", "ek"), "UseEK should select -T ek")
 }
+
+func TestOnlyProtocolsTSharkArgs(t *testing.T) {
+	cap := NewCapture(WithOnlyProtocols("ip", "dns", "frame"))
+	args, err := cap.getTSharkArgs()
+	assert.NoError(t, err)
+	assert.True(t, containsPair(args, "-J", "frame ip dns"), "OnlyProtocols should map to -J and keep frame once")
+
+	cap = NewCapture(WithOnlyFields("ip.src"))
+	args, err = cap.getTSharkArgs()
+	assert.NoError(t, err)
+	assert.True(t, containsPair(args, "-j",
+		"frame frame.number frame.len frame.cap_len frame.time_epoch frame.time frame.protocols ip ip.src"),
+		"OnlyFields should map to -j with the frame metadata fields and the layers holding them")
+
+	cap = NewCapture(WithOnlyFields("ip.src", "dns.qry.name", "ip.dst", "frame.len"))
+	args, err = cap.getTSharkArgs()
+	assert.NoError(t, err)
+	assert.True(t, containsPair(args, "-j",
+		"frame frame.number frame.len frame.cap_len frame.time_epoch frame.time frame.protocols "+
+			"ip ip.src dns dns.qry.name ip.dst"),
+		"each field's protocol is named once")
+
+	cap = NewCapture(WithOnlyProtocols("ip"), WithOnlyFields("dns.qry.name"))
+	_, err = cap.getTSharkArgs()
+	assert.Error(t, err, "-j and -J are mutually exclusive")
+}
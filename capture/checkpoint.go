@@ -0,0 +1,274 @@
+package capture
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/p-vbordei/GoShark/packet"
+	"github.com/p-vbordei/GoShark/pcapfile"
+)
+
+// Checkpoint records how far a file-processing job got, so a restarted job
+// can continue with WithResumeFrom instead of starting from frame 1.
+type Checkpoint struct {
+	FilePath string          `json:"file_path"`
+	Frame    int             `json:"frame"`           // Last fully processed frame number
+	Offset   int64           `json:"offset"`          // File offset just past that frame
+	State    json.RawMessage `json:"state,omitempty"` // Caller state, see CheckpointConfig.State
+	Saved    time.Time       `json:"saved"`
+}
+
+// LoadCheckpoint reads a checkpoint written by WithCheckpoint or Save.
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cp Checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("invalid checkpoint %s: %w", path, err)
+	}
+	return &cp, nil
+}
+
+// Save writes the checkpoint to path atomically, so a crash mid-write leaves
+// the previous checkpoint intact.
+func (cp *Checkpoint) Save(path string) error {
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// DecodeState unmarshals the caller state saved with the checkpoint into v.
+func (cp *Checkpoint) DecodeState(v interface{}) error {
+	if len(cp.State) == 0 {
+		return nil
+	}
+	return json.Unmarshal(cp.State, v)
+}
+
+// CheckpointConfig configures WithCheckpoint.
+type CheckpointConfig struct {
+	// Path is the checkpoint file, replaced on every save.
+	Path string
+	// EveryPackets saves after that many processed packets; Interval saves
+	// when that much time has passed since the last save. Either may be 0;
+	// a final checkpoint is always saved when processing ends.
+	EveryPackets int
+	Interval     time.Duration
+	// State, if set, returns caller state to save alongside the position
+	// (e.g. running totals). It is called on the callback's goroutine and
+	// must marshal to JSON.
+	State func() interface{}
+}
+
+// WithCheckpoint makes FileCapture.ApplyOnPackets periodically record the
+// last frame the callback has returned from, with its file offset and the
+// caller's state. A packet counts as processed once the callback returns.
+func WithCheckpoint(cfg CheckpointConfig) Option {
+	return func(v interface{}) {
+		if c, ok := v.(*FileCapture); ok {
+			c.checkpoint = &cfg
+		}
+	}
+}
+
+// WithResumeFrom restarts a FileCapture after the checkpoint's frame. The
+// earlier frames are skipped before dissection, never handed to tshark;
+// Start fails if the checkpoint was saved for another file (see
+// Checkpoint.FilePath; clear it to resume a moved file) or the file no
+// longer matches the checkpoint. Dissection
+// state that spans frames (TCP reassembly, conversations) starts afresh.
+func WithResumeFrom(cp *Checkpoint) Option {
+	return func(v interface{}) {
+		if c, ok := v.(*FileCapture); ok {
+			c.resume = cp
+		}
+	}
+}
+
+// checkResumeFile reports a checkpoint saved for a file other than the
+// capture's. Paths naming the same file match, as do equal absolute paths
+// when either file cannot be found.
+func (c *FileCapture) checkResumeFile() error {
+	cp := c.resume
+	if cp == nil || cp.FilePath == "" {
+		return nil
+	}
+	want, errWant := os.Stat(cp.FilePath)
+	have, errHave := os.Stat(c.FilePath)
+	if errWant == nil && errHave == nil {
+		if os.SameFile(want, have) {
+			return nil
+		}
+	} else if absPath(cp.FilePath) == absPath(c.FilePath) {
+		return nil
+	}
+	return fmt.Errorf("checkpoint is for %s, not %s", cp.FilePath, c.FilePath)
+}
+
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
+
+// frameEnds finds the file offset just past each processed frame by reading
+// the file a second time, behind tshark. Its memory stays constant however
+// many frames the feeder runs ahead, e.g. when a display filter drops most of
+// them. Reading starts after the n frames skipped when resuming, which end
+// at offset end, behind the preamble those frames left.
+type frameEnds struct {
+	mu       sync.Mutex
+	path     string
+	preamble []byte
+	n        int   // Frames read
+	end      int64 // File offset just past frame n
+
+	f      *os.File
+	r      *pcapfile.Reader
+	adjust int64 // File offset of offset 0 in r
+	err    error
+}
+
+// endOf returns the offset just past file frame n. Frames are processed in
+// order, so n is never before the last frame asked for.
+func (e *frameEnds) endOf(n int) (int64, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.r == nil && e.err == nil {
+		e.err = e.open()
+	}
+	for e.err == nil && e.n < n {
+		rec, err := e.r.Next()
+		if err != nil {
+			e.err = fmt.Errorf("failed to locate frame %d in %s: %w", n, e.path, err)
+			break
+		}
+		if rec.IsFrame {
+			e.n++
+			e.end = e.adjust + rec.Frame.Offset + rec.Frame.Size
+		}
+	}
+	return e.end, e.err == nil && e.n == n
+}
+
+func (e *frameEnds) open() error {
+	f, err := os.Open(e.path)
+	if err != nil {
+		return fmt.Errorf("error opening PCAP file %s: %w", e.path, err)
+	}
+	if _, err := f.Seek(e.end, io.SeekStart); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to read %s: %w", e.path, err)
+	}
+	r, err := pcapfile.NewReader(io.MultiReader(bytes.NewReader(e.preamble), f))
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to read %s: %w", e.path, err)
+	}
+	e.f, e.r, e.adjust = f, r, e.end-int64(len(e.preamble))
+	return nil
+}
+
+// close releases the file and returns the first error locating a frame.
+func (e *frameEnds) close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.f != nil {
+		e.f.Close()
+		e.f, e.r = nil, nil
+	}
+	return e.err
+}
+
+// checkpointed wraps callback to record checkpoints. finish saves the final
+// checkpoint and merges a save failure into the processing error.
+func (c *FileCapture) checkpointed(callback func(*packet.Packet) bool) (func(*packet.Packet) bool, func(error) error) {
+	cfg := c.checkpoint
+	if cfg == nil {
+		return callback, func(err error) error { return err }
+	}
+
+	var last *Checkpoint
+	var saveErr error
+	processed := 0
+	lastSave := time.Now()
+	save := func() {
+		if last == nil {
+			return
+		}
+		if cfg.State != nil {
+			state, err := json.Marshal(cfg.State())
+			if err != nil {
+				saveErr = fmt.Errorf("failed to marshal checkpoint state: %w", err)
+				return
+			}
+			last.State = state
+		}
+		last.Saved = time.Now()
+		if err := last.Save(cfg.Path); err != nil {
+			saveErr = fmt.Errorf("failed to save checkpoint: %w", err)
+			return
+		}
+		lastSave = last.Saved
+	}
+
+	wrapped := func(p *packet.Packet) bool {
+		stop := callback(p)
+		n, err := strconv.Atoi(p.FrameNumber)
+		if err != nil || c.fed == nil || c.fed.ends == nil {
+			return stop
+		}
+		end, ok := c.fed.ends.endOf(n)
+		if !ok {
+			return stop
+		}
+		last = &Checkpoint{FilePath: c.FilePath, Frame: n, Offset: end}
+		processed++
+		if (cfg.EveryPackets > 0 && processed%cfg.EveryPackets == 0) ||
+			(cfg.Interval > 0 && time.Since(lastSave) >= cfg.Interval) {
+			save()
+		}
+		return stop
+	}
+	finish := func(err error) error {
+		save()
+		if err == nil {
+			err = saveErr
+		}
+		if c.fed != nil && c.fed.ends != nil {
+			if endErr := c.fed.ends.close(); err == nil && endErr != nil {
+				err = fmt.Errorf("failed to checkpoint: %w", endErr)
+			}
+		}
+		return err
+	}
+	return wrapped, finish
+}
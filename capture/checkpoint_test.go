@@ -0,0 +1,183 @@
+package capture
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/p-vbordei/GoShark/packet"
+	"github.com/p-vbordei/GoShark/pcapfile"
+)
+
+func TestCheckpointSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "job.checkpoint")
+	cp := &Checkpoint{FilePath: "big.pcap", Frame: 42, Offset: 4096, State: []byte(`{"bytes":1500}`)}
+	require.NoError(t, cp.Save(path))
+
+	loaded, err := LoadCheckpoint(path)
+	require.NoError(t, err)
+	assert.Equal(t, 42, loaded.Frame)
+	assert.EqualValues(t, 4096, loaded.Offset)
+	var state struct{ Bytes int }
+	require.NoError(t, loaded.DecodeState(&state))
+	assert.Equal(t, 1500, state.Bytes)
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "no temporary files left behind")
+
+	_, err = LoadCheckpoint(filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+}
+
+func TestSkipToCheckpoint(t *testing.T) {
+	ix, err := pcapfile.BuildIndex(testPcap)
+	require.NoError(t, err)
+	third := ix.Frames[2]
+
+	skip := func(cp *Checkpoint) (int, []byte, error) {
+		f, err := os.Open(testPcap)
+		require.NoError(t, err)
+		defer f.Close()
+		r, err := pcapfile.NewReader(f)
+		require.NoError(t, err)
+		var preamble bytes.Buffer
+		n, end, err := skipToCheckpoint(&preamble, r, cp)
+		if err == nil {
+			assert.EqualValues(t, ix.Frames[n-1].Offset+ix.Frames[n-1].Size, end)
+		}
+		return n, preamble.Bytes(), err
+	}
+
+	n, preamble, err := skip(&Checkpoint{Frame: 3, Offset: third.Offset + third.Size})
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.EqualValues(t, ix.Frames[0].Offset, len(preamble), "preamble is the blocks before frame 1")
+
+	_, _, err = skip(&Checkpoint{Frame: 3, Offset: third.Offset})
+	assert.ErrorContains(t, err, "file has changed")
+	_, _, err = skip(&Checkpoint{Frame: 9})
+	assert.ErrorContains(t, err, "file has 5 frames")
+}
+
+func TestCheckpointedCallback(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cp.json")
+	total := 0
+	ix, err := pcapfile.BuildIndex(testPcap)
+	require.NoError(t, err)
+	fc := &FileCapture{FilePath: testPcap, checkpoint: &CheckpointConfig{
+		Path:         path,
+		EveryPackets: 2,
+		State:        func() interface{} { return map[string]int{"total": total} },
+	}}
+	fc.fed = &frameNumbers{ends: &frameEnds{path: testPcap}}
+
+	callback, finish := fc.checkpointed(func(p *packet.Packet) bool {
+		total++
+		return false
+	})
+	for n := 1; n <= 3; n++ {
+		callback(&packet.Packet{FrameNumber: fmt.Sprint(n)})
+	}
+	cp, err := LoadCheckpoint(path)
+	require.NoError(t, err)
+	assert.Equal(t, 2, cp.Frame, "saved every 2 packets")
+	assert.JSONEq(t, `{"total":2}`, string(cp.State))
+
+	require.NoError(t, finish(nil))
+	cp, err = LoadCheckpoint(path)
+	require.NoError(t, err)
+	assert.Equal(t, 3, cp.Frame, "final checkpoint on finish")
+	assert.EqualValues(t, ix.Frames[2].Offset+ix.Frames[2].Size, cp.Offset)
+	assert.Equal(t, testPcap, cp.FilePath)
+	assert.JSONEq(t, `{"total":3}`, string(cp.State))
+}
+
+func TestFrameEnds(t *testing.T) {
+	ix, err := pcapfile.BuildIndex(testPcap)
+	require.NoError(t, err)
+	endOf := func(i int) int64 { return ix.Frames[i-1].Offset + ix.Frames[i-1].Size }
+
+	e := &frameEnds{path: testPcap}
+	end, ok := e.endOf(2)
+	assert.True(t, ok)
+	assert.Equal(t, endOf(2), end)
+	end, ok = e.endOf(4)
+	assert.True(t, ok)
+	assert.Equal(t, endOf(4), end)
+	_, ok = e.endOf(3)
+	assert.False(t, ok, "earlier frames are passed")
+	_, ok = e.endOf(10)
+	assert.False(t, ok)
+	assert.ErrorContains(t, e.close(), "failed to locate frame 10")
+
+	// Resuming reads on from the skipped frames, behind their preamble.
+	f, err := os.Open(testPcap)
+	require.NoError(t, err)
+	defer f.Close()
+	r, err := pcapfile.NewReader(f)
+	require.NoError(t, err)
+	var preamble bytes.Buffer
+	n, skippedEnd, err := skipToCheckpoint(&preamble, r, &Checkpoint{Frame: 2})
+	require.NoError(t, err)
+	e = &frameEnds{path: testPcap, preamble: preamble.Bytes(), n: n, end: skippedEnd}
+	end, ok = e.endOf(4)
+	assert.True(t, ok)
+	assert.Equal(t, endOf(4), end)
+	end, ok = e.endOf(5)
+	assert.True(t, ok)
+	assert.Equal(t, endOf(5), end)
+	assert.NoError(t, e.close())
+}
+
+func TestResumeFromAnotherFile(t *testing.T) {
+	other := filepath.Join(t.TempDir(), "other.pcap")
+	data, err := os.ReadFile(testPcap)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(other, data, 0o644))
+
+	fc, err := NewFileCapture(testPcap, WithResumeFrom(&Checkpoint{FilePath: other, Frame: 1}))
+	require.NoError(t, err)
+	_, _, err = fc.Start()
+	assert.ErrorContains(t, err, "checkpoint is for "+other)
+
+	fc.resume.FilePath = filepath.Join(t.TempDir(), "moved.pcap")
+	_, _, err = fc.Start()
+	assert.ErrorContains(t, err, "checkpoint is for", "missing files compare by path")
+
+	abs, err := filepath.Abs(testPcap)
+	require.NoError(t, err)
+	fc.resume.FilePath = abs
+	assert.NoError(t, fc.checkResumeFile(), "paths naming the same file match")
+	fc.resume.FilePath = ""
+	assert.NoError(t, fc.checkResumeFile(), "no recorded path skips the check")
+}
+
+func TestCheckpointResumeIntegration(t *testing.T) {
+	requireTShark(t)
+	path := filepath.Join(t.TempDir(), "cp.json")
+
+	fc, err := NewFileCapture(testPcap, WithCheckpoint(CheckpointConfig{Path: path, EveryPackets: 1}))
+	require.NoError(t, err)
+	err = fc.ApplyOnPackets(func(p *packet.Packet) bool {
+		return p.FrameNumber == "3" // simulate the job dying after frame 3
+	}, context.Background())
+	require.NoError(t, err)
+
+	cp, err := LoadCheckpoint(path)
+	require.NoError(t, err)
+	require.Equal(t, 3, cp.Frame)
+
+	resumed, err := NewFileCapture(testPcap, WithResumeFrom(cp))
+	require.NoError(t, err)
+	pkts := collect(t, resumed)
+	require.Len(t, pkts, 2)
+	assert.Equal(t, "4", pkts[0].FrameNumber)
+	assert.Equal(t, "5", pkts[1].FrameNumber)
+}
@@ -0,0 +1,179 @@
+package capture
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/p-vbordei/GoShark/packet/consts"
+)
+
+// DecodeAs is a decode-as rule: traffic whose Table field matches Selector
+// is dissected as Protocol (tshark's -d "<table>==<selector>,<protocol>").
+//
+// For integer tables (ports, protocol numbers) Selector is a value ("8888"),
+// an inclusive range ("8888-8890") or a start and count ("8888:3"); several
+// of these may be given separated by commas ("8080,8443"). String tables take
+// a single string.
+type DecodeAs struct {
+	Table    string // Dissector table, e.g. "tcp.port"
+	Selector string
+	Protocol string // Protocol filter name, e.g. "http"
+}
+
+// String returns the rule in the form accepted by WithDecodes.
+func (d DecodeAs) String() string {
+	return d.Table + "==" + d.Selector + "," + d.Protocol
+}
+
+// ParseDecodeAs parses a "<table>==<selector>,<protocol>" rule.
+func ParseDecodeAs(rule string) (DecodeAs, error) {
+	table, rest, ok := strings.Cut(rule, "==")
+	comma := strings.LastIndex(rest, ",")
+	if !ok || comma < 0 {
+		return DecodeAs{}, fmt.Errorf("invalid decode-as rule %q: want <table>==<selector>,<protocol>", rule)
+	}
+	d := DecodeAs{
+		Table:    strings.TrimSpace(table),
+		Selector: strings.TrimSpace(rest[:comma]),
+		Protocol: strings.TrimSpace(rest[comma+1:]),
+	}
+	if d.Table == "" || d.Selector == "" || d.Protocol == "" {
+		return DecodeAs{}, fmt.Errorf("invalid decode-as rule %q: want <table>==<selector>,<protocol>", rule)
+	}
+	return d, nil
+}
+
+// WithDecodeAs adds typed decode-as rules. Unlike the raw rules of
+// WithDecodes, they are checked against the protocol registry (tshark -G
+// dissector-tables, -G protocols and -G decodes) before tshark starts.
+func WithDecodeAs(rules ...DecodeAs) Option {
+	return func(v interface{}) {
+		if c := getCapture(v); c != nil {
+			c.DecodeAsRules = append(c.DecodeAsRules, rules...)
+		}
+	}
+}
+
+// decodeAsArgs returns the -d arguments of the raw and typed decode-as rules.
+// Typed rules are validated and given one -d per selector; the registry is
+// only loaded when there are typed rules.
+func (c *Capture) decodeAsArgs() ([]string, error) {
+	var args []string
+	for _, rule := range c.Decodes {
+		args = append(args, "-d", rule)
+	}
+	if len(c.DecodeAsRules) == 0 {
+		return args, nil
+	}
+	registry, err := c.protocolRegistry()
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range c.DecodeAsRules {
+		selectors, err := d.validate(registry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid decode-as rule %q: %w", d.String(), err)
+		}
+		for _, selector := range selectors {
+			args = append(args, "-d", d.Table+"=="+selector+","+d.Protocol)
+		}
+	}
+	return args, nil
+}
+
+// validate checks the rule against the registry's dissector tables and
+// protocols and returns its individual selectors. The protocol need not be
+// registered in the table: -G decodes only lists default registrations, and
+// decoding as some other protocol is what decode-as is for.
+func (d DecodeAs) validate(registry *consts.Registry) ([]string, error) {
+	table, ok := registry.Table(d.Table)
+	if !ok {
+		return nil, fmt.Errorf("unknown dissector table %q", d.Table)
+	}
+	if !table.DecodeAs {
+		return nil, fmt.Errorf("dissector table %q does not support decode-as", d.Table)
+	}
+	if !registry.HasProtocol(strings.ToLower(d.Protocol)) {
+		return nil, fmt.Errorf("unknown protocol %q%s", d.Protocol, registeredHint(registry, d.Table))
+	}
+
+	bits, integer := integerTableBits(table.Type)
+	if !integer {
+		return []string{d.Selector}, nil
+	}
+	var selectors []string
+	for _, s := range strings.Split(d.Selector, ",") {
+		s = strings.TrimSpace(s)
+		if err := checkIntegerSelector(s, bits); err != nil {
+			return nil, fmt.Errorf("selector %q for %s (%s): %w", s, d.Table, table.Type, err)
+		}
+		selectors = append(selectors, s)
+	}
+	return selectors, nil
+}
+
+// integerTableBits returns the width of an integer dissector table type such
+// as "FT_UINT16".
+func integerTableBits(ftype string) (int, bool) {
+	for _, prefix := range []string{"FT_UINT", "FT_INT"} {
+		if rest, ok := strings.CutPrefix(ftype, prefix); ok {
+			if bits, err := strconv.Atoi(rest); err == nil {
+				return bits, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// checkIntegerSelector checks a value, "first-last" range or "first:count"
+// selector. Values may be decimal, 0x hex or 0 octal, as tshark accepts.
+func checkIntegerSelector(s string, bits int) error {
+	if first, last, ok := strings.Cut(s, "-"); ok {
+		lo, err := strconv.ParseUint(first, 0, bits)
+		if err != nil {
+			return err
+		}
+		hi, err := strconv.ParseUint(last, 0, bits)
+		if err != nil {
+			return err
+		}
+		if hi < lo {
+			return fmt.Errorf("range ends before it starts")
+		}
+		return nil
+	}
+	if first, count, ok := strings.Cut(s, ":"); ok {
+		lo, err := strconv.ParseUint(first, 0, bits)
+		if err != nil {
+			return err
+		}
+		n, err := strconv.ParseUint(count, 10, 64)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return fmt.Errorf("range count must be at least 1")
+		}
+		if bits < 64 && lo+n-1 >= 1<<bits {
+			return fmt.Errorf("range exceeds %d bits", bits)
+		}
+		return nil
+	}
+	_, err := strconv.ParseUint(s, 0, bits)
+	return err
+}
+
+// registeredHint lists a few protocols registered in table, for errors.
+func registeredHint(registry *consts.Registry, table string) string {
+	protocols := append([]string(nil), registry.ProtocolsIn(table)...)
+	if len(protocols) == 0 {
+		return ""
+	}
+	sort.Strings(protocols)
+	if len(protocols) > 8 {
+		protocols = append(protocols[:8], "...")
+	}
+	return fmt.Sprintf(" (protocols registered in %s: %s)", table, strings.Join(protocols, ", "))
+}
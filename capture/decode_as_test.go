@@ -0,0 +1,79 @@
+package capture
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDecodeAs(t *testing.T) {
+	d, err := ParseDecodeAs("tcp.port==8080,8443,http")
+	require.NoError(t, err)
+	assert.Equal(t, DecodeAs{Table: "tcp.port", Selector: "8080,8443", Protocol: "http"}, d)
+	assert.Equal(t, "tcp.port==8080,8443,http", d.String())
+
+	for _, bad := range []string{"tcp.port=8080,http", "tcp.port==8080", "==8080,http", "tcp.port==,http"} {
+		_, err := ParseDecodeAs(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestDecodeAsTSharkArgs(t *testing.T) {
+	registry := testDissectorRegistry(t)
+	require.NoError(t, registry.LoadDissectorTables(strings.NewReader(
+		"tcp.port\tTCP port\tFT_UINT16\tBASE_PT_TCP\tTCP\tDecode As supported\n"+
+			"ip.proto\tIP protocol\tFT_UINT8\tBASE_DEC\tIPv4\tDecode As not supported\n"+
+			"media_type\tInternet media type\tFT_STRING\tSTR_ASCII\tMedia\tDecode As supported\n")))
+	require.NoError(t, registry.LoadDecodes(strings.NewReader(
+		"tcp.port\t80\thttp\n"+
+			"tcp.port\t1883\tmqtt\n")))
+
+	require.NoError(t, registry.LoadDissectorTables(strings.NewReader(
+		"ethertype\tEthertype\tFT_UINT16\tBASE_HEX\tEthernet\tDecode As supported\n")))
+
+	cap := NewCapture(
+		WithProtocolRegistry(registry),
+		WithDecodes("tcp.port==8888,http"),
+		WithDecodeAs(
+			DecodeAs{Table: "tcp.port", Selector: "1880-1885, 9000:4", Protocol: "mqtt"},
+			DecodeAs{Table: "tcp.port", Selector: "5353", Protocol: "DNS"},
+			DecodeAs{Table: "media_type", Selector: "application/x-dns", Protocol: "dns"},
+			DecodeAs{Table: "ethertype", Selector: "0x8847", Protocol: "mqtt"},
+		),
+	)
+	args, err := cap.getTSharkArgs()
+	require.NoError(t, err)
+	assert.True(t, containsPair(args, "-d", "tcp.port==8888,http"))
+	assert.True(t, containsPair(args, "-d", "tcp.port==1880-1885,mqtt"), "one -d per selector")
+	assert.True(t, containsPair(args, "-d", "tcp.port==9000:4,mqtt"))
+	assert.True(t, containsPair(args, "-d", "tcp.port==5353,DNS"), "protocols without a default registration in the table are accepted")
+	assert.True(t, containsPair(args, "-d", "media_type==application/x-dns,dns"))
+	assert.True(t, containsPair(args, "-d", "ethertype==0x8847,mqtt"), "hex selectors are accepted")
+
+	for rule, want := range map[string]string{
+		"tcp.prt==8888,http":         "unknown dissector table",
+		"ip.proto==6,http":           "does not support decode-as",
+		"tcp.port==8888,htp":         "protocols registered in tcp.port: http, mqtt",
+		"tcp.port==70000,http":       "out of range",
+		"tcp.port==0x10000,http":     "out of range",
+		"tcp.port==8890-8888,http":   "range ends before it starts",
+		"tcp.port==65535:2,http":     "range exceeds 16 bits",
+		"tcp.port==http,http":        "invalid syntax",
+		"ethertype==0x8847-0x10,dns": "range ends before it starts",
+	} {
+		d, err := ParseDecodeAs(rule)
+		require.NoError(t, err, rule)
+		_, err = NewCapture(WithProtocolRegistry(registry), WithDecodeAs(d)).getTSharkArgs()
+		assert.ErrorContains(t, err, want, rule)
+	}
+}
+
+func TestDecodesPassThrough(t *testing.T) {
+	// Raw rules must not load the registry, which would run tshark.
+	cap := NewCapture(WithTSharkPath("/nonexistent/tshark"), WithDecodes("tcp.prt==8888,htp"))
+	args, err := cap.DissectionArgs()
+	require.NoError(t, err)
+	assert.True(t, containsPair(args, "-d", "tcp.prt==8888,htp"))
+}
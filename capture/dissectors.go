@@ -0,0 +1,117 @@
+package capture
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/p-vbordei/GoShark/packet/consts"
+	"github.com/p-vbordei/GoShark/tshark"
+)
+
+// WithEnableProtocols enables dissectors that are disabled in the tshark
+// profile (--enable-protocol). Names are protocol filter names, e.g. "mqtt".
+func WithEnableProtocols(protocols ...string) Option {
+	return func(v interface{}) {
+		if c := getCapture(v); c != nil {
+			c.EnableProtocols = append(c.EnableProtocols, protocols...)
+		}
+	}
+}
+
+// WithDisableProtocols switches dissectors off (--disable-protocol). Payload
+// a disabled dissector would have decoded is shown as data, and protocols
+// carried inside it are not dissected either, which makes decoding faster
+// when only lower layers are of interest.
+func WithDisableProtocols(protocols ...string) Option {
+	return func(v interface{}) {
+		if c := getCapture(v); c != nil {
+			c.DisableProtocols = append(c.DisableProtocols, protocols...)
+		}
+	}
+}
+
+// WithEnableHeuristics enables heuristic dissectors (--enable-heuristic), so
+// that protocols on non-standard ports are still recognised. Names are
+// heuristic short names, e.g. "tls_tcp", as listed by
+// "tshark -G heuristic-decodes".
+func WithEnableHeuristics(heuristics ...string) Option {
+	return func(v interface{}) {
+		if c := getCapture(v); c != nil {
+			c.EnableHeuristics = append(c.EnableHeuristics, heuristics...)
+		}
+	}
+}
+
+// WithDisableHeuristics disables heuristic dissectors (--disable-heuristic).
+func WithDisableHeuristics(heuristics ...string) Option {
+	return func(v interface{}) {
+		if c := getCapture(v); c != nil {
+			c.DisableHeuristics = append(c.DisableHeuristics, heuristics...)
+		}
+	}
+}
+
+// WithProtocolRegistry sets the registry dissector names and decode-as rules
+// are validated against, instead of loading it from tshark (see
+// tshark.LoadProtocolRegistry).
+func WithProtocolRegistry(registry *consts.Registry) Option {
+	return func(v interface{}) {
+		if c := getCapture(v); c != nil {
+			c.registry = registry
+		}
+	}
+}
+
+// dissectorArgs returns the --enable/--disable-protocol and -heuristic
+// arguments, after checking every name against the protocol registry so a
+// typo fails up front instead of being silently ignored by tshark.
+func (c *Capture) dissectorArgs() ([]string, error) {
+	if len(c.EnableProtocols)+len(c.DisableProtocols)+len(c.EnableHeuristics)+len(c.DisableHeuristics) == 0 {
+		return nil, nil
+	}
+	registry, err := c.protocolRegistry()
+	if err != nil {
+		return nil, err
+	}
+
+	var args, unknown []string
+	protocols := func(flag string, names []string) {
+		for _, name := range names {
+			if !registry.HasProtocol(strings.ToLower(name)) {
+				unknown = append(unknown, "protocol "+name)
+				continue
+			}
+			args = append(args, flag, name)
+		}
+	}
+	heuristics := func(flag string, names []string) {
+		for _, name := range names {
+			if _, ok := registry.Heuristic(name); !ok {
+				unknown = append(unknown, "heuristic "+name)
+				continue
+			}
+			args = append(args, flag, name)
+		}
+	}
+	protocols("--enable-protocol", c.EnableProtocols)
+	protocols("--disable-protocol", c.DisableProtocols)
+	heuristics("--enable-heuristic", c.EnableHeuristics)
+	heuristics("--disable-heuristic", c.DisableHeuristics)
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("unknown dissectors: %s", strings.Join(unknown, ", "))
+	}
+	return args, nil
+}
+
+// protocolRegistry returns the registry set with WithProtocolRegistry,
+// loading it from tshark on first use.
+func (c *Capture) protocolRegistry() (*consts.Registry, error) {
+	if c.registry == nil {
+		registry, err := tshark.LoadProtocolRegistry(c.TSharkPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load protocol registry: %w", err)
+		}
+		c.registry = registry
+	}
+	return c.registry, nil
+}
@@ -0,0 +1,54 @@
+package capture
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/p-vbordei/GoShark/packet/consts"
+)
+
+func testDissectorRegistry(t *testing.T) *consts.Registry {
+	t.Helper()
+	r := consts.NewRegistry()
+	require.NoError(t, r.LoadProtocols(strings.NewReader(
+		"Domain Name System\tDNS\tdns\n"+
+			"MQ Telemetry Transport Protocol\tMQTT\tmqtt\n"+
+			"Hypertext Transfer Protocol\tHTTP\thttp\n")))
+	require.NoError(t, r.LoadHeuristics(strings.NewReader(
+		"tcp\ttls_tcp\tF\n"+
+			"udp\tstun_udp\tT\n")))
+	return r
+}
+
+func TestDissectorTSharkArgs(t *testing.T) {
+	cap := NewCapture(
+		WithProtocolRegistry(testDissectorRegistry(t)),
+		WithEnableProtocols("mqtt"),
+		WithDisableProtocols("HTTP", "dns"),
+		WithEnableHeuristics("tls_tcp"),
+		WithDisableHeuristics("stun_udp"),
+	)
+	args, err := cap.getTSharkArgs()
+	require.NoError(t, err)
+	assert.True(t, containsPair(args, "--enable-protocol", "mqtt"))
+	assert.True(t, containsPair(args, "--disable-protocol", "HTTP"), "protocol names are case-insensitive")
+	assert.True(t, containsPair(args, "--disable-protocol", "dns"))
+	assert.True(t, containsPair(args, "--enable-heuristic", "tls_tcp"))
+	assert.True(t, containsPair(args, "--disable-heuristic", "stun_udp"))
+}
+
+func TestDissectorUnknownNames(t *testing.T) {
+	cap := NewCapture(
+		WithProtocolRegistry(testDissectorRegistry(t)),
+		WithDisableProtocols("dns", "nosuchproto"),
+		WithEnableHeuristics("tls"),
+	)
+	_, err := cap.getTSharkArgs()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "protocol nosuchproto")
+	assert.Contains(t, err.Error(), "heuristic tls")
+	assert.NotContains(t, err.Error(), "dns")
+}
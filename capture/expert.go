@@ -0,0 +1,79 @@
+package capture
+
+import (
+	"sync"
+
+	"github.com/p-vbordei/GoShark/packet"
+)
+
+// ExpertSummary counts the expert info of the packets a capture decoded,
+// like the totals of Wireshark's Expert Information dialog.
+type ExpertSummary struct {
+	Counts    map[packet.ExpertSeverity]int // Entries per severity.
+	Packets   int                           // Packets with at least one entry.
+	Malformed int                           // Malformed packets.
+}
+
+// WithExpertSummary makes the capture count expert info entries by severity
+// as packets are decoded; read the totals with ExpertSummary. Each run of
+// the capture starts a new count. Counting decodes every layer, so it
+// cancels most of the saving of WithLazyLayers.
+func WithExpertSummary() Option {
+	return func(v interface{}) {
+		if c := getCapture(v); c != nil {
+			c.expert = &expertTally{}
+		}
+	}
+}
+
+// ExpertSummary returns the expert info counts of the current or last run.
+// It is empty unless the capture was created with WithExpertSummary.
+func (c *Capture) ExpertSummary() ExpertSummary {
+	if c.expert == nil {
+		return ExpertSummary{Counts: map[packet.ExpertSeverity]int{}}
+	}
+	return c.expert.summary()
+}
+
+// expertTally accumulates an ExpertSummary. Decode workers add to it
+// concurrently.
+type expertTally struct {
+	mu  sync.Mutex
+	sum ExpertSummary
+}
+
+func (t *expertTally) reset() {
+	t.mu.Lock()
+	t.sum = ExpertSummary{Counts: make(map[packet.ExpertSeverity]int)}
+	t.mu.Unlock()
+}
+
+func (t *expertTally) add(p *packet.Packet) {
+	infos := p.ExpertInfo()
+	malformed := p.IsMalformed()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.sum.Counts == nil {
+		t.sum.Counts = make(map[packet.ExpertSeverity]int)
+	}
+	for _, info := range infos {
+		t.sum.Counts[info.Severity]++
+	}
+	if len(infos) > 0 {
+		t.sum.Packets++
+	}
+	if malformed {
+		t.sum.Malformed++
+	}
+}
+
+func (t *expertTally) summary() ExpertSummary {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s := t.sum
+	s.Counts = make(map[packet.ExpertSeverity]int, len(t.sum.Counts))
+	for sev, n := range t.sum.Counts {
+		s.Counts[sev] = n
+	}
+	return s
+}
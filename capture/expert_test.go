@@ -0,0 +1,52 @@
+package capture
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/p-vbordei/GoShark/packet"
+)
+
+const expertStream = `[
+{"_source":{"layers":{"frame":{"frame.number":"1"},"tcp":{"tcp.analysis":{"tcp.analysis.flags_tree":{"_ws.expert":{"tcp.analysis.retransmission":"","_ws.expert.message":"retransmission","_ws.expert.severity":"6291456","_ws.expert.group":"33554432"}}}}}}},
+{"_source":{"layers":{"frame":{"frame.number":"2"},"udp":{"udp.srcport":"53"}}}},
+{"_source":{"layers":{"frame":{"frame.number":"3"},"dns":{"dns.id":"0x0001"},"_ws.malformed":{"_ws.expert":{"_ws.malformed.expert":"","_ws.expert.message":"Malformed Packet","_ws.expert.severity":"8388608","_ws.expert.group":"117440512"}}}}}
+]
+`
+
+func TestExpertSummary(t *testing.T) {
+	c := NewCapture(WithExpertSummary(), WithDecodeWorkers(2))
+	for run := 0; run < 2; run++ {
+		pkts, err := c.sniffStream(context.Background(),
+			io.NopCloser(strings.NewReader(expertStream)), io.NopCloser(strings.NewReader("")))
+		require.NoError(t, err)
+		n := 0
+		for range pkts {
+			n++
+		}
+		require.Equal(t, 3, n)
+
+		sum := c.ExpertSummary()
+		assert.Equal(t, map[packet.ExpertSeverity]int{packet.SeverityWarning: 1, packet.SeverityError: 1}, sum.Counts,
+			"run %d: counts start afresh each run", run)
+		assert.Equal(t, 2, sum.Packets)
+		assert.Equal(t, 1, sum.Malformed)
+	}
+}
+
+func TestExpertSummaryDisabled(t *testing.T) {
+	c := NewCapture()
+	pkts, err := c.sniffStream(context.Background(),
+		io.NopCloser(strings.NewReader(expertStream)), io.NopCloser(strings.NewReader("")))
+	require.NoError(t, err)
+	for range pkts {
+	}
+	sum := c.ExpertSummary()
+	assert.Empty(t, sum.Counts)
+	assert.Zero(t, sum.Packets)
+}
@@ -0,0 +1,78 @@
+package capture
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/p-vbordei/GoShark/tshark"
+)
+
+// Export PDU tap names accepted by ExportPDUs. Dissectors export PDUs to the
+// tap of their layer, e.g. reassembled TCP payloads to ExportPDULayer4 and
+// SIP or Diameter messages to ExportPDULayer7.
+const (
+	ExportPDULayer3 = "OSI layer 3"
+	ExportPDULayer4 = "OSI layer 4"
+	ExportPDULayer7 = "OSI layer 7"
+)
+
+// ExportPDUs writes the PDUs dissectors export to tap (tshark's -U) into a
+// new pcapng file at outputFile, e.g. only the reassembled HTTP messages
+// with ExportPDULayer4 and WithDisplayFilter("http"). Each exported packet
+// holds one PDU, tagged with the dissector that produced it, so other tools
+// can read it without reassembling the original traffic.
+//
+// The display filter, read filter and dissection options apply. Frame and
+// time windows do not; ExportPDUs returns an error if one is set.
+func (c *FileCapture) ExportPDUs(ctx context.Context, tap, outputFile string) error {
+	args, err := c.exportPDUArgs(tap, outputFile)
+	if err != nil {
+		return err
+	}
+	tsharkPath, err := tshark.GetTSharkPath(c.TSharkPath)
+	if err != nil {
+		return err
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, tsharkPath, args...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("failed to export PDUs: %w: %s", err, msg)
+		}
+		return fmt.Errorf("failed to export PDUs: %w", err)
+	}
+	return nil
+}
+
+func (c *FileCapture) exportPDUArgs(tap, outputFile string) ([]string, error) {
+	if tap == "" {
+		return nil, fmt.Errorf("export PDU tap name cannot be empty")
+	}
+	if outputFile == "" {
+		return nil, fmt.Errorf("output file cannot be empty for PDU export")
+	}
+	if c.window.isSet() || c.resume != nil {
+		return nil, fmt.Errorf("PDU export does not support frame windows or resuming")
+	}
+	if err := c.checkFilters(); err != nil {
+		return nil, err
+	}
+	dissectionArgs, err := c.DissectionArgs()
+	if err != nil {
+		return nil, err
+	}
+
+	args := append([]string{"-n", "-r", c.FilePath}, dissectionArgs...)
+	if c.DisplayFilter != "" {
+		args = append(args, "-Y", c.DisplayFilter)
+	}
+	return append(args, "-U", tap, "-F", "pcapng", "-w", outputFile), nil
+}
@@ -0,0 +1,52 @@
+package capture
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportPDUArgs(t *testing.T) {
+	fc, err := NewFileCapture("../test.pcap",
+		WithDisplayFilter("http"),
+		WithReadFilter("tcp"),
+		WithOverridePreferences("tcp.desegment_tcp_streams:TRUE"))
+	require.NoError(t, err)
+
+	args, err := fc.exportPDUArgs(ExportPDULayer4, "out.pcapng")
+	require.NoError(t, err)
+	assert.True(t, containsPair(args, "-r", "../test.pcap"))
+	assert.True(t, containsPair(args, "-Y", "http"))
+	assert.True(t, containsPair(args, "-R", "tcp"))
+	assert.True(t, containsPair(args, "-o", "tcp.desegment_tcp_streams:TRUE"))
+	assert.True(t, containsPair(args, "-U", "OSI layer 4"))
+	assert.True(t, containsPair(args, "-F", "pcapng"))
+	assert.True(t, containsPair(args, "-w", "out.pcapng"))
+	assert.NotContains(t, args, "-T", "no packets are printed")
+}
+
+func TestExportPDUArgsErrors(t *testing.T) {
+	fc, err := NewFileCapture("../test.pcap")
+	require.NoError(t, err)
+	_, err = fc.exportPDUArgs("", "out.pcapng")
+	assert.ErrorContains(t, err, "tap name")
+	_, err = fc.exportPDUArgs(ExportPDULayer7, "")
+	assert.ErrorContains(t, err, "output file")
+
+	fc, err = NewFileCapture("../test.pcap", WithFrameRange(2, 3))
+	require.NoError(t, err)
+	_, err = fc.exportPDUArgs(ExportPDULayer7, "out.pcapng")
+	assert.ErrorContains(t, err, "frame windows")
+}
+
+func TestExportPDUs(t *testing.T) {
+	requireTShark(t)
+	fc, err := NewFileCapture("../test.pcap")
+	require.NoError(t, err)
+	out := filepath.Join(t.TempDir(), "pdus.pcapng")
+	require.NoError(t, fc.ExportPDUs(context.Background(), ExportPDULayer4, out))
+	assert.FileExists(t, out)
+}
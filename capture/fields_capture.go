@@ -0,0 +1,493 @@
+package capture
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldsFormat controls how tshark renders rows in -T fields mode
+// (its -E options). The zero value selects tab-separated, double-quoted
+// values with every occurrence of a field joined by commas.
+type FieldsFormat struct {
+	Separator  rune   // -E separator; default '\t'
+	Quote      rune   // -E quote: '"', '\'' or -1 for none; default '"'
+	Occurrence string // -E occurrence: "a" (all, default), "f" (first) or "l" (last)
+	Aggregator rune   // -E aggregator; default ','
+}
+
+// withDefaults fills unset members with tshark-compatible defaults.
+func (f FieldsFormat) withDefaults() FieldsFormat {
+	if f.Separator == 0 {
+		f.Separator = '\t'
+	}
+	if f.Quote == 0 {
+		f.Quote = '"'
+	}
+	if f.Occurrence == "" {
+		f.Occurrence = "a"
+	}
+	if f.Aggregator == 0 {
+		f.Aggregator = ','
+	}
+	return f
+}
+
+// fieldsCharArg renders a separator/aggregator for tshark's -E, which uses
+// "/t" and "/s" for tab and space.
+func fieldsCharArg(r rune) string {
+	switch r {
+	case '\t':
+		return "/t"
+	case ' ':
+		return "/s"
+	default:
+		return string(r)
+	}
+}
+
+// args returns the -T fields arguments for the given fields.
+func (f FieldsFormat) args(fields []string) []string {
+	f = f.withDefaults()
+	args := []string{"-T", "fields"}
+	for _, field := range fields {
+		args = append(args, "-e", field)
+	}
+	quote := "n"
+	switch f.Quote {
+	case '"':
+		quote = "d"
+	case '\'':
+		quote = "s"
+	}
+	return append(args,
+		"-E", "header=n",
+		"-E", "separator="+fieldsCharArg(f.Separator),
+		"-E", "quote="+quote,
+		"-E", "occurrence="+f.Occurrence,
+		"-E", "aggregator="+fieldsCharArg(f.Aggregator),
+	)
+}
+
+// WithFieldsFormat sets the -E row format used by FieldsCapture.
+func WithFieldsFormat(format FieldsFormat) Option {
+	return func(v interface{}) {
+		if c := getCapture(v); c != nil {
+			c.fieldsFormat = format
+		}
+	}
+}
+
+// FieldsRow is one packet of a FieldsCapture: the values of the requested
+// fields, in request order. A field that is absent from the packet is empty;
+// one that occurs several times holds every occurrence joined by the
+// aggregator (see Values).
+type FieldsRow struct {
+	fields     []string
+	index      map[string]int
+	values     []string
+	aggregator string
+}
+
+// Fields returns the field names of the row, in request order.
+func (r *FieldsRow) Fields() []string {
+	return r.fields
+}
+
+// Raw returns the raw column values, in request order.
+func (r *FieldsRow) Raw() []string {
+	return r.values
+}
+
+// Has reports whether the field has a value in this packet.
+func (r *FieldsRow) Has(field string) bool {
+	return r.String(field) != ""
+}
+
+// String returns the raw value of a field, or "" if the field is absent or
+// was not requested.
+func (r *FieldsRow) String(field string) string {
+	i, ok := r.index[field]
+	if !ok || i >= len(r.values) {
+		return ""
+	}
+	return r.values[i]
+}
+
+// Values returns every occurrence of a field. tshark joins occurrences with
+// the aggregator without quoting them, so a value that itself contains the
+// aggregator, such as an HTTP header with commas, is split apart as well;
+// read such fields with String, or pick an aggregator that cannot occur in
+// their values.
+func (r *FieldsRow) Values(field string) []string {
+	s := r.String(field)
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, r.aggregator)
+}
+
+// first returns the first occurrence of a field.
+func (r *FieldsRow) first(field string) (string, error) {
+	values := r.Values(field)
+	if len(values) == 0 {
+		return "", fmt.Errorf("field %s not present", field)
+	}
+	return values[0], nil
+}
+
+// Int returns the first occurrence of a field as an integer. Hexadecimal
+// values such as tcp.flags ("0x0018") are accepted.
+func (r *FieldsRow) Int(field string) (int64, error) {
+	s, err := r.first(field)
+	if err != nil {
+		return 0, err
+	}
+	return parseFieldInt(s)
+}
+
+// Float returns the first occurrence of a field as a float.
+func (r *FieldsRow) Float(field string) (float64, error) {
+	s, err := r.first(field)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// Bool returns the first occurrence of a field as a boolean. tshark renders
+// booleans as "1"/"0" or "True"/"False" depending on the field.
+func (r *FieldsRow) Bool(field string) (bool, error) {
+	s, err := r.first(field)
+	if err != nil {
+		return false, err
+	}
+	return parseFieldBool(s)
+}
+
+// Time returns the first occurrence of a field as a time. Epoch seconds
+// (frame.time_epoch) and RFC 3339 values are accepted.
+func (r *FieldsRow) Time(field string) (time.Time, error) {
+	s, err := r.first(field)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return parseFieldTime(s)
+}
+
+func parseFieldInt(s string) (int64, error) {
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		v, err := strconv.ParseUint(s[2:], 16, 64)
+		return int64(v), err
+	}
+	return strconv.ParseInt(s, 10, 64)
+}
+
+func parseFieldBool(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "1", "true":
+		return true, nil
+	case "0", "false":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid boolean value %q", s)
+}
+
+func parseFieldTime(s string) (time.Time, error) {
+	if secs, err := strconv.ParseFloat(s, 64); err == nil {
+		whole := int64(secs)
+		return time.Unix(whole, int64((secs-float64(whole))*1e9)), nil
+	}
+	return time.Parse(time.RFC3339Nano, s)
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Decode fills the struct pointed to by v from the row. Struct fields are
+// matched by tag, e.g. `shark:"ip.src"`; untagged fields and fields tagged
+// "-" are skipped, as are absent values. Supported field types are string,
+// bool, integers, floats, time.Time and slices of those (one element per
+// occurrence).
+func (r *FieldsRow) Decode(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("decode target must be a non-nil struct pointer, got %T", v)
+	}
+	rv = rv.Elem()
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		name := sf.Tag.Get("shark")
+		if name == "" || name == "-" || !sf.IsExported() {
+			continue
+		}
+		values := r.Values(name)
+		if len(values) == 0 {
+			continue
+		}
+		fv := rv.Field(i)
+		if fv.Kind() == reflect.Slice {
+			slice := reflect.MakeSlice(fv.Type(), len(values), len(values))
+			for j, s := range values {
+				if err := setFieldValue(slice.Index(j), s); err != nil {
+					return fmt.Errorf("field %s: %w", name, err)
+				}
+			}
+			fv.Set(slice)
+			continue
+		}
+		if err := setFieldValue(fv, values[0]); err != nil {
+			return fmt.Errorf("field %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// setFieldValue converts s into the type of v and stores it.
+func setFieldValue(v reflect.Value, s string) error {
+	if v.Type() == timeType {
+		t, err := parseFieldTime(s)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := parseFieldBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := parseFieldInt(s)
+		if err != nil {
+			return err
+		}
+		if v.OverflowInt(n) {
+			return fmt.Errorf("value %s overflows %s", s, v.Type())
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := parseFieldInt(s)
+		if err != nil {
+			return err
+		}
+		if n < 0 || v.OverflowUint(uint64(n)) {
+			return fmt.Errorf("value %s overflows %s", s, v.Type())
+		}
+		v.SetUint(uint64(n))
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// FieldsCapture runs a capture in tshark's -T fields mode: instead of the
+// full dissection tree, tshark prints only the requested fields, one row per
+// packet. Skipping JSON/PDML decoding makes this the fast path for bulk
+// analytics over large files. It wraps any capture type (file, pipe, live,
+// remote), so filters and other options apply unchanged.
+type FieldsCapture struct {
+	*Capture
+	columns []string // The requested fields, set on Capture only while tshark starts.
+	start   func() (io.ReadCloser, io.ReadCloser, error)
+}
+
+// NewFieldsCapture creates a fields-mode capture over source, which must be
+// a *FileCapture, *PipeCapture, *LiveCapture, *LiveRingCapture or
+// *RemoteCapture. Options are applied to the source capture. The source
+// stays in its own output mode, so it can still be used on its own, but not
+// while the fields capture is starting.
+func NewFieldsCapture(source interface{}, fields []string, options ...Option) (*FieldsCapture, error) {
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("fields capture requires at least one field")
+	}
+	starter, ok := source.(interface {
+		Start() (io.ReadCloser, io.ReadCloser, error)
+	})
+	c := getCapture(source)
+	if !ok || c == nil {
+		return nil, fmt.Errorf("unsupported capture type %T for fields capture", source)
+	}
+	for _, option := range options {
+		option(source)
+	}
+	return &FieldsCapture{Capture: c, columns: append([]string(nil), fields...), start: starter.Start}, nil
+}
+
+// inFieldsMode runs f with the fields set on the source capture.
+func (fc *FieldsCapture) inFieldsMode(f func()) {
+	fc.Capture.fields = fc.columns
+	defer func() { fc.Capture.fields = nil }()
+	f()
+}
+
+// getTSharkArgs returns the tshark arguments of the fields run.
+func (fc *FieldsCapture) getTSharkArgs() (args []string, err error) {
+	fc.inFieldsMode(func() { args, err = fc.Capture.getTSharkArgs() })
+	return args, err
+}
+
+// Start launches tshark and returns its stdout and stderr.
+func (fc *FieldsCapture) Start() (stdout io.ReadCloser, stderr io.ReadCloser, err error) {
+	fc.inFieldsMode(func() { stdout, stderr, err = fc.start() })
+	return stdout, stderr, err
+}
+
+// ApplyOnRows streams rows to the callback. If the callback returns true,
+// capturing stops early.
+func (fc *FieldsCapture) ApplyOnRows(callback func(*FieldsRow) bool, ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stdout, stderr, err := fc.Start()
+	if err != nil {
+		return err
+	}
+
+	var rows <-chan *FieldsRow
+	var errc <-chan error
+	fc.inFieldsMode(func() { rows, errc = fc.sniffRows(ctx, stdout, stderr) })
+	for row := range rows {
+		if callback(row) {
+			fc.Stop()
+			return nil
+		}
+	}
+	if err := <-errc; err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// LoadRows reads up to count rows (count <= 0 means all).
+func (fc *FieldsCapture) LoadRows(ctx context.Context, count int) ([]*FieldsRow, error) {
+	var rows []*FieldsRow
+	err := fc.ApplyOnRows(func(r *FieldsRow) bool {
+		rows = append(rows, r)
+		return count > 0 && len(rows) >= count
+	}, ctx)
+	return rows, err
+}
+
+// sniffRows parses -T fields output from stdout. The error channel yields
+// one value (nil on a clean end of input) after the row channel is closed.
+func (c *Capture) sniffRows(ctx context.Context, stdout io.ReadCloser, stderr io.ReadCloser) (<-chan *FieldsRow, <-chan error) {
+	format := c.fieldsFormat.withDefaults()
+	fields := c.fields
+	index := make(map[string]int, len(fields))
+	for i, f := range fields {
+		index[f] = i
+	}
+	aggregator := string(format.Aggregator)
+
+	out := make(chan *FieldsRow, 100)
+	errc := make(chan error, 1)
+	done := make(chan struct{})
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			stdout.Close()
+			stderr.Close()
+		case <-done:
+		}
+	}()
+
+	go func() {
+		defer stdout.Close()
+		defer stderr.Close()
+		defer close(errc)
+		defer close(out)
+		defer close(done)
+
+		next := fieldsRecordReader(stdout, format)
+		for {
+			values, err := next()
+			if err == io.EOF {
+				errc <- nil
+				return
+			}
+			if err != nil {
+				if ctx.Err() == nil {
+					errc <- fmt.Errorf("failed to parse fields output: %w", err)
+				}
+				return
+			}
+			row := &FieldsRow{fields: fields, index: index, values: values, aggregator: aggregator}
+			select {
+			case <-ctx.Done():
+				return
+			case out <- row:
+			}
+		}
+	}()
+
+	return out, errc
+}
+
+// fieldsRecordReader returns a function yielding one record per line of
+// -T fields output. tshark prints an absent field as nothing rather than as
+// an empty quoted string, so a packet lacking every requested field is an
+// empty line; each line is read on its own to keep rows aligned with
+// packets. Double-quoted lines are split as CSV so separators inside values
+// survive; otherwise lines are split on the separator, since values may
+// then contain stray quote characters.
+func fieldsRecordReader(r io.Reader, format FieldsFormat) func() ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	sep := string(format.Separator)
+	var quote string
+	if format.Quote > 0 {
+		quote = string(format.Quote)
+	}
+	return func() ([]string, error) {
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil {
+				return nil, err
+			}
+			return nil, io.EOF
+		}
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if format.Quote == '"' {
+			return splitQuotedFields(line, format.Separator)
+		}
+		values := strings.Split(line, sep)
+		if quote != "" {
+			for i, v := range values {
+				if len(v) >= 2 && strings.HasPrefix(v, quote) && strings.HasSuffix(v, quote) {
+					values[i] = v[1 : len(v)-1]
+				}
+			}
+		}
+		return values, nil
+	}
+}
+
+// splitQuotedFields splits one line of double-quoted output.
+func splitQuotedFields(line string, sep rune) ([]string, error) {
+	if line == "" {
+		return []string{""}, nil
+	}
+	cr := csv.NewReader(strings.NewReader(line))
+	cr.Comma = sep
+	cr.LazyQuotes = true
+	cr.FieldsPerRecord = -1
+	return cr.Read()
+}
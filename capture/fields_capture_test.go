@@ -0,0 +1,156 @@
+package capture
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFieldsCaptureArgs(t *testing.T) {
+	source := NewPipeCapture(strings.NewReader(""), WithUseJSON(true))
+	fc, err := NewFieldsCapture(source,
+		[]string{"ip.src", "tcp.port"},
+		WithDisplayFilter("tcp"),
+		WithFieldsFormat(FieldsFormat{Occurrence: "f"}))
+	require.NoError(t, err)
+
+	args, err := fc.getTSharkArgs()
+	require.NoError(t, err)
+	joined := strings.Join(args, " ")
+	assert.Contains(t, joined, "-Y tcp")
+	assert.Contains(t, joined, "-T fields -e ip.src -e tcp.port")
+	assert.Contains(t, joined, "-E separator=/t -E quote=d -E occurrence=f -E aggregator=,")
+	assert.NotContains(t, joined, "-T json")
+
+	args, err = source.getTSharkArgs()
+	require.NoError(t, err)
+	joined = strings.Join(args, " ")
+	assert.Contains(t, joined, "-T json", "the source capture keeps its output mode")
+	assert.NotContains(t, joined, "-e ip.src")
+
+	_, err = NewFieldsCapture(NewPipeCapture(strings.NewReader("")), nil)
+	assert.Error(t, err)
+	_, err = NewFieldsCapture(struct{}{}, []string{"ip.src"})
+	assert.Error(t, err)
+}
+
+func sniffTestRows(t *testing.T, c *Capture, output string) []*FieldsRow {
+	t.Helper()
+	rows, errc := c.sniffRows(context.Background(),
+		io.NopCloser(strings.NewReader(output)), io.NopCloser(strings.NewReader("")))
+	var out []*FieldsRow
+	for r := range rows {
+		out = append(out, r)
+	}
+	require.NoError(t, <-errc)
+	return out
+}
+
+func TestSniffRowsTyped(t *testing.T) {
+	c := NewCapture()
+	c.fields = []string{"frame.time_epoch", "ip.src", "tcp.srcport", "tcp.flags", "tcp.flags.syn", "ip.ttl"}
+	output := "\"1700000000.250000000\"\t\"10.0.0.1\"\t\"443\"\t\"0x0012\"\t\"1\"\t\"64,63\"\n" +
+		"\"1700000001.000000000\"\t\"10.0.0.2\"\t\t\t\t\"128\"\n"
+
+	rows := sniffTestRows(t, c, output)
+	require.Len(t, rows, 2)
+
+	r := rows[0]
+	assert.Equal(t, "10.0.0.1", r.String("ip.src"))
+	port, err := r.Int("tcp.srcport")
+	require.NoError(t, err)
+	assert.EqualValues(t, 443, port)
+	flags, err := r.Int("tcp.flags")
+	require.NoError(t, err)
+	assert.EqualValues(t, 0x12, flags)
+	syn, err := r.Bool("tcp.flags.syn")
+	require.NoError(t, err)
+	assert.True(t, syn)
+	assert.Equal(t, []string{"64", "63"}, r.Values("ip.ttl"))
+	ts, err := r.Time("frame.time_epoch")
+	require.NoError(t, err)
+	assert.Equal(t, time.Unix(1700000000, 250000000).UnixNano(), ts.UnixNano())
+
+	assert.False(t, rows[1].Has("tcp.srcport"))
+	_, err = rows[1].Int("tcp.srcport")
+	assert.Error(t, err)
+}
+
+func TestSniffRowsAbsentSingleField(t *testing.T) {
+	c := NewCapture()
+	c.fields = []string{"http.host"}
+	rows := sniffTestRows(t, c, "\"a.example\"\n\n\n\"b.example\"\n")
+	require.Len(t, rows, 4, "packets without the field must keep their rows")
+	assert.Equal(t, "a.example", rows[0].String("http.host"))
+	assert.False(t, rows[1].Has("http.host"))
+	assert.False(t, rows[2].Has("http.host"))
+	assert.Equal(t, "b.example", rows[3].String("http.host"))
+}
+
+func TestSniffRowsQuotedSeparator(t *testing.T) {
+	c := NewCapture(WithFieldsFormat(FieldsFormat{Separator: ','}))
+	c.fields = []string{"http.user_agent", "ip.src"}
+	rows := sniffTestRows(t, c, "\"Mozilla/5.0 (X11, Linux)\",\"10.0.0.1\"\n")
+	require.Len(t, rows, 1)
+	assert.Equal(t, "Mozilla/5.0 (X11, Linux)", rows[0].String("http.user_agent"))
+	assert.Equal(t, "10.0.0.1", rows[0].String("ip.src"))
+}
+
+func TestFieldsRowDecode(t *testing.T) {
+	c := NewCapture()
+	c.fields = []string{"ip.src", "tcp.srcport", "ip.ttl", "tcp.flags.syn"}
+	rows := sniffTestRows(t, c, "\"10.0.0.1\"\t\"443\"\t\"64,63\"\t\"True\"\n")
+	require.Len(t, rows, 1)
+
+	var v struct {
+		Src     string  `shark:"ip.src"`
+		SrcPort uint16  `shark:"tcp.srcport"`
+		TTLs    []int   `shark:"ip.ttl"`
+		SYN     bool    `shark:"tcp.flags.syn"`
+		Missing float64 `shark:"udp.length"`
+		Ignored string
+	}
+	require.NoError(t, rows[0].Decode(&v))
+	assert.Equal(t, "10.0.0.1", v.Src)
+	assert.EqualValues(t, 443, v.SrcPort)
+	assert.Equal(t, []int{64, 63}, v.TTLs)
+	assert.True(t, v.SYN)
+	assert.Zero(t, v.Missing)
+
+	var bad struct {
+		Port int8 `shark:"tcp.srcport"`
+	}
+	assert.Error(t, rows[0].Decode(&bad), "443 overflows int8")
+	assert.Error(t, rows[0].Decode(v), "non-pointer target")
+}
+
+func TestSniffRowsUnquoted(t *testing.T) {
+	c := NewCapture(WithFieldsFormat(FieldsFormat{Separator: '|', Quote: -1, Aggregator: ' '}))
+	c.fields = []string{"http.user_agent", "ip.ttl"}
+	rows := sniffTestRows(t, c, "curl \"x\"|64 63\n")
+	require.Len(t, rows, 1)
+	assert.Equal(t, "curl \"x\"", rows[0].String("http.user_agent"))
+	assert.Equal(t, []string{"64", "63"}, rows[0].Values("ip.ttl"))
+}
+
+func TestFieldsCaptureIntegration(t *testing.T) {
+	requireTShark(t)
+
+	file, err := NewFileCapture(testPcap)
+	require.NoError(t, err)
+	fc, err := NewFieldsCapture(file, []string{"frame.number", "tcp.srcport"})
+	require.NoError(t, err)
+
+	rows, err := fc.LoadRows(context.Background(), 0)
+	require.NoError(t, err)
+	require.Len(t, rows, 5, "test.pcap has 5 packets")
+	n, err := rows[0].Int("frame.number")
+	require.NoError(t, err)
+	require.EqualValues(t, 1, n)
+	require.True(t, rows[0].Has("tcp.srcport"))
+}
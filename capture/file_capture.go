@@ -7,7 +7,9 @@ import (
 	"os"
 	"time"
 
+	sharkerrors "github.com/p-vbordei/GoShark/errors"
 	"github.com/p-vbordei/GoShark/packet"
+	"github.com/p-vbordei/GoShark/pcapfile"
 	"github.com/p-vbordei/GoShark/tshark"
 )
 
@@ -15,6 +17,11 @@ import (
 type FileCapture struct {
 	Capture
 	FilePath string
+
+	window     frameWindow       // Frames fed to tshark; see WithTimeRange/WithFrameRange.
+	resume     *Checkpoint       // Skip frames up to this checkpoint; see WithResumeFrom.
+	checkpoint *CheckpointConfig // Periodic checkpoints; see WithCheckpoint.
+	fed        *frameNumbers     // Frames fed to the running tshark, when feeding a window.
 }
 
 // NewFileCapture creates a new FileCapture instance.
@@ -31,22 +38,63 @@ func NewFileCapture(filePath string, options ...Option) (*FileCapture, error) {
 		option(c)
 	}
 
-	// Check if the file exists and is readable
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("PCAP file not found at %s", filePath)
-	} else if err != nil {
-		return nil, fmt.Errorf("error accessing PCAP file %s: %w", filePath, err)
+	if err := checkCaptureFile(filePath); err != nil {
+		return nil, err
 	}
 
 	return c, nil
 }
 
+// checkCaptureFile fails fast on a file tshark could not read: a missing
+// file (FileNotFoundError), or a directory, an empty file or a pcap or
+// pcapng file whose header is truncated or corrupt (FileFormatError). Other
+// formats tshark reads, such as compressed captures, snoop or ERF, are left
+// for tshark to judge, as are pipes and other non-regular files.
+func checkCaptureFile(path string) error {
+	st, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return sharkerrors.NewFileNotFoundError(path)
+	} else if err != nil {
+		return fmt.Errorf("error accessing PCAP file %s: %w", path, err)
+	}
+	if st.IsDir() {
+		return sharkerrors.NewFileFormatError(path, fmt.Errorf("is a directory"))
+	}
+	if !st.Mode().IsRegular() {
+		return nil
+	}
+	if st.Size() == 0 {
+		return sharkerrors.NewFileFormatError(path, fmt.Errorf("file is empty"))
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("error accessing PCAP file %s: %w", path, err)
+	}
+	defer f.Close()
+	if _, err := pcapfile.Probe(f); err != nil && !sharkerrors.Is(err, pcapfile.ErrUnknownFormat) {
+		return sharkerrors.NewFileFormatError(path, err)
+	}
+	return nil
+}
+
+// Info reads the capture file once and returns its metadata: format,
+// encapsulation, packet count, timestamps, interfaces and hashes. Only pcap
+// and pcapng files are supported; see pcapfile.FileInfo.
+func (c *FileCapture) Info() (*pcapfile.Info, error) {
+	return pcapfile.FileInfo(c.FilePath)
+}
+
 // Start begins the file capture process.
 func (c *FileCapture) Start() (io.ReadCloser, io.ReadCloser, error) {
 	if c.FilePath == "" {
 		return nil, nil, fmt.Errorf("file path cannot be empty for file capture")
 	}
 
+	if c.window.isSet() || c.resume != nil || c.checkpoint != nil {
+		return c.startWindow()
+	}
+
 	// Start with -r flag and file path
 	args := []string{"-r", c.FilePath}
 
@@ -93,10 +141,13 @@ func (c *FileCapture) SniffContinuously(ctx context.Context) (<-chan *packet.Pac
 }
 
 // ApplyOnPackets applies the callback to all captured packets.
+// With WithCheckpoint, progress is checkpointed as the callback returns.
 func (c *FileCapture) ApplyOnPackets(callback func(*packet.Packet) bool, ctx context.Context) error {
-	return c.Capture.ApplyOnPackets(callback, ctx, func() (io.ReadCloser, io.ReadCloser, error) {
+	callback, finish := c.checkpointed(callback)
+	err := c.Capture.ApplyOnPackets(callback, ctx, func() (io.ReadCloser, io.ReadCloser, error) {
 		return c.Start()
 	})
+	return finish(err)
 }
 
 // LoadPackets eagerly reads up to count packets from the file (count <= 0 means
@@ -109,5 +160,7 @@ func (c *FileCapture) LoadPackets(ctx context.Context, count int) ([]*packet.Pac
 // packets or once timeout elapses (see Capture.ApplyOnPacketsWithLimit).
 func (c *FileCapture) ApplyOnPacketsWithLimit(callback func(*packet.Packet) bool,
 	ctx context.Context, packetCount int, timeout time.Duration) error {
-	return c.Capture.ApplyOnPacketsWithLimit(callback, ctx, packetCount, timeout, c.Start)
+	callback, finish := c.checkpointed(callback)
+	err := c.Capture.ApplyOnPacketsWithLimit(callback, ctx, packetCount, timeout, c.Start)
+	return finish(err)
 }
@@ -0,0 +1,53 @@
+package capture
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sharkerrors "github.com/p-vbordei/GoShark/errors"
+	"github.com/p-vbordei/GoShark/pcapfile"
+)
+
+func TestNewFileCaptureChecksFile(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := NewFileCapture(filepath.Join(dir, "missing.pcap"))
+	var notFound *sharkerrors.FileNotFoundError
+	require.ErrorAs(t, err, &notFound)
+	assert.Equal(t, filepath.Join(dir, "missing.pcap"), notFound.FilePath())
+
+	data, err := os.ReadFile(testPcap)
+	require.NoError(t, err)
+	for name, content := range map[string][]byte{
+		"empty.pcap":     nil,
+		"truncated.pcap": data[:10],
+	} {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, content, 0o644))
+		_, err := NewFileCapture(path)
+		var badFormat *sharkerrors.FileFormatError
+		assert.ErrorAs(t, err, &badFormat, name)
+	}
+	_, err = NewFileCapture(dir)
+	var badFormat *sharkerrors.FileFormatError
+	assert.ErrorAs(t, err, &badFormat, "directory")
+
+	// Formats other than pcap and pcapng are left for tshark to judge.
+	other := filepath.Join(dir, "capture.snoop")
+	require.NoError(t, os.WriteFile(other, []byte("snoop\x00\x00\x00\x00\x00\x00\x02\x00\x00\x00\x04"), 0o644))
+	_, err = NewFileCapture(other)
+	assert.NoError(t, err)
+}
+
+func TestFileCaptureInfo(t *testing.T) {
+	fc, err := NewFileCapture(testPcap)
+	require.NoError(t, err)
+	info, err := fc.Info()
+	require.NoError(t, err)
+	assert.Equal(t, pcapfile.FormatPcapNG, info.Format)
+	assert.Equal(t, 5, info.Packets)
+}
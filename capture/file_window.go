@@ -0,0 +1,323 @@
+package capture
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/p-vbordei/GoShark/packet"
+	"github.com/p-vbordei/GoShark/pcapfile"
+	"github.com/p-vbordei/GoShark/tshark"
+)
+
+// frameWindow selects the frames of a file that are handed to tshark.
+type frameWindow struct {
+	start, end  time.Time // Capture time window [start, end); zero means open
+	first, last int       // Frame number window [first, last]; 0 means open
+}
+
+func (w frameWindow) isSet() bool {
+	return !w.start.IsZero() || !w.end.IsZero() || w.first > 0 || w.last > 0
+}
+
+// selects reports whether frame number n belongs to the window, and whether
+// the window has been passed so no later frame can.
+func (w frameWindow) selects(n int, f pcapfile.Frame) (keep, done bool) {
+	if w.last > 0 && n > w.last {
+		return false, true
+	}
+	if !w.end.IsZero() && !f.Time.Before(w.end) {
+		return false, true
+	}
+	if n < w.first {
+		return false, false
+	}
+	if !w.start.IsZero() && f.Time.Before(w.start) {
+		return false, false
+	}
+	return true, false
+}
+
+// WithTimeRange restricts a FileCapture to frames captured in [start, end);
+// a zero start or end leaves that side open. Frames outside the window are
+// dropped before dissection, and reading stops at the first frame captured
+// at or after end, so the file is assumed to be in time order. Packets keep
+// their frame numbers in the whole file, except in FieldsCapture rows.
+func WithTimeRange(start, end time.Time) Option {
+	return func(v interface{}) {
+		if c, ok := v.(*FileCapture); ok {
+			c.window.start, c.window.end = start, end
+		}
+	}
+}
+
+// WithFrameRange restricts a FileCapture to frame numbers first through
+// last (1-based, inclusive, as tshark numbers them); last 0 means through
+// the end of the file. Other frames are dropped before dissection and
+// reading stops after frame last. It can be combined with WithTimeRange.
+func WithFrameRange(first, last int) Option {
+	return func(v interface{}) {
+		if c, ok := v.(*FileCapture); ok {
+			c.window.first, c.window.last = first, last
+		}
+	}
+}
+
+// frameNumbers maps the frames fed to tshark (1-based, in order) back to
+// their frame numbers in the file. Consecutive frames share one run, so a
+// contiguous window costs O(1) memory. With checkpoints, ends locates the
+// processed frames in the file.
+type frameNumbers struct {
+	mu   sync.Mutex
+	runs []frameRun
+	fed  int
+
+	ends *frameEnds
+}
+
+// frameRun says fed frames [fed, fed+count) are file frames [number, ...).
+type frameRun struct {
+	fed, number, count int
+}
+
+func (m *frameNumbers) add(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.fed++
+	if last := len(m.runs) - 1; last >= 0 && m.runs[last].number+m.runs[last].count == n {
+		m.runs[last].count++
+	} else {
+		m.runs = append(m.runs, frameRun{fed: m.fed, number: n, count: 1})
+	}
+}
+
+func (m *frameNumbers) renumber(p *packet.Packet) {
+	k, err := strconv.Atoi(p.FrameNumber)
+	if err != nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := sort.Search(len(m.runs), func(i int) bool { return m.runs[i].fed+m.runs[i].count > k })
+	if i < len(m.runs) && k >= m.runs[i].fed {
+		renumberFrame(p, m.runs[i].number+k-m.runs[i].fed)
+	}
+}
+
+// startWindow starts tshark reading stdin and feeds it the frames of the
+// file inside the window. When resuming from a checkpoint, the frames before
+// it are skipped here, before tshark starts, so a file that no longer matches
+// the checkpoint is reported by Start.
+func (c *FileCapture) startWindow() (io.ReadCloser, io.ReadCloser, error) {
+	if err := c.checkResumeFile(); err != nil {
+		return nil, nil, fmt.Errorf("cannot resume %s: %w", c.FilePath, err)
+	}
+	window := c.window
+	if c.resume != nil && c.resume.Frame >= window.first {
+		window.first = c.resume.Frame + 1
+	}
+	if window.last > 0 && window.last < window.first {
+		return nil, nil, fmt.Errorf("invalid frame range %d-%d", window.first, window.last)
+	}
+	if !window.start.IsZero() && !window.end.IsZero() && !window.start.Before(window.end) {
+		return nil, nil, fmt.Errorf("invalid time range: start %v is not before end %v", window.start, window.end)
+	}
+	f, err := os.Open(c.FilePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error opening PCAP file %s: %w", c.FilePath, err)
+	}
+	reader, err := pcapfile.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, nil, fmt.Errorf("failed to read %s: %w", c.FilePath, err)
+	}
+	var preamble bytes.Buffer
+	skipped, skippedEnd := 0, int64(0)
+	if c.resume != nil {
+		if skipped, skippedEnd, err = skipToCheckpoint(&preamble, reader, c.resume); err != nil {
+			f.Close()
+			return nil, nil, fmt.Errorf("cannot resume %s: %w", c.FilePath, err)
+		}
+	}
+
+	numbers := &frameNumbers{}
+	if c.checkpoint != nil {
+		numbers.ends = &frameEnds{path: c.FilePath, preamble: preamble.Bytes(), n: skipped, end: skippedEnd}
+	}
+	feed := func(w io.Writer) error {
+		if _, err := w.Write(preamble.Bytes()); err != nil {
+			return err
+		}
+		return feedWindow(w, reader, window, skipped, numbers)
+	}
+
+	tsharkArgs, err := c.getTSharkArgs()
+	if err != nil {
+		f.Close()
+		return nil, nil, fmt.Errorf("failed to get tshark arguments: %w", err)
+	}
+	input := "-"
+	if c.twoPass() {
+		// Two passes need a seekable file rather than stdin.
+		input, err = spoolCapture(reader.Format(), feed)
+		f.Close()
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	cleanup := func() {
+		f.Close()
+		if input != "-" {
+			os.Remove(input)
+		}
+	}
+	cmd, err := tshark.RunTSharkCommand(c.TSharkPath, append([]string{"-r", input}, tsharkArgs...)...)
+	if err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("failed to run tshark command: %w", err)
+	}
+	c.cmd = cmd
+
+	var stdin io.WriteCloser
+	if input == "-" {
+		if stdin, err = cmd.StdinPipe(); err != nil {
+			cleanup()
+			return nil, nil, fmt.Errorf("failed to get stdin pipe: %w", err)
+		}
+	}
+	stdoutPipe, err := cmd.StdoutPipe()
+	if err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("failed to get stdout pipe: %w", err)
+	}
+	stderrPipe, err := cmd.StderrPipe()
+	if err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("failed to get stderr pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("failed to start tshark command: %w", err)
+	}
+
+	c.fed = numbers
+	c.packetHook = numbers.renumber
+	c.inputErr = nil
+	if stdin == nil {
+		return &removeOnClose{ReadCloser: stdoutPipe, path: input}, stderrPipe, nil
+	}
+	result := &feedResult{done: make(chan struct{})}
+	c.inputErr = result.wait
+	go func() {
+		defer close(result.done)
+		defer f.Close()
+		defer stdin.Close()
+		err := feed(stdin)
+		switch {
+		case err == nil:
+		case errors.Is(err, syscall.EPIPE) || errors.Is(err, os.ErrClosed):
+			// tshark stopped reading: it exited or was stopped.
+			if c.debug {
+				log.Printf("goshark: feeding %s to tshark: %v", c.FilePath, err)
+			}
+		default:
+			result.err = fmt.Errorf("failed to feed %s to tshark: %w", c.FilePath, err)
+		}
+	}()
+	return &feedErrorReader{ReadCloser: stdoutPipe, feed: result}, stderrPipe, nil
+}
+
+// feedResult is the error feeding a file to tshark ended with; done is
+// closed once the feeder has finished.
+type feedResult struct {
+	done chan struct{}
+	err  error
+}
+
+func (r *feedResult) wait() error {
+	<-r.done
+	return r.err
+}
+
+// feedErrorReader returns the feed error in place of the end of tshark's
+// output, so a file that cannot be read to the end does not look complete.
+type feedErrorReader struct {
+	io.ReadCloser
+	feed *feedResult
+}
+
+func (r *feedErrorReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if err == io.EOF {
+		if feedErr := r.feed.wait(); feedErr != nil {
+			return n, feedErr
+		}
+	}
+	return n, err
+}
+
+// skipToCheckpoint reads r up to and including frame cp.Frame, writing the
+// non-frame records the remaining frames depend on to preamble. It checks
+// that the frame ends at cp.Offset, when recorded, and returns the number of
+// frames read and the offset just past the last one.
+func skipToCheckpoint(preamble io.Writer, r *pcapfile.Reader, cp *Checkpoint) (int, int64, error) {
+	n, end := 0, int64(0)
+	for n < cp.Frame {
+		rec, err := r.Next()
+		if err == io.EOF {
+			return n, end, fmt.Errorf("file has %d frames, checkpoint is at frame %d", n, cp.Frame)
+		}
+		if err != nil {
+			return n, end, err
+		}
+		if !rec.IsFrame {
+			if _, err := preamble.Write(rec.Data); err != nil {
+				return n, end, err
+			}
+			continue
+		}
+		n++
+		end = rec.Frame.Offset + rec.Frame.Size
+		if n == cp.Frame && cp.Offset > 0 && end != cp.Offset {
+			return n, end, fmt.Errorf("frame %d ends at offset %d, checkpoint recorded %d; the file has changed", n, end, cp.Offset)
+		}
+	}
+	return n, end, nil
+}
+
+// feedWindow copies the records of r that a file restricted to window needs:
+// every non-frame record and the selected frames. n frames have already
+// been read from r.
+func feedWindow(dst io.Writer, r *pcapfile.Reader, window frameWindow, n int, numbers *frameNumbers) error {
+	for {
+		rec, err := r.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if rec.IsFrame {
+			n++
+			keep, done := window.selects(n, rec.Frame)
+			if done {
+				return nil
+			}
+			if !keep {
+				continue
+			}
+			numbers.add(n)
+		}
+		if _, err := dst.Write(rec.Data); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package capture
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/p-vbordei/GoShark/packet"
+	"github.com/p-vbordei/GoShark/pcapfile"
+)
+
+func TestFrameWindowSelects(t *testing.T) {
+	base := time.Unix(1700000000, 0)
+	at := func(d time.Duration) pcapfile.Frame { return pcapfile.Frame{Time: base.Add(d)} }
+
+	frames := frameWindow{first: 2, last: 3}
+	for _, tc := range []struct {
+		n          int
+		keep, done bool
+	}{{1, false, false}, {2, true, false}, {3, true, false}, {4, false, true}} {
+		keep, done := frames.selects(tc.n, at(0))
+		assert.Equal(t, tc.keep, keep, "frame %d", tc.n)
+		assert.Equal(t, tc.done, done, "frame %d", tc.n)
+	}
+
+	times := frameWindow{start: base.Add(time.Second), end: base.Add(3 * time.Second)}
+	for _, tc := range []struct {
+		d          time.Duration
+		keep, done bool
+	}{{0, false, false}, {time.Second, true, false}, {2999 * time.Millisecond, true, false}, {3 * time.Second, false, true}} {
+		keep, done := times.selects(1, at(tc.d))
+		assert.Equal(t, tc.keep, keep, "offset %v", tc.d)
+		assert.Equal(t, tc.done, done, "offset %v", tc.d)
+	}
+
+	openEnded := frameWindow{start: base}
+	keep, done := openEnded.selects(1000, at(time.Hour))
+	assert.True(t, keep)
+	assert.False(t, done)
+	assert.False(t, frameWindow{}.isSet())
+}
+
+func TestFeedWindow(t *testing.T) {
+	ix, err := pcapfile.BuildIndex(testPcap)
+	require.NoError(t, err)
+	f, err := os.Open(testPcap)
+	require.NoError(t, err)
+	defer f.Close()
+	r, err := pcapfile.NewReader(f)
+	require.NoError(t, err)
+
+	window := frameWindow{first: 2, end: ix.Frames[4].Time}
+	numbers := &frameNumbers{}
+	var out bytes.Buffer
+	require.NoError(t, feedWindow(&out, r, window, 0, numbers))
+	assert.Equal(t, []frameRun{{fed: 1, number: 2, count: 3}}, numbers.runs)
+
+	sliced, err := pcapfile.NewIndex(bytes.NewReader(out.Bytes()))
+	require.NoError(t, err)
+	require.Equal(t, 3, sliced.Len())
+	assert.True(t, sliced.Frames[0].Time.Equal(ix.Frames[1].Time))
+
+	// tshark numbers the sliced frames 1..3; they map back to 2..4.
+	p := &packet.Packet{FrameNumber: "3"}
+	numbers.renumber(p)
+	assert.Equal(t, "4", p.FrameNumber)
+}
+
+func TestFileCaptureWindowIntegration(t *testing.T) {
+	requireTShark(t)
+
+	full, err := NewFileCapture(testPcap)
+	require.NoError(t, err)
+	all := collect(t, full)
+	require.Len(t, all, 5)
+	third, err := all[2].SniffTime()
+	require.NoError(t, err)
+
+	fc, err := NewFileCapture(testPcap, WithFrameRange(2, 4))
+	require.NoError(t, err)
+	pkts := collect(t, fc)
+	require.Len(t, pkts, 3)
+	assert.Equal(t, "2", pkts[0].FrameNumber)
+	assert.Equal(t, "4", pkts[2].FrameNumber)
+	assert.Equal(t, all[1].RawData, pkts[0].RawData)
+
+	fc, err = NewFileCapture(testPcap, WithTimeRange(third, time.Time{}))
+	require.NoError(t, err)
+	pkts = collect(t, fc)
+	require.Len(t, pkts, 3)
+	assert.Equal(t, "3", pkts[0].FrameNumber)
+}
+
+func TestFrameNumbersRuns(t *testing.T) {
+	m := &frameNumbers{}
+	for _, n := range []int{3, 4, 5, 9, 10} {
+		m.add(n)
+	}
+	assert.Len(t, m.runs, 2)
+	for k, want := range map[string]string{"1": "3", "3": "5", "4": "9", "5": "10", "6": "6"} {
+		p := &packet.Packet{FrameNumber: k}
+		m.renumber(p)
+		assert.Equal(t, want, p.FrameNumber, "fed frame %s", k)
+	}
+}
+
+func TestFeedErrorReachesCaller(t *testing.T) {
+	tsharkPath := fakeFilterTools(t, "cat > /dev/null\n", "exit 0\n")
+	data, err := os.ReadFile(testPcap)
+	require.NoError(t, err)
+	truncated := filepath.Join(t.TempDir(), "truncated.pcap")
+	require.NoError(t, os.WriteFile(truncated, data[:len(data)-10], 0o644))
+
+	fc, err := NewFileCapture(truncated, WithTSharkPath(tsharkPath), WithFrameRange(2, 0))
+	require.NoError(t, err)
+	err = fc.ApplyOnPackets(func(*packet.Packet) bool { return false }, context.Background())
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	assert.ErrorContains(t, err, "failed to feed "+truncated)
+
+	stdout, stderr, err := fc.Start()
+	require.NoError(t, err)
+	defer stderr.Close()
+	_, err = io.ReadAll(stdout)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF, "the feed error replaces the end of output")
+	stdout.Close()
+
+	fc, err = NewFileCapture(testPcap, WithTSharkPath(tsharkPath), WithFrameRange(2, 0))
+	require.NoError(t, err)
+	assert.NoError(t, fc.ApplyOnPackets(func(*packet.Packet) bool { return false }, context.Background()))
+}
@@ -0,0 +1,50 @@
+package capture
+
+import "github.com/p-vbordei/GoShark/tshark"
+
+// WithStrictFilters validates the display, read and capture filters before
+// tshark starts, so a typo fails Start with an *errors.InvalidFilterError
+// carrying tshark's message and the error position, instead of tshark exiting
+// mid-stream. Display and read filters are checked offline
+// (tshark.ValidateDisplayFilter) for every capture type. Capture filters only
+// apply to live captures, so only those compile them, with "dumpcap -d" for
+// the capture's first interface and its data link type (see
+// WithDataLinkType); file and pipe captures never run dumpcap.
+func WithStrictFilters() Option {
+	return func(v interface{}) {
+		if c := getCapture(v); c != nil {
+			c.strictFilters = true
+		}
+	}
+}
+
+// checkFilters validates the display and read filters in strict mode.
+func (c *Capture) checkFilters() error {
+	if !c.strictFilters {
+		return nil
+	}
+	for _, filter := range []string{c.DisplayFilter, c.ReadFilter} {
+		if err := tshark.ValidateDisplayFilter(c.TSharkPath, filter); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkBPFFilter compiles the live capture's BPF and capture filters for its
+// first interface and data link type in strict mode.
+func (lc *LiveCapture) checkBPFFilter() error {
+	if !lc.strictFilters {
+		return nil
+	}
+	iface := ""
+	if len(lc.Interfaces) > 0 {
+		iface = lc.Interfaces[0]
+	}
+	for _, filter := range []string{lc.BPFFilter, lc.CaptureFilter} {
+		if err := tshark.ValidateCaptureFilter(lc.TSharkPath, filter, iface, lc.DataLinkType); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package capture
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sharkerrors "github.com/p-vbordei/GoShark/errors"
+)
+
+func TestStrictFiltersValidateBeforeStart(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "tshark")
+
+	cap := NewCapture(WithTSharkPath(missing), WithDisplayFilter("tcp"))
+	_, err := cap.getTSharkArgs()
+	assert.NoError(t, err, "filters are not validated by default")
+
+	cap = NewCapture(WithTSharkPath(missing), WithDisplayFilter("tcp"), WithStrictFilters())
+	_, err = cap.getTSharkArgs()
+	assert.ErrorContains(t, err, "failed to run tshark", "strict mode runs the validator")
+}
+
+// fakeFilterTools installs shell scripts as tshark and dumpcap in a
+// temporary directory and returns the tshark path.
+func fakeFilterTools(t *testing.T, tsharkScript, dumpcapScript string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake tools are shell scripts")
+	}
+	dir := t.TempDir()
+	for name, script := range map[string]string{"tshark": tsharkScript, "dumpcap": dumpcapScript} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script), 0o755))
+	}
+	return filepath.Join(dir, "tshark")
+}
+
+func TestStrictDisplayFilterRejected(t *testing.T) {
+	tsharkPath := fakeFilterTools(t,
+		"echo 'tshark: \"foo\" is neither a field nor a protocol name.' >&2\n"+
+			"echo '    ip.src == 1 && foo' >&2\n"+
+			"echo '                   ^~~' >&2\n"+
+			"exit 4\n",
+		"exit 0\n")
+
+	cap := NewCapture(WithTSharkPath(tsharkPath), WithDisplayFilter("ip.src == 1 && foo"), WithStrictFilters())
+	_, err := cap.getTSharkArgs()
+	var invalid *sharkerrors.InvalidFilterError
+	require.ErrorAs(t, err, &invalid)
+	assert.Equal(t, "ip.src == 1 && foo", invalid.Filter())
+	assert.Equal(t, "\"foo\" is neither a field nor a protocol name.", invalid.Detail())
+	assert.Equal(t, 15, invalid.Offset())
+}
+
+func TestStrictCaptureFilterOnlyCompiledForLiveCaptures(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "dumpcap-args")
+	tsharkPath := fakeFilterTools(t, "exit 0\n",
+		"echo \"$@\" > '"+marker+"'\n"+
+			"echo \"dumpcap: Invalid capture filter \\\"tcp prt 80\\\" for interface 'eth0'.\" >&2\n"+
+			"echo \"That string isn't a valid capture filter (syntax error).\" >&2\n"+
+			"exit 2\n")
+
+	// A file capture has no interface to compile for; dumpcap must not run.
+	cap := NewCapture(WithTSharkPath(tsharkPath), WithCaptureFilter("tcp prt 80"), WithStrictFilters())
+	_, err := cap.getTSharkArgs()
+	require.NoError(t, err)
+	_, err = os.Stat(marker)
+	assert.True(t, os.IsNotExist(err), "dumpcap must not run for non-live captures")
+
+	lc := &LiveCapture{Capture: NewCapture(WithTSharkPath(tsharkPath), WithStrictFilters()), Interfaces: []string{"eth0"}}
+	WithBPFFilter("tcp prt 80")(lc)
+	WithDataLinkType("EN10MB")(lc)
+	err = lc.checkBPFFilter()
+	var invalid *sharkerrors.InvalidFilterError
+	require.ErrorAs(t, err, &invalid)
+	assert.Equal(t, "tcp prt 80", invalid.Filter())
+	assert.Contains(t, invalid.Detail(), "isn't a valid capture filter")
+	assert.Equal(t, -1, invalid.Offset(), "dumpcap reports no position")
+
+	args, err := os.ReadFile(marker)
+	require.NoError(t, err)
+	assert.Equal(t, "-d -f tcp prt 80 -i eth0 -y EN10MB\n", string(args))
+	assert.True(t, containsPair(lc.getDumpcapParameters(), "-y", "EN10MB"))
+}
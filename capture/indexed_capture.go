@@ -0,0 +1,212 @@
+package capture
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/p-vbordei/GoShark/packet"
+	"github.com/p-vbordei/GoShark/pcapfile"
+	"github.com/p-vbordei/GoShark/tshark"
+)
+
+// indexedCacheSize is how many decoded frames an IndexedFileCapture keeps.
+const indexedCacheSize = 256
+
+// IndexedFileCapture gives random access to the frames of a pcap/pcapng file
+// without decoding all of it. The file is scanned once for frame offsets and
+// timestamps; Get and Range then hand tshark only the requested frames.
+//
+// Frames are dissected in isolation from the rest of the file, so analysis
+// that spans frames (TCP reassembly and sequence analysis, request/response
+// matching, frame.time_relative) only sees the requested range. A display
+// filter, if set, applies within the range.
+type IndexedFileCapture struct {
+	*FileCapture
+
+	index *pcapfile.Index
+	file  *os.File
+
+	mu    sync.Mutex
+	cache *lruCache
+}
+
+// NewIndexedFileCapture indexes the capture file at filePath. Options are
+// those of NewFileCapture.
+func NewIndexedFileCapture(filePath string, options ...Option) (*IndexedFileCapture, error) {
+	fc, err := NewFileCapture(filePath, options...)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("error opening PCAP file %s: %w", filePath, err)
+	}
+	index, err := pcapfile.NewIndex(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to index %s: %w", filePath, err)
+	}
+	return &IndexedFileCapture{
+		FileCapture: fc,
+		index:       index,
+		file:        f,
+		cache:       newLRUCache(indexedCacheSize),
+	}, nil
+}
+
+// Index returns the frame index of the file.
+func (c *IndexedFileCapture) Index() *pcapfile.Index {
+	return c.index
+}
+
+// Len returns the number of frames in the file.
+func (c *IndexedFileCapture) Len() int {
+	return c.index.Len()
+}
+
+// Get decodes frame i (0-based; tshark's frame number i+1). Recently decoded
+// frames are cached.
+func (c *IndexedFileCapture) Get(i int) (*packet.Packet, error) {
+	c.mu.Lock()
+	p, ok := c.cache.get(i)
+	c.mu.Unlock()
+	if ok {
+		return p, nil
+	}
+	pkts, err := c.Range(i, i+1)
+	if err != nil {
+		return nil, err
+	}
+	if len(pkts) == 0 {
+		return nil, fmt.Errorf("frame %d did not match the display filter", i)
+	}
+	return pkts[0], nil
+}
+
+// Range decodes frames [from, to) with a single tshark run and returns them
+// in order. Frames a display filter rejects are omitted.
+func (c *IndexedFileCapture) Range(from, to int) ([]*packet.Packet, error) {
+	if from < 0 || to > c.index.Len() || from > to {
+		return nil, fmt.Errorf("frame range [%d, %d) out of bounds [0, %d)", from, to, c.index.Len())
+	}
+	if from == to {
+		return nil, nil
+	}
+	pkts, err := c.decodeFrames(context.Background(), from, to)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	for _, p := range pkts {
+		if n, err := strconv.Atoi(p.FrameNumber); err == nil {
+			c.cache.put(n-1, p)
+		}
+	}
+	c.mu.Unlock()
+	return pkts, nil
+}
+
+// SeekTime returns the index of the first frame captured at or after t, or
+// Len() if there is none.
+func (c *IndexedFileCapture) SeekTime(t time.Time) int {
+	return c.index.SeekTime(t)
+}
+
+// Close stops any running tshark process and closes the file.
+func (c *IndexedFileCapture) Close() error {
+	c.Stop()
+	return c.file.Close()
+}
+
+// decodeFrames runs tshark over frames [from, to) fed on stdin and renumbers
+// the packets to their frame numbers in the whole file.
+func (c *IndexedFileCapture) decodeFrames(ctx context.Context, from, to int) ([]*packet.Packet, error) {
+	tsharkArgs, err := c.getTSharkArgs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tshark arguments: %w", err)
+	}
+	writeFrames := func(w io.Writer) error { return c.index.WriteFrames(w, c.file, from, to) }
+	input := "-"
+	if c.twoPass() {
+		// Two passes need a seekable file rather than stdin.
+		if input, err = spoolCapture(c.index.Format, writeFrames); err != nil {
+			return nil, err
+		}
+		defer os.Remove(input)
+	}
+	cmd, err := tshark.RunTSharkCommand(c.TSharkPath, append([]string{"-r", input}, tsharkArgs...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run tshark command: %w", err)
+	}
+	var stdin io.WriteCloser
+	if input == "-" {
+		if stdin, err = cmd.StdinPipe(); err != nil {
+			return nil, fmt.Errorf("failed to get stdin pipe: %w", err)
+		}
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stdout pipe: %w", err)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start tshark command: %w", err)
+	}
+
+	writeErr := make(chan error, 1)
+	if stdin == nil {
+		writeErr <- nil
+	} else {
+		go func() {
+			err := writeFrames(stdin)
+			stdin.Close()
+			writeErr <- err
+		}()
+	}
+
+	stream, err := c.sniffStream(ctx, stdout, io.NopCloser(strings.NewReader("")))
+	if err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return nil, err
+	}
+	var pkts []*packet.Packet
+	for p := range stream {
+		pkts = append(pkts, p)
+	}
+	if err := cmd.Wait(); err != nil {
+		return nil, fmt.Errorf("tshark failed decoding frames [%d, %d): %w: %s", from, to, err, strings.TrimSpace(stderr.String()))
+	}
+	if err := <-writeErr; err != nil {
+		return nil, fmt.Errorf("failed to feed frames to tshark: %w", err)
+	}
+
+	for _, p := range pkts {
+		if n, err := strconv.Atoi(p.FrameNumber); err == nil {
+			renumberFrame(p, n+from)
+		}
+	}
+	return pkts, nil
+}
+
+// renumberFrame sets a packet's frame number. tshark numbers frames from the
+// first one it was fed, which differs from their numbers in the file when
+// only part of the file is fed.
+func renumberFrame(p *packet.Packet, number int) {
+	p.FrameNumber = strconv.Itoa(number)
+	if frame := p.GetLayer("frame"); frame != nil {
+		for _, key := range []string{"frame.number", "frame_frame_number"} {
+			if _, ok := frame.Fields[key]; ok {
+				frame.Fields[key] = p.FrameNumber
+			}
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package capture
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/p-vbordei/GoShark/packet"
+)
+
+func TestIndexedFileCaptureIndex(t *testing.T) {
+	c, err := NewIndexedFileCapture(testPcap, WithDisplayFilter("tcp"))
+	require.NoError(t, err)
+	defer c.Close()
+
+	assert.Equal(t, 5, c.Len())
+	assert.Equal(t, "tcp", c.DisplayFilter, "options apply to the underlying capture")
+	assert.Equal(t, 2, c.SeekTime(c.Index().Frames[2].Time))
+
+	_, err = c.Range(3, 6)
+	assert.Error(t, err)
+	_, err = c.Get(-1)
+	assert.Error(t, err)
+	pkts, err := c.Range(2, 2)
+	assert.NoError(t, err)
+	assert.Empty(t, pkts)
+
+	_, err = NewIndexedFileCapture("../README.md")
+	assert.Error(t, err, "not a capture file")
+}
+
+func TestRenumberFrame(t *testing.T) {
+	p := &packet.Packet{FrameNumber: "2", Layers: []packet.Layer{
+		{Name: "frame", Fields: map[string]interface{}{"frame.number": "2"}},
+	}}
+	renumberFrame(p, 12)
+	assert.Equal(t, "12", p.FrameNumber)
+	assert.Equal(t, "12", p.GetLayer("frame").GetField("frame.number"))
+}
+
+func TestIndexedFileCaptureIntegration(t *testing.T) {
+	requireTShark(t)
+
+	full, err := NewFileCapture(testPcap)
+	require.NoError(t, err)
+	all := collect(t, full)
+
+	c, err := NewIndexedFileCapture(testPcap)
+	require.NoError(t, err)
+	defer c.Close()
+
+	p, err := c.Get(3)
+	require.NoError(t, err)
+	assert.Equal(t, "4", p.FrameNumber)
+	assert.Equal(t, all[3].FrameTimeEpoch, p.FrameTimeEpoch)
+	assert.Equal(t, all[3].RawData, p.RawData)
+
+	pkts, err := c.Range(1, 4)
+	require.NoError(t, err)
+	require.Len(t, pkts, 3)
+	for i, p := range pkts {
+		assert.Equal(t, all[i+1].FrameNumber, p.FrameNumber)
+		assert.Equal(t, all[i+1].HighestLayer(), p.HighestLayer())
+	}
+}
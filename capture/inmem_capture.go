@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"slices"
 	"time"
 
 	"golang.org/x/mod/semver"
@@ -88,8 +89,18 @@ func (c *InMemCapture) getTSharkProcess() error {
 		}
 	}
 
-	// Set up command line arguments for reading from stdin and outputting JSON
-	args := []string{"--enable-heuristic", heuristicProto, "-i", "-", "-o", "tcp.relative_sequence_numbers:FALSE", "-Tjson"}
+	// Set up command line arguments for reading from stdin and outputting JSON.
+	// The TLS heuristic is on by default unless explicitly disabled.
+	var args []string
+	if !slices.Contains(c.DisableHeuristics, heuristicProto) {
+		args = append(args, "--enable-heuristic", heuristicProto)
+	}
+	dissectorArgs, err := c.dissectorArgs()
+	if err != nil {
+		return err
+	}
+	args = append(args, dissectorArgs...)
+	args = append(args, "-i", "-", "-o", "tcp.relative_sequence_numbers:FALSE", "-Tjson")
 
 	// Create the command
 	cmd, err := tshark.RunTSharkCommand(tsharkPath, args...)
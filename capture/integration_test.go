@@ -186,3 +186,18 @@ func TestPipeCaptureIntegration(t *testing.T) {
 	require.Len(t, pkts, 5, "pipe capture should yield all 5 packets of test.pcap")
 	require.Equal(t, "1", pkts[0].FrameNumber)
 }
+
+func TestFileCaptureIntegrationOnlyProtocols(t *testing.T) {
+	requireTShark(t)
+
+	fc, err := NewFileCapture(testPcap, WithOnlyProtocols("tcp"))
+	require.NoError(t, err)
+
+	pkts := collect(t, fc)
+	require.Len(t, pkts, 5)
+	for _, p := range pkts {
+		require.NotEmpty(t, p.FrameNumber, "frame metadata must survive -J")
+		require.True(t, p.HasLayer("tcp"))
+		require.False(t, p.HasLayer("ip"), "ip should be filtered out by -J")
+	}
+}
@@ -16,6 +16,9 @@ type LiveCapture struct {
 	*Capture
 	Interfaces []string
 	BPFFilter  string
+	// DataLinkType is the link-layer header type to capture with (dumpcap
+	// -y), e.g. "EN10MB"; empty uses each interface's default.
+	DataLinkType string
 }
 
 // NewLiveCapture creates a new LiveCapture instance with the specified interfaces.
@@ -58,6 +61,21 @@ func WithBPFFilter(filter string) Option {
 	}
 }
 
+// WithDataLinkType sets the link-layer header type of a live capture (dumpcap
+// -y, e.g. "EN10MB" or "IEEE802_11_RADIO"). Strict mode compiles capture
+// filters for it.
+func WithDataLinkType(linkType string) Option {
+	return func(v interface{}) {
+		if lc, ok := v.(*LiveCapture); ok {
+			lc.DataLinkType = linkType
+		} else if rc, ok := v.(*RemoteCapture); ok && rc.LiveCapture != nil {
+			rc.LiveCapture.DataLinkType = linkType
+		} else if lrc, ok := v.(*LiveRingCapture); ok && lrc.LiveCapture != nil {
+			lrc.LiveCapture.DataLinkType = linkType
+		}
+	}
+}
+
 // VerifyCaptureParameters checks if the specified interfaces exist.
 func (lc *LiveCapture) VerifyCaptureParameters() error {
 	allInterfaces, err := tshark.GetAllTSharkInterfaceNames(lc.TSharkPath)
@@ -96,10 +114,17 @@ func isNumeric(s string) bool {
 
 // Start begins the live capture process.
 func (lc *LiveCapture) Start() (stdout io.ReadCloser, stderr io.ReadCloser, err error) {
+	if err := lc.liveTwoPassError(); err != nil {
+		return nil, nil, err
+	}
+
 	// Verify interfaces exist
 	if err := lc.VerifyCaptureParameters(); err != nil {
 		return nil, nil, err
 	}
+	if err := lc.checkBPFFilter(); err != nil {
+		return nil, nil, err
+	}
 
 	// Get dumpcap parameters
 	dumpcapParams := lc.getDumpcapParameters()
@@ -198,6 +223,11 @@ func (lc *LiveCapture) getDumpcapParameters() []string {
 		params = append(params, "-I")
 	}
 
+	// Before any -i, the link type applies to every interface
+	if lc.DataLinkType != "" {
+		params = append(params, "-y", lc.DataLinkType)
+	}
+
 	// Add interfaces
 	for _, iface := range lc.Interfaces {
 		params = append(params, "-i", iface)
@@ -67,6 +67,9 @@ func WithRingFileName(name string) Option {
 // getRingTSharkArgs builds the full tshark argument vector for a ring capture:
 // the base capture arguments plus the ring-buffer flags and interfaces.
 func (lrc *LiveRingCapture) getRingTSharkArgs() ([]string, error) {
+	if err := lrc.liveTwoPassError(); err != nil {
+		return nil, err
+	}
 	tsharkArgs, err := lrc.getTSharkArgs()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get tshark arguments: %w", err)
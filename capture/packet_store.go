@@ -0,0 +1,391 @@
+package capture
+
+import (
+	"bufio"
+	"container/list"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/p-vbordei/GoShark/packet"
+)
+
+// ErrPacketEvicted is returned by PacketStore.Get for a packet a bounded
+// store has dropped.
+var ErrPacketEvicted = errors.New("packet evicted from store")
+
+// PacketStore retains the packets LoadPackets passes through, for indexed
+// access via Capture.Get/Len/Packets. Packets are indexed in load order
+// starting at 0; indexes stay valid after eviction. Implementations are safe
+// for concurrent use.
+type PacketStore interface {
+	// Add appends a packet.
+	Add(p *packet.Packet) error
+	// Get returns the i-th packet added. It returns ErrPacketEvicted if the
+	// store no longer holds it.
+	Get(i int) (*packet.Packet, error)
+	// Len returns the number of packets added since the last Reset.
+	Len() int
+	// Reset discards all packets.
+	Reset() error
+	// Close releases the store's resources.
+	Close() error
+}
+
+// WithPacketStore sets where LoadPackets retains packets. The default is an
+// unbounded MemoryStore. The caller owns the store and closes it.
+func WithPacketStore(store PacketStore) Option {
+	return func(v interface{}) {
+		if c := getCapture(v); c != nil {
+			c.store = store
+		}
+	}
+}
+
+func checkIndex(i, n int) error {
+	if i < 0 || i >= n {
+		return fmt.Errorf("packet index %d out of range [0, %d)", i, n)
+	}
+	return nil
+}
+
+// MemoryStore keeps every packet in memory. It is the default store.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	packets []*packet.Packet
+}
+
+// NewMemoryStore creates an unbounded in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+func (s *MemoryStore) Add(p *packet.Packet) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.packets = append(s.packets, p)
+	return nil
+}
+
+func (s *MemoryStore) Get(i int) (*packet.Packet, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if err := checkIndex(i, len(s.packets)); err != nil {
+		return nil, err
+	}
+	return s.packets[i], nil
+}
+
+func (s *MemoryStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.packets)
+}
+
+// Packets returns all stored packets.
+func (s *MemoryStore) Packets() []*packet.Packet {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.packets
+}
+
+func (s *MemoryStore) Reset() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.packets = nil
+	return nil
+}
+
+func (s *MemoryStore) Close() error {
+	return s.Reset()
+}
+
+// lruCache maps packet indexes to packets, evicting the least recently used
+// entry beyond capacity. It is not synchronized.
+type lruCache struct {
+	capacity int
+	order    *list.List // front = most recently used
+	items    map[int]*list.Element
+}
+
+type lruEntry struct {
+	index int
+	pkt   *packet.Packet
+}
+
+func newLRUCache(capacity int) *lruCache {
+	return &lruCache{capacity: capacity, order: list.New(), items: make(map[int]*list.Element)}
+}
+
+func (c *lruCache) get(i int) (*packet.Packet, bool) {
+	e, ok := c.items[i]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(*lruEntry).pkt, true
+}
+
+func (c *lruCache) put(i int, p *packet.Packet) {
+	if c.capacity <= 0 {
+		return
+	}
+	if e, ok := c.items[i]; ok {
+		e.Value.(*lruEntry).pkt = p
+		c.order.MoveToFront(e)
+		return
+	}
+	c.items[i] = c.order.PushFront(&lruEntry{index: i, pkt: p})
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruEntry).index)
+	}
+}
+
+func (c *lruCache) reset() {
+	c.order.Init()
+	c.items = make(map[int]*list.Element)
+}
+
+// LRUStore keeps at most a fixed number of packets in memory, evicting the
+// least recently added or accessed one. Evicted packets are gone: Get returns
+// ErrPacketEvicted for them.
+type LRUStore struct {
+	mu    sync.Mutex
+	cache *lruCache
+	n     int
+}
+
+// NewLRUStore creates a store retaining at most capacity packets.
+func NewLRUStore(capacity int) (*LRUStore, error) {
+	if capacity <= 0 {
+		return nil, fmt.Errorf("LRU store capacity must be positive, got %d", capacity)
+	}
+	return &LRUStore{cache: newLRUCache(capacity)}, nil
+}
+
+func (s *LRUStore) Add(p *packet.Packet) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cache.put(s.n, p)
+	s.n++
+	return nil
+}
+
+func (s *LRUStore) Get(i int) (*packet.Packet, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := checkIndex(i, s.n); err != nil {
+		return nil, err
+	}
+	if p, ok := s.cache.get(i); ok {
+		return p, nil
+	}
+	return nil, ErrPacketEvicted
+}
+
+func (s *LRUStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.n
+}
+
+func (s *LRUStore) Reset() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cache.reset()
+	s.n = 0
+	return nil
+}
+
+func (s *LRUStore) Close() error {
+	return s.Reset()
+}
+
+// storedPacket is the on-disk form of a packet. Layer fields are stored as
+// JSON, so numbers come back as float64 (as from tshark JSON output);
+// JSONLayer/XMLLayer/EKLayer are not stored.
+type storedPacket struct {
+	Index          string        `json:"index,omitempty"`
+	FrameNumber    string        `json:"frame_number,omitempty"`
+	FrameLen       string        `json:"frame_len,omitempty"`
+	FrameCapLen    string        `json:"frame_cap_len,omitempty"`
+	FrameTimeEpoch string        `json:"frame_time_epoch,omitempty"`
+	FrameTime      string        `json:"frame_time,omitempty"`
+	RawData        []byte        `json:"raw,omitempty"`
+	Layers         []storedLayer `json:"layers"`
+}
+
+type storedLayer struct {
+	Name    string                         `json:"name"`
+	Fields  map[string]interface{}         `json:"fields,omitempty"`
+	Offsets map[string]*packet.FieldOffset `json:"offsets,omitempty"`
+	Pos     int                            `json:"pos,omitempty"`
+	Len     int                            `json:"len,omitempty"`
+}
+
+func encodeStoredPacket(p *packet.Packet) ([]byte, error) {
+	if err := p.DecodeAll(); err != nil {
+		return nil, err
+	}
+	sp := storedPacket{
+		Index:          p.Index.ProtocolID,
+		FrameNumber:    p.FrameNumber,
+		FrameLen:       p.FrameLen,
+		FrameCapLen:    p.FrameCapLen,
+		FrameTimeEpoch: p.FrameTimeEpoch,
+		FrameTime:      p.FrameTime,
+		RawData:        p.RawData,
+		Layers:         make([]storedLayer, len(p.Layers)),
+	}
+	for i, l := range p.Layers {
+		sp.Layers[i] = storedLayer{Name: l.Name, Fields: l.Fields, Offsets: l.Offsets, Pos: l.Pos, Len: l.Len}
+	}
+	return json.Marshal(&sp)
+}
+
+func decodeStoredPacket(data []byte) (*packet.Packet, error) {
+	var sp storedPacket
+	if err := json.Unmarshal(data, &sp); err != nil {
+		return nil, err
+	}
+	p := &packet.Packet{
+		FrameNumber:    sp.FrameNumber,
+		FrameLen:       sp.FrameLen,
+		FrameCapLen:    sp.FrameCapLen,
+		FrameTimeEpoch: sp.FrameTimeEpoch,
+		FrameTime:      sp.FrameTime,
+		RawData:        sp.RawData,
+		Layers:         make([]packet.Layer, len(sp.Layers)),
+	}
+	p.Index.ProtocolID = sp.Index
+	for i, l := range sp.Layers {
+		if l.Fields == nil {
+			l.Fields = make(map[string]interface{})
+		}
+		if l.Offsets == nil {
+			l.Offsets = make(map[string]*packet.FieldOffset)
+		}
+		p.Layers[i] = packet.Layer{Name: l.Name, Fields: l.Fields, Offsets: l.Offsets, Pos: l.Pos, Len: l.Len}
+	}
+	return p, nil
+}
+
+// DiskStore spills packets to a temporary file and reloads them on Get, so
+// indexed access works on captures larger than memory. Only the file offset
+// of each packet and a small LRU cache of decoded packets are kept in memory.
+// Reloaded packets are copies: changes to them are not persisted.
+type DiskStore struct {
+	mu      sync.Mutex
+	file    *os.File
+	w       *bufio.Writer
+	size    int64   // bytes written, including unflushed ones
+	offsets []int64 // start of each packet; packet i ends at offsets[i+1] or size
+	cache   *lruCache
+}
+
+// NewDiskStore creates a store backed by a temporary file in dir (os.TempDir
+// when empty). cacheSize decoded packets are kept in memory; 0 disables the
+// cache. Close removes the file.
+func NewDiskStore(dir string, cacheSize int) (*DiskStore, error) {
+	f, err := os.CreateTemp(dir, "goshark-packets-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create packet store file: %w", err)
+	}
+	return &DiskStore{file: f, w: bufio.NewWriterSize(f, 256*1024), cache: newLRUCache(cacheSize)}, nil
+}
+
+// Path returns the backing file's path.
+func (s *DiskStore) Path() string {
+	return s.file.Name()
+}
+
+func (s *DiskStore) Add(p *packet.Packet) error {
+	data, err := encodeStoredPacket(p)
+	if err != nil {
+		return fmt.Errorf("failed to serialize packet: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.w == nil {
+		return fmt.Errorf("packet store is closed")
+	}
+	if _, err := s.w.Write(data); err != nil {
+		return fmt.Errorf("failed to write packet store: %w", err)
+	}
+	s.offsets = append(s.offsets, s.size)
+	s.size += int64(len(data))
+	return nil
+}
+
+func (s *DiskStore) Get(i int) (*packet.Packet, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := checkIndex(i, len(s.offsets)); err != nil {
+		return nil, err
+	}
+	if p, ok := s.cache.get(i); ok {
+		return p, nil
+	}
+	if s.w.Buffered() > 0 {
+		if err := s.w.Flush(); err != nil {
+			return nil, fmt.Errorf("failed to write packet store: %w", err)
+		}
+	}
+	end := s.size
+	if i+1 < len(s.offsets) {
+		end = s.offsets[i+1]
+	}
+	data := make([]byte, end-s.offsets[i])
+	if _, err := s.file.ReadAt(data, s.offsets[i]); err != nil {
+		return nil, fmt.Errorf("failed to read packet %d from store: %w", i, err)
+	}
+	p, err := decodeStoredPacket(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode packet %d from store: %w", i, err)
+	}
+	s.cache.put(i, p)
+	return p, nil
+}
+
+func (s *DiskStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.offsets)
+}
+
+func (s *DiskStore) Reset() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.w == nil {
+		return fmt.Errorf("packet store is closed")
+	}
+	s.w.Reset(s.file)
+	s.offsets, s.size = nil, 0
+	s.cache.reset()
+	if err := s.file.Truncate(0); err != nil {
+		return fmt.Errorf("failed to reset packet store: %w", err)
+	}
+	_, err := s.file.Seek(0, 0)
+	return err
+}
+
+// Close closes and removes the backing file.
+func (s *DiskStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.w == nil {
+		return nil
+	}
+	s.w = nil
+	s.offsets, s.size = nil, 0
+	s.cache.reset()
+	err := s.file.Close()
+	if rmErr := os.Remove(s.file.Name()); err == nil {
+		err = rmErr
+	}
+	return err
+}
@@ -0,0 +1,150 @@
+package capture
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/p-vbordei/GoShark/packet"
+)
+
+func storePacket(i int) *packet.Packet {
+	p := &packet.Packet{FrameNumber: fmt.Sprint(i + 1), RawData: []byte{byte(i), 0xff}}
+	p.Index.ProtocolID = "packets"
+	p.Layers = []packet.Layer{
+		{Name: "frame", Fields: map[string]interface{}{"frame.number": fmt.Sprint(i + 1)}},
+		{Name: "ip", Pos: 14, Len: 20,
+			Fields: map[string]interface{}{
+				"ip.src":       "10.0.0.1",
+				"ip.ttl_raw":   []interface{}{"40", float64(22), float64(1)},
+				"ip.opts_tree": map[string]interface{}{"ip.opt.type": "1"},
+			},
+			Offsets: map[string]*packet.FieldOffset{"ip.ttl": {Start: 22, Length: 1, Name: "ip.ttl"}}},
+	}
+	return p
+}
+
+func TestMemoryStore(t *testing.T) {
+	s := NewMemoryStore()
+	for i := 0; i < 3; i++ {
+		require.NoError(t, s.Add(storePacket(i)))
+	}
+	assert.Equal(t, 3, s.Len())
+	p, err := s.Get(2)
+	require.NoError(t, err)
+	assert.Equal(t, "3", p.FrameNumber)
+	_, err = s.Get(3)
+	assert.Error(t, err)
+
+	require.NoError(t, s.Reset())
+	assert.Equal(t, 0, s.Len())
+}
+
+func TestLRUStore(t *testing.T) {
+	_, err := NewLRUStore(0)
+	require.Error(t, err)
+
+	s, err := NewLRUStore(2)
+	require.NoError(t, err)
+	require.NoError(t, s.Add(storePacket(0)))
+	require.NoError(t, s.Add(storePacket(1)))
+	_, err = s.Get(0) // 0 becomes most recently used
+	require.NoError(t, err)
+	require.NoError(t, s.Add(storePacket(2))) // evicts 1
+
+	assert.Equal(t, 3, s.Len(), "indexes stay stable after eviction")
+	_, err = s.Get(1)
+	assert.ErrorIs(t, err, ErrPacketEvicted)
+	for _, i := range []int{0, 2} {
+		p, err := s.Get(i)
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprint(i+1), p.FrameNumber)
+	}
+	_, err = s.Get(5)
+	assert.NotErrorIs(t, err, ErrPacketEvicted)
+}
+
+func TestDiskStore(t *testing.T) {
+	s, err := NewDiskStore(t.TempDir(), 2)
+	require.NoError(t, err)
+	defer s.Close()
+
+	const n = 50
+	for i := 0; i < n; i++ {
+		require.NoError(t, s.Add(storePacket(i)))
+	}
+	assert.Equal(t, n, s.Len())
+
+	for _, i := range []int{0, 49, 17, 0} {
+		p, err := s.Get(i)
+		require.NoError(t, err)
+		want := storePacket(i)
+		assert.Equal(t, want.FrameNumber, p.FrameNumber)
+		assert.Equal(t, want.RawData, p.RawData)
+		assert.Equal(t, want.Index.ProtocolID, p.Index.ProtocolID)
+		ip := p.GetLayer("ip")
+		require.NotNil(t, ip)
+		assert.Equal(t, want.Layers[1].Fields, ip.Fields)
+		assert.Equal(t, 14, ip.Pos)
+		assert.Equal(t, 20, ip.Len)
+		assert.Equal(t, 22, ip.GetFieldOffset("ip.ttl").Start)
+	}
+
+	// Adding after a read appends past the flushed data.
+	require.NoError(t, s.Add(storePacket(n)))
+	p, err := s.Get(n)
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprint(n+1), p.FrameNumber)
+
+	require.NoError(t, s.Reset())
+	assert.Equal(t, 0, s.Len())
+	require.NoError(t, s.Add(storePacket(7)))
+	p, err = s.Get(0)
+	require.NoError(t, err)
+	assert.Equal(t, "8", p.FrameNumber)
+
+	path := s.Path()
+	require.NoError(t, s.Close())
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err), "Close removes the backing file")
+}
+
+func TestLoadPacketsWithStore(t *testing.T) {
+	start := func() (io.ReadCloser, io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader(jsonStream(20))), io.NopCloser(strings.NewReader("")), nil
+	}
+
+	lru, err := NewLRUStore(5)
+	require.NoError(t, err)
+	c := NewCapture(WithPacketStore(lru))
+	pkts, err := c.LoadPackets(context.Background(), 0, start)
+	require.NoError(t, err)
+	assert.Nil(t, pkts, "bounded stores do not return the whole capture")
+	assert.Equal(t, 20, c.Len())
+	assert.Nil(t, c.Get(0), "evicted")
+	assert.Equal(t, "20", c.Get(19).FrameNumber)
+	assert.Len(t, c.Packets(), 5)
+
+	disk, err := NewDiskStore(t.TempDir(), 0)
+	require.NoError(t, err)
+	defer disk.Close()
+	c = NewCapture(WithPacketStore(disk))
+	_, err = c.LoadPackets(context.Background(), 0, start)
+	require.NoError(t, err)
+	assert.Equal(t, 20, c.Len())
+	assert.Equal(t, "5", c.Get(4).FrameNumber)
+	assert.Equal(t, "5", c.Get(4).GetLayer("udp").GetField("udp.srcport"))
+
+	// The default store returns and retains everything.
+	c = NewCapture()
+	pkts, err = c.LoadPackets(context.Background(), 10, start)
+	require.NoError(t, err)
+	assert.Len(t, pkts, 10)
+	assert.Equal(t, 10, c.Len())
+}
@@ -1,10 +1,14 @@
 package capture
 
 import (
+	"bufio"
+	"bytes"
 	"fmt"
 	"io"
+	"os"
 	"os/exec"
 
+	"github.com/p-vbordei/GoShark/pcapfile"
 	"github.com/p-vbordei/GoShark/tshark"
 )
 
@@ -32,12 +36,30 @@ func (pc *PipeCapture) Start() (stdout io.ReadCloser, stderr io.ReadCloser, err
 		return nil, nil, fmt.Errorf("failed to get tshark arguments: %w", err)
 	}
 
-	// Add -r - to read from stdin
-	tsharkArgs = append(tsharkArgs, "-r", "-")
+	// Add -r - to read from stdin. Two-pass analysis needs a seekable file,
+	// so the pipe is spooled to a temporary file first.
+	input := "-"
+	if pc.twoPass() {
+		pipe := bufio.NewReader(pc.pipe)
+		input, err = spoolCapture(peekFormat(pipe), func(w io.Writer) error {
+			_, err := io.Copy(w, pipe)
+			return err
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	tsharkArgs = append(tsharkArgs, "-r", input)
+	cleanup := func() {
+		if input != "-" {
+			os.Remove(input)
+		}
+	}
 
 	// Get tshark path
 	tsharkPath, err := pc.getTSharkPath()
 	if err != nil {
+		cleanup()
 		return nil, nil, fmt.Errorf("failed to get tshark path: %w", err)
 	}
 
@@ -45,30 +67,49 @@ func (pc *PipeCapture) Start() (stdout io.ReadCloser, stderr io.ReadCloser, err
 	cmd := exec.Command(tsharkPath, tsharkArgs...)
 
 	// Set stdin to the pipe
-	cmd.Stdin = pc.pipe
+	if input == "-" {
+		cmd.Stdin = pc.pipe
+	}
 
 	// Get stdout and stderr pipes
 	stdoutPipe, err := cmd.StdoutPipe()
 	if err != nil {
+		cleanup()
 		return nil, nil, fmt.Errorf("failed to get stdout pipe: %w", err)
 	}
 
 	stderrPipe, err := cmd.StderrPipe()
 	if err != nil {
+		cleanup()
 		return nil, nil, fmt.Errorf("failed to get stderr pipe: %w", err)
 	}
 
 	// Start the command
 	if err := cmd.Start(); err != nil {
+		cleanup()
 		return nil, nil, fmt.Errorf("failed to start tshark command: %w", err)
 	}
 
 	// Store the command
 	pc.cmd = cmd
 
+	if input != "-" {
+		return &removeOnClose{ReadCloser: stdoutPipe, path: input}, stderrPipe, nil
+	}
 	return stdoutPipe, stderrPipe, nil
 }
 
+// peekFormat detects the capture format at the start of r without consuming
+// it.
+func peekFormat(r *bufio.Reader) pcapfile.Format {
+	magic, _ := r.Peek(4)
+	rd, err := pcapfile.NewReader(bytes.NewReader(magic))
+	if err != nil {
+		return pcapfile.FormatUnknown
+	}
+	return rd.Format()
+}
+
 // getTSharkPath returns the path to the tshark executable.
 func (pc *PipeCapture) getTSharkPath() (string, error) {
 	if pc.TSharkPath != "" {
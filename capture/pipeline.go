@@ -0,0 +1,320 @@
+package capture
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io"
+	"sync"
+
+	"github.com/p-vbordei/GoShark/packet"
+	"github.com/p-vbordei/GoShark/packet/layers"
+	"github.com/p-vbordei/GoShark/tshark"
+	"github.com/p-vbordei/GoShark/tshark/ek_field_mapping"
+)
+
+// reorderWindowPerWorker bounds how many packets may be framed ahead of the
+// oldest one still being decoded, per worker. It caps the reorder buffer.
+const reorderWindowPerWorker = 16
+
+// WithDecodeWorkers decodes tshark output on n goroutines. The stream is cut
+// into per-packet chunks (JSON objects, EK lines, PDML <packet> elements) on
+// one goroutine, decoded in parallel, and re-sequenced so packets are still
+// delivered in frame order. n <= 1 decodes on the reading goroutine.
+func WithDecodeWorkers(n int) Option {
+	return func(v interface{}) {
+		if c := getCapture(v); c != nil {
+			c.DecodeWorkers = n
+		}
+	}
+}
+
+// frameFunc returns the next undecoded packet chunk, or io.EOF. The chunk
+// may be reused by the next call unless the framer says otherwise.
+type frameFunc func() ([]byte, error)
+
+// decodeFunc decodes one chunk. ok is false for chunks that carry no packet
+// (EK index lines, PDML packets that fail conversion); an error ends the
+// stream.
+type decodeFunc func(chunk []byte) (pkt *packet.Packet, ok bool, err error)
+
+// streamFormat builds the framer for stdout and a factory for per-worker
+// decoders according to the capture's output format. reusesChunks reports
+// whether frames must be copied before being handed to another goroutine.
+func (c *Capture) streamFormat(stdout io.Reader, ekMappings *ek_field_mapping.FieldMappings) (next frameFunc, newDecoder func() decodeFunc, reusesChunks bool) {
+	switch {
+	case c.UseEK:
+		return ekFramer(stdout), func() decodeFunc {
+			parser := tshark.NewEKParser(tshark.WithEKIncludeRaw(c.IncludeRaw),
+				tshark.WithEKFieldMappings(ekMappings))
+			return func(chunk []byte) (*packet.Packet, bool, error) {
+				return parser.ParseRecord(chunk)
+			}
+		}, false
+	case c.UseJSON:
+		opts := packet.DecoderOptions{LazyLayers: c.LazyLayers}
+		framer := packet.NewDecoder(stdout, opts)
+		return framer.NextRaw, func() decodeFunc {
+			decoder := packet.NewDecoder(nil, opts)
+			return func(chunk []byte) (*packet.Packet, bool, error) {
+				pkt, err := decoder.DecodePacket(chunk)
+				if err != nil {
+					return nil, false, err
+				}
+				// Populate JSON layers; lazily decoded layers have no fields yet.
+				if !c.LazyLayers {
+					for i := range pkt.Layers {
+						pkt.Layers[i].JSONLayer = layers.NewJSONLayer(pkt.Layers[i].Name, pkt.Layers[i].Fields, pkt.Layers[i].Name, false)
+					}
+				}
+				return pkt, true, nil
+			}
+		}, true
+	default:
+		return pdmlFramer(stdout), func() decodeFunc {
+			parser := tshark.NewXMLParser(tshark.WithXMLIncludeRaw(c.IncludeRaw))
+			return func(chunk []byte) (*packet.Packet, bool, error) {
+				var pdmlPacket tshark.PDMLPacket
+				if err := xml.Unmarshal(chunk, &pdmlPacket); err != nil {
+					return nil, false, err
+				}
+				// Convert PDMLPacket to packet.Packet using XMLParser's logic
+				pkt, err := parser.ConvertPDMLPacket(&pdmlPacket)
+				return pkt, err == nil, nil
+			}
+		}, false
+	}
+}
+
+// withPacketHook runs hook on every packet the decoders produce.
+func withPacketHook(newDecoder func() decodeFunc, hook func(*packet.Packet)) func() decodeFunc {
+	return func() decodeFunc {
+		decode := newDecoder()
+		return func(chunk []byte) (*packet.Packet, bool, error) {
+			pkt, ok, err := decode(chunk)
+			if ok && err == nil {
+				hook(pkt)
+			}
+			return pkt, ok, err
+		}
+	}
+}
+
+// ekFramer cuts EK output into records: tshark writes one JSON record per
+// line, alternating {"index":...} metadata and packet records.
+func ekFramer(r io.Reader) frameFunc {
+	br := bufio.NewReaderSize(r, 64*1024)
+	return func() ([]byte, error) {
+		for {
+			line, err := br.ReadBytes('\n')
+			if trimmed := bytes.TrimSpace(line); len(trimmed) > 0 {
+				return trimmed, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+var (
+	pdmlPacketStart = []byte("<packet")
+	pdmlPacketEnd   = []byte("</packet>")
+)
+
+// pdmlFramer cuts PDML output into <packet> elements. The closing tag cannot
+// occur inside attribute values (tshark escapes '<'), so a textual search is
+// enough; the bytes around packets (<pdml>, comments) are discarded.
+func pdmlFramer(r io.Reader) frameFunc {
+	br := bufio.NewReaderSize(r, 64*1024)
+	var pending []byte
+	eof := false
+	return func() ([]byte, error) {
+		for {
+			start := indexPDMLPacketStart(pending)
+			if start >= 0 {
+				if end := bytes.Index(pending[start:], pdmlPacketEnd); end >= 0 {
+					end += start + len(pdmlPacketEnd)
+					chunk := append([]byte(nil), pending[start:end]...)
+					pending = append(pending[:0], pending[end:]...)
+					return chunk, nil
+				}
+				pending = append(pending[:0], pending[start:]...)
+			} else if len(pending) > len(pdmlPacketStart) {
+				// Keep only a tail that could hold a split "<packet".
+				pending = append(pending[:0], pending[len(pending)-len(pdmlPacketStart):]...)
+			}
+			if eof {
+				return nil, io.EOF
+			}
+			line, err := br.ReadSlice('\n')
+			pending = append(pending, line...)
+			switch err {
+			case nil, bufio.ErrBufferFull:
+			case io.EOF:
+				eof = true
+			default:
+				return nil, err
+			}
+		}
+	}
+}
+
+// indexPDMLPacketStart finds a "<packet" start tag, skipping "<packets" and
+// similar longer element names.
+func indexPDMLPacketStart(b []byte) int {
+	offset := 0
+	for {
+		i := bytes.Index(b[offset:], pdmlPacketStart)
+		if i < 0 {
+			return -1
+		}
+		i += offset
+		after := i + len(pdmlPacketStart)
+		if after >= len(b) {
+			return -1 // cannot tell yet
+		}
+		switch b[after] {
+		case '>', ' ', '\t', '\n', '\r', '/':
+			return i
+		}
+		offset = after
+	}
+}
+
+// decodeStream frames stdout and decodes each chunk, sending packets to out
+// in stream order until the input ends, a chunk fails to decode, or ctx is
+// canceled. With workers > 1 decoding runs in parallel.
+func decodeStream(ctx context.Context, next frameFunc, newDecoder func() decodeFunc,
+	reusesChunks bool, workers int, out chan<- *packet.Packet) {
+	if workers > 1 {
+		decodeParallel(ctx, next, newDecoder, reusesChunks, workers, out)
+		return
+	}
+
+	decode := newDecoder()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+		chunk, err := next()
+		if err != nil {
+			return
+		}
+		pkt, ok, err := decode(chunk)
+		if err != nil {
+			return
+		}
+		if !ok {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case out <- pkt:
+		}
+	}
+}
+
+// decodeParallel is decodeStream's multi-worker path: a framing goroutine,
+// a pool of decode workers and a reorder buffer that releases packets in
+// sequence. At most workers*reorderWindowPerWorker chunks are in flight.
+func decodeParallel(ctx context.Context, next frameFunc, newDecoder func() decodeFunc,
+	reusesChunks bool, workers int, out chan<- *packet.Packet) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type job struct {
+		seq   uint64
+		chunk []byte
+	}
+	type result struct {
+		seq uint64
+		pkt *packet.Packet
+		ok  bool
+		err error
+	}
+
+	window := make(chan struct{}, workers*reorderWindowPerWorker)
+	jobs := make(chan job, workers)
+	results := make(chan result, workers)
+
+	// Framing stage.
+	go func() {
+		defer close(jobs)
+		var seq uint64
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case window <- struct{}{}:
+			}
+			chunk, err := next()
+			if err != nil {
+				<-window
+				return
+			}
+			if reusesChunks {
+				chunk = append([]byte(nil), chunk...)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case jobs <- job{seq: seq, chunk: chunk}:
+			}
+			seq++
+		}
+	}()
+
+	// Decode workers, each with its own decoder state.
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			decode := newDecoder()
+			for j := range jobs {
+				pkt, ok, err := decode(j.chunk)
+				select {
+				case <-ctx.Done():
+					return
+				case results <- result{seq: j.seq, pkt: pkt, ok: ok, err: err}:
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// Reorder buffer: release results strictly in sequence.
+	pending := make(map[uint64]result)
+	var nextSeq uint64
+	for r := range results {
+		pending[r.seq] = r
+		for {
+			r, ok := pending[nextSeq]
+			if !ok {
+				break
+			}
+			delete(pending, nextSeq)
+			nextSeq++
+			<-window
+			if r.err != nil {
+				return
+			}
+			if !r.ok {
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case out <- r.pkt:
+			}
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package capture
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/p-vbordei/GoShark/packet"
+)
+
+func jsonStream(n int) string {
+	var b strings.Builder
+	b.WriteString("[\n")
+	for i := 1; i <= n; i++ {
+		if i > 1 {
+			b.WriteString(",\n")
+		}
+		fmt.Fprintf(&b, `{"_source":{"layers":{"frame":{"frame.number":"%d"},"udp":{"udp.srcport":"%d","udp.payload":"%s"}}}}`,
+			i, i, strings.Repeat("ab:", i%50))
+	}
+	b.WriteString("\n]\n")
+	return b.String()
+}
+
+func ekStream(n int) string {
+	var b strings.Builder
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&b, "{\"index\":{\"_index\":\"packets-%d\"}}\n", i)
+		fmt.Fprintf(&b, "{\"timestamp\":\"1620067200000\",\"layers\":{\"frame\":{\"frame_frame_number\":\"%d\"}}}\n", i)
+	}
+	return b.String()
+}
+
+func pdmlStream(n int) string {
+	var b strings.Builder
+	b.WriteString("<?xml version=\"1.0\"?>\n<pdml version=\"0\">\n")
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&b, "<packet>\n  <proto name=\"frame\">\n    <field name=\"frame.number\" show=\"%d\"/>\n  </proto>\n</packet>\n", i)
+	}
+	b.WriteString("</pdml>\n")
+	return b.String()
+}
+
+// sniffAll drains sniffStream over output and returns the frame numbers.
+func sniffAll(t *testing.T, c *Capture, output io.Reader) []string {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	pkts, err := c.sniffStream(ctx, io.NopCloser(output), io.NopCloser(strings.NewReader("")))
+	require.NoError(t, err)
+	var frames []string
+	for p := range pkts {
+		frames = append(frames, p.FrameNumber)
+	}
+	require.NoError(t, ctx.Err(), "stream did not finish")
+	return frames
+}
+
+func sequence(n int) []string {
+	out := make([]string, n)
+	for i := range out {
+		out[i] = fmt.Sprint(i + 1)
+	}
+	return out
+}
+
+func TestDecodeWorkersPreserveOrder(t *testing.T) {
+	const n = 500
+	tests := []struct {
+		name   string
+		opts   []Option
+		output string
+	}{
+		{"json", nil, jsonStream(n)},
+		{"json-lazy", []Option{WithLazyLayers(true)}, jsonStream(n)},
+		{"ek", []Option{WithUseEK(true)}, ekStream(n)},
+		{"pdml", []Option{WithUseJSON(false)}, pdmlStream(n)},
+	}
+	for _, tt := range tests {
+		for _, workers := range []int{1, 4} {
+			t.Run(fmt.Sprintf("%s/workers=%d", tt.name, workers), func(t *testing.T) {
+				c := NewCapture(append(tt.opts, WithDecodeWorkers(workers))...)
+				assert.Equal(t, sequence(n), sniffAll(t, c, strings.NewReader(tt.output)))
+			})
+		}
+	}
+}
+
+func TestPDMLFramerSplitReads(t *testing.T) {
+	// One byte per read splits every tag across reads.
+	c := NewCapture(WithUseJSON(false), WithDecodeWorkers(3))
+	got := sniffAll(t, c, iotest.OneByteReader(strings.NewReader(pdmlStream(20))))
+	assert.Equal(t, sequence(20), got)
+}
+
+func TestDecodeWorkersStopAtBadPacket(t *testing.T) {
+	// Packet 4 is malformed: packets before it are delivered in order, none after.
+	output := strings.Replace(jsonStream(10), `"frame.number":"4"}`, `"frame.number":}`, 1)
+	c := NewCapture(WithDecodeWorkers(4))
+	assert.Equal(t, sequence(3), sniffAll(t, c, strings.NewReader(output)))
+}
+
+func TestDecodeWorkersEarlyStop(t *testing.T) {
+	c := NewCapture(WithDecodeWorkers(4))
+	var got []*packet.Packet
+	err := c.ApplyOnPackets(func(p *packet.Packet) bool {
+		got = append(got, p)
+		return len(got) == 5
+	}, context.Background(), func() (io.ReadCloser, io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader(jsonStream(1000))), io.NopCloser(strings.NewReader("")), nil
+	})
+	require.NoError(t, err)
+	require.Len(t, got, 5)
+	assert.Equal(t, "5", got[4].FrameNumber)
+}
+
+func BenchmarkSniffStreamWorkers(b *testing.B) {
+	output := jsonStream(2000)
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			b.SetBytes(int64(len(output)))
+			c := NewCapture(WithDecodeWorkers(workers))
+			for i := 0; i < b.N; i++ {
+				pkts, err := c.sniffStream(context.Background(),
+					io.NopCloser(strings.NewReader(output)), io.NopCloser(strings.NewReader("")))
+				if err != nil {
+					b.Fatal(err)
+				}
+				for range pkts {
+				}
+			}
+		})
+	}
+}
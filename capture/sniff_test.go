@@ -11,6 +11,7 @@ import (
 
 	"github.com/p-vbordei/GoShark/packet"
 	"github.com/p-vbordei/GoShark/packet/layers"
+	"github.com/p-vbordei/GoShark/tshark/ek_field_mapping"
 )
 
 func TestSniffStreamJSON(t *testing.T) {
@@ -200,3 +201,31 @@ func TestSniffStreamCancellation(t *testing.T) {
 		t.Fatal("Timeout waiting for channel closure after cancellation")
 	}
 }
+
+func TestSniffStreamEKFieldMappings(t *testing.T) {
+	mappings := ek_field_mapping.NewFieldMappings()
+	mappings.AddMapping("udp", "udp_udp_srcport", "int")
+
+	ekData := `{"index":{"_index":"packets-1"}}
+{"timestamp":"1620067200000","layers":{"frame":{"frame_frame_number":"1"},"udp":{"udp_udp_srcport":"53"}}}
+`
+	c := NewCapture(WithUseEK(true), WithEKFieldMappings(mappings))
+	stdout := io.NopCloser(strings.NewReader(ekData))
+	stderr := io.NopCloser(strings.NewReader(""))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	packetsChan, err := c.sniffStream(ctx, stdout, stderr)
+	assert.NoError(t, err)
+
+	var p *packet.Packet
+	select {
+	case p = <-packetsChan:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timeout waiting for EK packet")
+	}
+	assert.NotNil(t, p)
+	assert.Equal(t, "1", p.FrameNumber)
+	assert.Equal(t, 53, p.GetLayer("udp").Fields["udp_udp_srcport"])
+}
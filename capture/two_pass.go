@@ -0,0 +1,110 @@
+package capture
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/p-vbordei/GoShark/pcapfile"
+)
+
+// WithTwoPass enables tshark's two-pass analysis (-2) on file and pipe
+// captures. The first pass reads the whole capture, so fields that depend on
+// later frames (tcp.analysis.*, request_in/response_in links, reassembled
+// PDU boundaries) are reliable; output starts only after the first pass.
+// Live captures do not support it.
+func WithTwoPass() Option {
+	return func(v interface{}) {
+		if c := getCapture(v); c != nil {
+			c.TwoPass = true
+		}
+	}
+}
+
+// WithReadFilter sets a read filter (-R), applied during the first pass of a
+// two-pass analysis. Unlike the display filter (-Y), frames it rejects are
+// dropped before the second pass, so later frames cannot refer to them. It
+// implies WithTwoPass.
+func WithReadFilter(filter string) Option {
+	return func(v interface{}) {
+		if c := getCapture(v); c != nil {
+			c.ReadFilter = filter
+		}
+	}
+}
+
+// WithTCPDesegment toggles reassembly of PDUs spanning TCP segments
+// (tcp.desegment_tcp_streams). Reassembled PDUs are attributed to the frame
+// carrying their last segment.
+func WithTCPDesegment(on bool) Option {
+	return WithOverridePreferences("tcp.desegment_tcp_streams:" + prefBool(on))
+}
+
+// WithIPDefragment toggles reassembly of fragmented IPv4 and IPv6 datagrams
+// (ip.defragment, ipv6.defragment).
+func WithIPDefragment(on bool) Option {
+	return WithOverridePreferences("ip.defragment:"+prefBool(on), "ipv6.defragment:"+prefBool(on))
+}
+
+// prefBool formats a boolean tshark preference value.
+func prefBool(on bool) string {
+	if on {
+		return "TRUE"
+	}
+	return "FALSE"
+}
+
+// twoPass reports whether tshark runs a two-pass analysis. tshark cannot
+// read its input twice from a pipe, so captures that feed tshark on stdin
+// spool the input to a temporary file instead.
+func (c *Capture) twoPass() bool {
+	return c.TwoPass || c.ReadFilter != ""
+}
+
+// liveTwoPassError rejects two-pass options on a live capture, which has no
+// end for the first pass to reach.
+func (c *Capture) liveTwoPassError() error {
+	if c.twoPass() {
+		return fmt.Errorf("two-pass analysis and read filters are not supported on live captures")
+	}
+	return nil
+}
+
+// spoolCapture writes a capture file of the given format with write into a
+// temporary file and returns its path. The file is named after the format,
+// or has no extension when it is unknown, so tshark detects it by content.
+func spoolCapture(format pcapfile.Format, write func(io.Writer) error) (string, error) {
+	pattern := "goshark-*"
+	if format != pcapfile.FormatUnknown {
+		pattern += "." + format.String()
+	}
+	f, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary capture file: %w", err)
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", fmt.Errorf("failed to write temporary capture file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("failed to write temporary capture file: %w", err)
+	}
+	return f.Name(), nil
+}
+
+// removeOnClose removes a spooled capture file once tshark's output has been
+// consumed.
+type removeOnClose struct {
+	io.ReadCloser
+	path string
+	once sync.Once
+}
+
+func (r *removeOnClose) Close() error {
+	err := r.ReadCloser.Close()
+	r.once.Do(func() { os.Remove(r.path) })
+	return err
+}
@@ -0,0 +1,73 @@
+package capture
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/p-vbordei/GoShark/pcapfile"
+)
+
+func TestTwoPassTSharkArgs(t *testing.T) {
+	cap := NewCapture(WithTwoPass())
+	args, err := cap.getTSharkArgs()
+	require.NoError(t, err)
+	assert.Contains(t, args, "-2")
+	assert.NotContains(t, args, "-R")
+
+	cap = NewCapture(WithReadFilter("tcp"))
+	args, err = cap.getTSharkArgs()
+	require.NoError(t, err)
+	assert.Contains(t, args, "-2", "a read filter implies two-pass")
+	assert.True(t, containsPair(args, "-R", "tcp"))
+
+	cap = NewCapture(WithTCPDesegment(false), WithIPDefragment(true))
+	args, err = cap.getTSharkArgs()
+	require.NoError(t, err)
+	assert.True(t, containsPair(args, "-o", "tcp.desegment_tcp_streams:FALSE"))
+	assert.True(t, containsPair(args, "-o", "ip.defragment:TRUE"))
+	assert.True(t, containsPair(args, "-o", "ipv6.defragment:TRUE"))
+}
+
+func TestLiveCaptureRejectsTwoPass(t *testing.T) {
+	lc := &LiveCapture{Capture: NewCapture(WithReadFilter("dns"))}
+	_, _, err := lc.Start()
+	assert.ErrorContains(t, err, "not supported on live captures")
+}
+
+func TestSpoolCapture(t *testing.T) {
+	path, err := spoolCapture(pcapfile.FormatPcap, func(w io.Writer) error {
+		_, err := io.WriteString(w, "frames")
+		return err
+	})
+	require.NoError(t, err)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "frames", string(data))
+
+	rc := &removeOnClose{ReadCloser: io.NopCloser(strings.NewReader("")), path: path}
+	require.NoError(t, rc.Close())
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err), "spooled file removed on close")
+
+	assert.True(t, strings.HasSuffix(path, ".pcap"), "spool file named after its format")
+
+	_, err = spoolCapture(pcapfile.FormatUnknown, func(w io.Writer) error { return io.ErrUnexpectedEOF })
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
+
+func TestPipeCaptureTwoPassStartFailureRemovesSpool(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	header := []byte{0xd4, 0xc3, 0xb2, 0xa1, 2, 0, 4, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xff, 0, 0, 1, 0, 0, 0}
+	pc := NewPipeCapture(strings.NewReader(string(header)), WithTwoPass(), WithTSharkPath("/nonexistent/tshark"))
+	_, _, err := pc.Start()
+	require.Error(t, err)
+	entries, err := os.ReadDir(tmp)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "spooled file removed when tshark fails to start")
+}
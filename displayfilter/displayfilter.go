@@ -0,0 +1,211 @@
+// Package displayfilter builds Wireshark display filters from Go values, so
+// filters are never assembled by string concatenation. Values are quoted and
+// escaped for their type, and compound expressions are parenthesised so
+// operator precedence never changes their meaning.
+//
+//	f := displayfilter.And(
+//		displayfilter.Field("ip.src").Eq(netip.MustParseAddr("10.0.0.1")),
+//		displayfilter.Field("http.request.uri").Contains("/login"),
+//	)
+//	capture.WithDisplayFilter(f.String())
+package displayfilter
+
+import "strings"
+
+// Expr is a display-filter expression that evaluates to true or false.
+type Expr interface {
+	// String returns the expression in display-filter syntax.
+	String() string
+	// compound reports whether the expression must be parenthesised when
+	// nested inside another logical operator.
+	compound() bool
+}
+
+// Value is an operand of a comparison: a field, a slice of one, a function
+// call or a literal.
+type Value struct {
+	text string
+}
+
+// Field references a field or protocol by its filter name, e.g. "ip.src".
+func Field(name string) Value {
+	return Value{text: name}
+}
+
+// Literal is a value written verbatim, e.g. an enumeration name or a
+// number in a base the caller has already formatted.
+func Literal(text string) Value {
+	return Value{text: text}
+}
+
+// String returns the operand in display-filter syntax.
+func (v Value) String() string {
+	return v.text
+}
+
+// Slice selects length bytes starting at offset (negative counts from the
+// end), e.g. eth.src[0:3].
+func (v Value) Slice(offset, length int) Value {
+	return Value{text: v.text + "[" + itoa(offset) + ":" + itoa(length) + "]"}
+}
+
+// SliceRange selects bytes first through last inclusive, e.g. eth.src[1-2].
+func (v Value) SliceRange(first, last int) Value {
+	return Value{text: v.text + "[" + itoa(first) + "-" + itoa(last) + "]"}
+}
+
+// Func calls a display-filter function, e.g. Func("len", Field("tcp.payload")).
+func Func(name string, args ...Value) Value {
+	parts := make([]string, len(args))
+	for i, a := range args {
+		parts[i] = a.text
+	}
+	return Value{text: name + "(" + strings.Join(parts, ", ") + ")"}
+}
+
+// Len is len(v): the length in bytes of a field or string.
+func Len(v Value) Value { return Func("len", v) }
+
+// Count is count(v): how many times a field occurs in the packet.
+func Count(v Value) Value { return Func("count", v) }
+
+// Lower is lower(v), a string converted to lower case.
+func Lower(v Value) Value { return Func("lower", v) }
+
+// Upper is upper(v), a string converted to upper case.
+func Upper(v Value) Value { return Func("upper", v) }
+
+// Exists is true when the field or protocol is present.
+func (v Value) Exists() Expr { return test{v.text} }
+
+// Eq is v == x.
+func (v Value) Eq(x interface{}) Expr { return v.compare("==", x) }
+
+// Ne is v != x.
+func (v Value) Ne(x interface{}) Expr { return v.compare("!=", x) }
+
+// Gt is v > x.
+func (v Value) Gt(x interface{}) Expr { return v.compare(">", x) }
+
+// Ge is v >= x.
+func (v Value) Ge(x interface{}) Expr { return v.compare(">=", x) }
+
+// Lt is v < x.
+func (v Value) Lt(x interface{}) Expr { return v.compare("<", x) }
+
+// Le is v <= x.
+func (v Value) Le(x interface{}) Expr { return v.compare("<=", x) }
+
+// Contains is true when the field contains x, a string or bytes.
+func (v Value) Contains(x interface{}) Expr { return v.compare("contains", x) }
+
+// Matches is true when the field matches the PCRE regular expression re.
+func (v Value) Matches(re string) Expr { return v.compare("matches", re) }
+
+// BitAnd is true when v & mask is non-zero.
+func (v Value) BitAnd(mask interface{}) Expr { return v.compare("&", mask) }
+
+// In is true when the field equals one of values.
+func (v Value) In(values ...interface{}) Expr {
+	parts := make([]string, len(values))
+	for i, x := range values {
+		parts[i] = Format(x)
+	}
+	return test{v.text + " in {" + strings.Join(parts, " ") + "}"}
+}
+
+func (v Value) compare(op string, x interface{}) Expr {
+	return test{v.text + " " + op + " " + Format(x)}
+}
+
+// test is a comparison or existence test.
+type test struct {
+	text string
+}
+
+func (t test) String() string { return t.text }
+func (t test) compound() bool { return false }
+
+// Raw is a filter written verbatim. It is parenthesised when nested.
+func Raw(filter string) Expr {
+	return raw{filter}
+}
+
+type raw struct {
+	text string
+}
+
+func (r raw) String() string { return r.text }
+func (r raw) compound() bool { return true }
+
+// logical joins expressions with && or ||.
+type logical struct {
+	op    string
+	exprs []Expr
+}
+
+// And is true when every expression is. Nil and empty expressions are
+// skipped; And() with none is the empty filter, which matches everything.
+func And(exprs ...Expr) Expr {
+	return join("&&", exprs)
+}
+
+// Or is true when any expression is. Nil and empty expressions are skipped.
+func Or(exprs ...Expr) Expr {
+	return join("||", exprs)
+}
+
+func join(op string, exprs []Expr) Expr {
+	var kept []Expr
+	for _, e := range exprs {
+		if e != nil && e.String() != "" {
+			kept = append(kept, e)
+		}
+	}
+	if len(kept) == 1 {
+		return kept[0]
+	}
+	return logical{op: op, exprs: kept}
+}
+
+func (l logical) String() string {
+	parts := make([]string, len(l.exprs))
+	for i, e := range l.exprs {
+		parts[i] = nested(e)
+	}
+	return strings.Join(parts, " "+l.op+" ")
+}
+
+func (l logical) compound() bool { return len(l.exprs) > 1 }
+
+// Not negates an expression. Negating a nil or empty expression gives the
+// empty expression, which And and Or skip.
+func Not(e Expr) Expr {
+	if e == nil || e.String() == "" {
+		return logical{}
+	}
+	return not{e}
+}
+
+type not struct {
+	expr Expr
+}
+
+func (n not) String() string {
+	if s, ok := n.expr.(test); ok && !strings.Contains(s.text, " ") {
+		return "!" + s.text
+	}
+	return "!(" + n.expr.String() + ")"
+}
+
+func (n not) compound() bool { return false }
+
+// nested renders e as an operand of a logical operator. Wireshark has given
+// && and || different precedences across releases, so compound operands are
+// always parenthesised.
+func nested(e Expr) string {
+	if e.compound() {
+		return "(" + e.String() + ")"
+	}
+	return e.String()
+}
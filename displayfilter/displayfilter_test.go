@@ -0,0 +1,119 @@
+package displayfilter
+
+import (
+	"net"
+	"net/netip"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/p-vbordei/GoShark/packet"
+)
+
+func TestBuilder(t *testing.T) {
+	tests := []struct {
+		expr Expr
+		want string
+	}{
+		{Field("ip.src").Eq(netip.MustParseAddr("10.0.0.1")), "ip.src == 10.0.0.1"},
+		{Field("ip.addr").Eq(netip.MustParsePrefix("10.0.0.0/8")), "ip.addr == 10.0.0.0/8"},
+		{Field("eth.src").Eq(net.HardwareAddr{0, 0x1b, 0x21, 1, 2, 3}), "eth.src == 00:1b:21:01:02:03"},
+		{Field("tcp.port").Ge(1024), "tcp.port >= 1024"},
+		{Field("http.host").Eq(`a"b\c`), `http.host == "a\"b\\c"`},
+		{Field("http.user_agent").Contains("curl\x00\n"), `http.user_agent contains "curl\x00\n"`},
+		{Field("tcp.payload").Contains([]byte("GET")), "tcp.payload contains 47:45:54"},
+		{Field("udp.payload").Contains([]byte{0x7f}), `udp.payload contains "\x7f"`},
+		{Field("dns.qry.name").Matches(`^www\.`), `dns.qry.name matches "^www\\."`},
+		{Field("tcp.port").In(80, 443, 8080), "tcp.port in {80 443 8080}"},
+		{Field("tcp.flags").BitAnd(Literal("0x02")), "tcp.flags & 0x02"},
+		{Field("eth.src").Slice(0, 3).Eq([]byte{0, 0x1b, 0x21}), "eth.src[0:3] == 00:1b:21"},
+		{Field("frame").SliceRange(-4, -1).Exists(), "frame[-4--1]"},
+		{Len(Field("tcp.payload")).Gt(100), "len(tcp.payload) > 100"},
+		{Lower(Field("http.host")).Eq("example.com"), `lower(http.host) == "example.com"`},
+		{Count(Field("ip.addr")).Eq(2), "count(ip.addr) == 2"},
+		{Field("ip.src").Eq(Field("ip.dst")), "ip.src == ip.dst"},
+		{Field("tcp.analysis.retransmission").Eq(true), "tcp.analysis.retransmission == 1"},
+		{Field("frame.time_delta").Gt(1500 * time.Millisecond), "frame.time_delta > 1.5"},
+		{Field("frame.time").Ge(time.Date(2025, 5, 3, 10, 0, 0, 0, time.UTC)),
+			`frame.time >= "May 03, 2025 10:00:00.000000000 UTC"`},
+		{Not(Field("arp").Exists()), "!arp"},
+		{Not(Field("tcp.port").Eq(22)), "!(tcp.port == 22)"},
+		{And(Field("tcp").Exists(), Or(Field("tcp.port").Eq(80), Field("tcp.port").Eq(443))),
+			"tcp && (tcp.port == 80 || tcp.port == 443)"},
+		{Or(And(Field("a").Exists(), Field("b").Exists()), Raw("c or d")), "(a && b) || (c or d)"},
+		{And(nil, Field("dns").Exists(), And()), "dns"},
+		{And(), ""},
+		{Not(nil), ""},
+		{Not(And()), ""},
+		{And(Not(nil), Field("dns").Exists()), "dns"},
+	}
+	for _, tt := range tests {
+		if got := tt.expr.String(); got != tt.want {
+			t.Errorf("got %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestConversation(t *testing.T) {
+	k := packet.SessionKey{Protocol: "tcp", SrcIP: "10.0.0.1", SrcPort: "51000", DstIP: "10.0.0.2", DstPort: "443"}
+	want := "(ip.src == 10.0.0.1 && ip.dst == 10.0.0.2 && tcp.srcport == 51000 && tcp.dstport == 443) || " +
+		"(ip.src == 10.0.0.2 && ip.dst == 10.0.0.1 && tcp.srcport == 443 && tcp.dstport == 51000)"
+	if got := Conversation(k).String(); got != want {
+		t.Errorf("got %q\nwant %q", got, want)
+	}
+
+	k = packet.SessionKey{Protocol: "icmpv6", SrcIP: "fe80::1", DstIP: "fe80::2"}
+	want = "(ipv6.src == fe80::1 && ipv6.dst == fe80::2 && icmpv6) || (ipv6.src == fe80::2 && ipv6.dst == fe80::1 && icmpv6)"
+	if got := Conversation(k).String(); got != want {
+		t.Errorf("got %q\nwant %q", got, want)
+	}
+}
+
+func TestApplyAsFilter(t *testing.T) {
+	data, err := os.ReadFile("../tshark_output.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkts, err := packet.ParsePackets(data)
+	if err != nil || len(pkts) == 0 {
+		t.Fatalf("ParsePackets: %v", err)
+	}
+	p := pkts[0]
+
+	for field, want := range map[string]string{
+		"ip.src":          "ip.src == 127.0.0.1",
+		"tcp.srcport":     "tcp.srcport == 58894",
+		"ip.flags.df":     "ip.flags.df == 1", // nested in ip.flags_tree
+		"ip.flags":        "ip.flags == 0x02",
+		"frame.protocols": `frame.protocols == "null:ip:tcp:data"`,
+		"tcp":             "tcp",
+	} {
+		expr, err := ApplyAsFilter(p, field)
+		if err != nil {
+			t.Errorf("%s: %v", field, err)
+			continue
+		}
+		if got := expr.String(); got != want {
+			t.Errorf("%s: got %q, want %q", field, got, want)
+		}
+	}
+
+	if _, err := ApplyAsFilter(p, "http.host"); err == nil {
+		t.Error("expected an error for a field the packet lacks")
+	}
+}
+
+func TestApplyAsFilterDuplicateLayers(t *testing.T) {
+	// IP-in-IP: the field is only in the second ip layer.
+	p := &packet.Packet{Layers: []packet.Layer{
+		{Name: "ip", Fields: map[string]interface{}{"ip.src": "10.0.0.1"}},
+		{Name: "ip", Fields: map[string]interface{}{"ip.src": "192.168.1.1", "ip.opt.len": "4"}},
+	}}
+	expr, err := ApplyAsFilter(p, "ip.opt.len")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := expr.String(); got != "ip.opt.len == 4" {
+		t.Errorf("got %q, want %q", got, "ip.opt.len == 4")
+	}
+}
@@ -0,0 +1,578 @@
+package displayfilter
+
+import (
+	"bytes"
+	"encoding/hex"
+	"net/netip"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/p-vbordei/GoShark/packet"
+)
+
+// Filter is a display filter compiled for evaluation on decoded packets,
+// without tshark. It supports comparisons (==, !=, ===, ~=, <, <=, >, >=,
+// and their word forms), and/or/not, contains, matches, bitwise and,
+// membership in sets ("in {80 443 8000..8010}"), field existence, slices,
+// and the len, count, lower and upper functions.
+//
+// As in tshark, a field that occurs several times matches a comparison if
+// any occurrence does (all occurrences for != and ===), and a comparison on a
+// missing field is false. Values are typed from their text, since decoded
+// packets carry no field types: numbers (decimal or 0x hex) compare
+// numerically, addresses as addresses (an address matches a CIDR block that
+// contains it), anything else as strings. A field is taken to hold bytes
+// only when tshark rendered it as colon-separated hex, as it does byte
+// fields such as tcp.payload or eth.src; it then compares with quoted
+// strings, contains and matches as bytes. Other hex-like text, such as a
+// host named "ab-cd", stays a string unless compared with a hex literal.
+// Absolute times compare as strings, and protocols support only existence
+// tests.
+type Filter struct {
+	text string
+	root node
+}
+
+// Compile parses a display filter. A syntax error is reported as an
+// *errors.InvalidFilterError carrying the offset of the offending token.
+func Compile(filter string) (*Filter, error) {
+	tokens, err := lex(filter)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{filter: filter, tokens: tokens}
+	if p.peek().kind == tokEOF {
+		return &Filter{text: filter}, nil
+	}
+	root, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, p.errorf(t, "unexpected %q", t.text)
+	}
+	return &Filter{text: filter, root: root}, nil
+}
+
+// MustCompile is like Compile but panics on an invalid filter.
+func MustCompile(filter string) *Filter {
+	f, err := Compile(filter)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+// String returns the filter as it was compiled.
+func (f *Filter) String() string {
+	return f.text
+}
+
+// Match reports whether the packet matches the filter. The empty filter
+// matches every packet.
+func (f *Filter) Match(p *packet.Packet) bool {
+	if f.root == nil {
+		return true
+	}
+	return f.root.eval(p)
+}
+
+// Apply returns the packets that match the filter, in order.
+func (f *Filter) Apply(pkts []*packet.Packet) []*packet.Packet {
+	var out []*packet.Packet
+	for _, p := range pkts {
+		if f.Match(p) {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+type node interface {
+	eval(p *packet.Packet) bool
+}
+
+type andNode struct{ left, right node }
+
+func (n andNode) eval(p *packet.Packet) bool { return n.left.eval(p) && n.right.eval(p) }
+
+type orNode struct{ left, right node }
+
+func (n orNode) eval(p *packet.Packet) bool { return n.left.eval(p) || n.right.eval(p) }
+
+type notNode struct{ inner node }
+
+func (n notNode) eval(p *packet.Packet) bool { return !n.inner.eval(p) }
+
+type existsNode struct{ operand operand }
+
+func (n existsNode) eval(p *packet.Packet) bool {
+	if f, ok := n.operand.(fieldOperand); ok {
+		return fieldExists(p, f.name)
+	}
+	return len(n.operand.values(p)) > 0
+}
+
+type cmpNode struct {
+	op          string
+	left, right operand
+}
+
+func (n cmpNode) eval(p *packet.Packet) bool {
+	left, right := n.left.values(p), n.right.values(p)
+	if len(left) == 0 || len(right) == 0 {
+		return false
+	}
+	switch n.op {
+	case "!=", "!==":
+		// tshark: true only if no occurrence is equal.
+		return !anyPair(left, right, "==")
+	case "===":
+		for _, l := range left {
+			if !anyPair([]value{l}, right, "==") {
+				return false
+			}
+		}
+		return true
+	case "~=":
+		return anyPair(left, right, "!=")
+	}
+	return anyPair(left, right, n.op)
+}
+
+func anyPair(left, right []value, op string) bool {
+	for _, l := range left {
+		for _, r := range right {
+			if compareValues(l, r, op) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type inNode struct {
+	left operand
+	set  []setElem
+}
+
+func (n inNode) eval(p *packet.Packet) bool {
+	left := n.left.values(p)
+	for _, elem := range n.set {
+		if elem.hi == nil {
+			if anyPair(left, elem.lo.values(p), "==") {
+				return true
+			}
+			continue
+		}
+		lo, hi := elem.lo.values(p), elem.hi.values(p)
+		for _, l := range left {
+			if anyPair([]value{l}, lo, ">=") && anyPair([]value{l}, hi, "<=") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type matchNode struct {
+	left operand
+	re   *regexp.Regexp
+}
+
+func (n matchNode) eval(p *packet.Packet) bool {
+	for _, v := range n.left.values(p) {
+		if n.re.MatchString(v.matchText()) {
+			return true
+		}
+	}
+	return false
+}
+
+// value kinds.
+const (
+	kindRaw    = iota // Field text or bare literal; typed on comparison
+	kindString        // Quoted literal or string function result
+	kindBytes         // Slice result
+	kindNumber        // Function result such as len()
+)
+
+type value struct {
+	kind  int
+	text  string
+	b     []byte
+	num   float64
+	field bool // kindRaw text of a packet field rather than a literal
+}
+
+// str returns the value as text. Only byte strings are decoded; field text
+// is returned as tshark rendered it.
+func (v value) str() string {
+	if v.kind == kindBytes {
+		return string(v.b)
+	}
+	return v.text
+}
+
+// hexBytes decodes a raw value written as hex bytes. Literals may separate
+// the bytes with ':', '.' or '-'; field text must use ':', as tshark does
+// for byte fields, so that strings such as "ad.be" are not taken for bytes.
+func (v value) hexBytes() ([]byte, bool) {
+	if v.kind != kindRaw || isAddress(v.text) {
+		return nil, false
+	}
+	if v.field && !colonBytesPattern.MatchString(v.text) {
+		return nil, false
+	}
+	return hexBytes(v.text)
+}
+
+// matchText returns the text a regular expression is matched against: the
+// decoded bytes of byte strings and byte fields, the text of anything else.
+func (v value) matchText() string {
+	if v.field {
+		if b, ok := v.hexBytes(); ok {
+			return string(b)
+		}
+	}
+	return v.str()
+}
+
+// operand produces the values a filter term has in a packet.
+type operand interface {
+	values(p *packet.Packet) []value
+}
+
+type literalOperand struct{ v value }
+
+func (o literalOperand) values(*packet.Packet) []value { return []value{o.v} }
+
+type fieldOperand struct{ name string }
+
+func (o fieldOperand) values(p *packet.Packet) []value {
+	var out []value
+	for _, s := range fieldValues(p, o.name) {
+		out = append(out, value{kind: kindRaw, text: s, field: true})
+	}
+	return out
+}
+
+type sliceOperand struct {
+	inner  operand
+	ranges []sliceRange
+}
+
+func (o sliceOperand) values(p *packet.Packet) []value {
+	var out []value
+	for _, v := range o.inner.values(p) {
+		data, ok := toBytes(v)
+		if !ok {
+			continue
+		}
+		var sliced []byte
+		for _, r := range o.ranges {
+			part, ok := r.apply(data)
+			if !ok {
+				sliced = nil
+				break
+			}
+			sliced = append(sliced, part...)
+		}
+		if sliced != nil {
+			out = append(out, value{kind: kindBytes, b: sliced})
+		}
+	}
+	return out
+}
+
+func (r sliceRange) apply(data []byte) ([]byte, bool) {
+	start := r.offset
+	if start < 0 {
+		start += len(data)
+	}
+	end := len(data)
+	if r.length >= 0 {
+		end = start + r.length
+	}
+	if start < 0 || start > len(data) || end > len(data) || end <= start {
+		return nil, false
+	}
+	return data[start:end], true
+}
+
+type funcOperand struct {
+	name string
+	arg  operand
+}
+
+func (o funcOperand) values(p *packet.Packet) []value {
+	args := o.arg.values(p)
+	if o.name == "count" {
+		return []value{{kind: kindNumber, num: float64(len(args))}}
+	}
+	out := make([]value, 0, len(args))
+	for _, v := range args {
+		switch o.name {
+		case "len":
+			n := len(v.str())
+			if b, ok := toBytes(v); ok {
+				n = len(b)
+			}
+			out = append(out, value{kind: kindNumber, num: float64(n)})
+		case "lower":
+			out = append(out, value{kind: kindString, text: strings.ToLower(v.str())})
+		case "upper":
+			out = append(out, value{kind: kindString, text: strings.ToUpper(v.str())})
+		}
+	}
+	return out
+}
+
+// compareValues applies a binary operator to two values, typing them from
+// the more specific side: bytes, then numbers, then addresses, then strings.
+func compareValues(l, r value, op string) bool {
+	if op == "&" {
+		a, ok1 := numberOf(l)
+		b, ok2 := numberOf(r)
+		return ok1 && ok2 && int64(a)&int64(b) != 0
+	}
+
+	if l.kind == kindBytes || r.kind == kindBytes || bytesLike(l, r) {
+		a, ok1 := toBytes(l)
+		b, ok2 := toBytes(r)
+		if ok1 && ok2 {
+			if op == "contains" {
+				return bytes.Contains(a, b)
+			}
+			return ordered(bytes.Compare(a, b), op)
+		}
+	}
+	if op == "contains" {
+		return strings.Contains(l.str(), r.str())
+	}
+	if l.kind != kindString && r.kind != kindString {
+		if a, ok := numberOf(l); ok {
+			if b, ok := numberOf(r); ok {
+				switch {
+				case a < b:
+					return ordered(-1, op)
+				case a > b:
+					return ordered(1, op)
+				}
+				return ordered(0, op)
+			}
+		}
+		if result, ok := compareAddresses(l.text, r.text, op); ok {
+			return result
+		}
+	}
+	return ordered(strings.Compare(l.str(), r.str()), op)
+}
+
+// bytesLike reports whether a comparison is between byte strings: both sides
+// are hex bytes, e.g. a MAC address and a byte literal, or one side is a
+// byte field and the other a quoted string.
+func bytesLike(l, r value) bool {
+	_, lhex := l.hexBytes()
+	_, rhex := r.hexBytes()
+	return (lhex && (rhex || l.field && r.kind == kindString)) ||
+		(rhex && r.field && l.kind == kindString)
+}
+
+func ordered(cmp int, op string) bool {
+	switch op {
+	case "==":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+	return false
+}
+
+// compareAddresses compares two IP addresses; an address equals a CIDR
+// block that contains it.
+func compareAddresses(l, r, op string) (bool, bool) {
+	la, lerr := netip.ParseAddr(l)
+	if lerr != nil {
+		return false, false
+	}
+	if prefix, err := netip.ParsePrefix(r); err == nil && (op == "==" || op == "!=") {
+		return prefix.Contains(la) == (op == "=="), true
+	}
+	ra, err := netip.ParseAddr(r)
+	if err != nil {
+		return false, false
+	}
+	return ordered(la.Compare(ra), op), true
+}
+
+func isAddress(s string) bool {
+	_, err := netip.ParseAddr(s)
+	return err == nil
+}
+
+// numberOf parses a value as a number: decimal, 0x hex, or a boolean name.
+func numberOf(v value) (float64, bool) {
+	switch v.kind {
+	case kindNumber:
+		return v.num, true
+	case kindRaw:
+		return parseNumber(v.text)
+	}
+	return 0, false
+}
+
+func parseNumber(s string) (float64, bool) {
+	switch strings.ToLower(s) {
+	case "true":
+		return 1, true
+	case "false":
+		return 0, true
+	}
+	if n, err := strconv.ParseInt(s, 0, 64); err == nil {
+		return float64(n), true
+	}
+	if n, err := strconv.ParseUint(s, 0, 64); err == nil {
+		return float64(n), true
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil && !strings.ContainsAny(s, "xXpP") {
+		return f, true
+	}
+	return 0, false
+}
+
+// toBytes converts a value to a byte string: addresses to their network
+// form, hex bytes to their bytes (see value.hexBytes), a single hex byte
+// literal to one byte, and strings to their UTF-8 bytes.
+func toBytes(v value) ([]byte, bool) {
+	switch v.kind {
+	case kindBytes:
+		return v.b, true
+	case kindString:
+		return []byte(v.text), true
+	case kindNumber:
+		return nil, false
+	}
+	if a, err := netip.ParseAddr(v.text); err == nil {
+		return a.AsSlice(), true
+	}
+	if b, ok := v.hexBytes(); ok {
+		return b, true
+	}
+	if !v.field && len(v.text) == 2 {
+		if b, err := hex.DecodeString(v.text); err == nil {
+			return b, true
+		}
+	}
+	return []byte(v.text), true
+}
+
+// hexBytes decodes hex bytes separated by ':', '.' or '-', e.g. a MAC
+// address or a payload as tshark renders it.
+func hexBytes(s string) ([]byte, bool) {
+	if !bytesPattern.MatchString(s) {
+		return nil, false
+	}
+	out := make([]byte, 0, (len(s)+1)/3)
+	for i := 0; i < len(s); i += 3 {
+		b, err := hex.DecodeString(s[i : i+2])
+		if err != nil {
+			return nil, false
+		}
+		out = append(out, b...)
+	}
+	return out, true
+}
+
+// fieldValues returns every value of a field in the packet, including
+// occurrences nested in subtrees and encapsulated protocols.
+func fieldValues(p *packet.Packet, name string) []string {
+	_ = p.DecodeAll()
+	var out []string
+	for i := range p.Layers {
+		collectField(p.Layers[i].Fields, name, &out)
+	}
+	return out
+}
+
+func collectField(fields map[string]interface{}, name string, out *[]string) {
+	for key, v := range fields {
+		if key == name {
+			appendValues(v, out)
+			continue
+		}
+		for _, sub := range subtrees(v) {
+			collectField(sub, name, out)
+		}
+	}
+}
+
+// subtrees returns the field maps nested in a value: a subtree, or each
+// subtree of a repeated one.
+func subtrees(v interface{}) []map[string]interface{} {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		return []map[string]interface{}{x}
+	case []interface{}:
+		var out []map[string]interface{}
+		for _, e := range x {
+			if m, ok := e.(map[string]interface{}); ok {
+				out = append(out, m)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func appendValues(v interface{}, out *[]string) {
+	switch x := v.(type) {
+	case []interface{}:
+		for _, e := range x {
+			appendValues(e, out)
+		}
+	case map[string]interface{}:
+		// A subtree carries no value of its own.
+	default:
+		if s, ok := fieldString(x); ok {
+			*out = append(*out, s)
+		}
+	}
+}
+
+// fieldExists reports whether a protocol layer or field is present.
+func fieldExists(p *packet.Packet, name string) bool {
+	if p.HasLayer(name) {
+		return true
+	}
+	_ = p.DecodeAll()
+	for i := range p.Layers {
+		if hasField(p.Layers[i].Fields, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasField(fields map[string]interface{}, name string) bool {
+	if _, ok := fields[name]; ok {
+		return true
+	}
+	for _, v := range fields {
+		for _, sub := range subtrees(v) {
+			if hasField(sub, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
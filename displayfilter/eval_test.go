@@ -0,0 +1,211 @@
+package displayfilter
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	sharkerrors "github.com/p-vbordei/GoShark/errors"
+	"github.com/p-vbordei/GoShark/packet"
+)
+
+func testPacket() *packet.Packet {
+	return &packet.Packet{Layers: []packet.Layer{
+		{Name: "frame", Fields: map[string]interface{}{"frame.len": "74", "frame.time_delta": "0.250000000"}},
+		{Name: "eth", Fields: map[string]interface{}{"eth.src": "00:1b:21:01:02:03", "eth.type": "0x0800"}},
+		{Name: "ip", Fields: map[string]interface{}{
+			"ip.src":   "10.0.0.1",
+			"ip.dst":   "192.168.1.20",
+			"ip.addr":  []interface{}{"10.0.0.1", "192.168.1.20"},
+			"ip.flags": "0x02",
+			"ip.flags_tree": map[string]interface{}{
+				"ip.flags.df": "1",
+			},
+		}},
+		{Name: "tcp", Fields: map[string]interface{}{
+			"tcp.srcport": "51000",
+			"tcp.dstport": "80",
+			"tcp.port":    []interface{}{"51000", "80"},
+			"tcp.flags":   "0x0018",
+			"tcp.payload": "47:45:54:20:2f:6c:6f:67:69:6e",
+		}},
+		{Name: "http", Fields: map[string]interface{}{
+			"GET /login HTTP/1.1\\r\\n": map[string]interface{}{
+				"http.request.method": "GET",
+				"http.request.uri":    "/login",
+			},
+			"http.host": "Example.COM",
+		}},
+	}}
+}
+
+func TestFilterMatch(t *testing.T) {
+	p := testPacket()
+	tests := []struct {
+		filter string
+		want   bool
+	}{
+		{"", true},
+		{"tcp", true},
+		{"udp", false},
+		{"http.host", true},
+		{"ip.flags.df", true},
+		{"!udp && tcp", true},
+		{"ip.src == 10.0.0.1", true},
+		{"ip.src eq 10.0.0.2", false},
+		{"ip.src == 10.0.0.0/8", true},
+		{"ip.dst != 10.0.0.0/8", true},
+		{"ip.src < ip.dst", true},
+		{"ip.addr == 192.168.1.20", true},
+		{"ip.addr != 10.0.0.1", false},
+		{"ip.addr ~= 10.0.0.1", true},
+		{"ip.addr === 10.0.0.1", false},
+		{"tcp.port == 80", true},
+		{"tcp.dstport > 1024", false},
+		{"tcp.srcport >= 1024 and tcp.dstport le 80", true},
+		{"tcp.port in {443 8080}", false},
+		{"tcp.port in {22, 80}", true},
+		{"tcp.srcport in {50000..52000}", true},
+		{"tcp.flags & 0x08", true},
+		{"tcp.flags bitand 0x02", false},
+		{"ip.flags == 2", true},
+		{"ip.flags.df == True", true},
+		{"frame.time_delta > 0.2", true},
+		{`http.request.method == "GET"`, true},
+		{"http.request.method == GET", true},
+		{`http.host == "example.com"`, false},
+		{`lower(http.host) == "example.com"`, true},
+		{`upper(http.request.uri) contains "LOGIN"`, true},
+		{`http.host matches "^example\\.com$"`, true},
+		{`http.host ~ "org$"`, false},
+		{`tcp.payload contains "GET"`, true},
+		{`tcp.payload contains 2f:6c`, true},
+		{`tcp.payload matches "^get /"`, true},
+		{"tcp.payload[0:3] == 47:45:54", true},
+		{`tcp.payload[0:3] == "GET"`, true},
+		{"tcp.payload[-5:] == 6c:6f:67:69:6e", true},
+		{"tcp.payload[0] == 47", true},
+		{"tcp.payload[1-2] == 45:54", true},
+		{"tcp.payload[0:2,4:1] == 47:45:2f", true},
+		{"tcp.payload[100:1]", false},
+		{"eth.src == 00:1b:21:01:02:03", true},
+		{"eth.src[0:3] == 00:1b:21", true},
+		{"len(tcp.payload) == 10", true},
+		{"len(ip.src) == 4", true},
+		{"count(ip.addr) == 2", true},
+		{"count(udp.port) == 0", true},
+		{"tcp.port == 80 || udp.port == 53 && dns", true},
+		{"(tcp.port == 22 || udp) && tcp", false},
+		{"not (tcp.port == 22)", true},
+		{"udp.port != 53", false},
+	}
+	for _, tt := range tests {
+		f, err := Compile(tt.filter)
+		if err != nil {
+			t.Errorf("%q: %v", tt.filter, err)
+			continue
+		}
+		if got := f.Match(p); got != tt.want {
+			t.Errorf("%q: got %v, want %v", tt.filter, got, tt.want)
+		}
+	}
+}
+
+func TestFilterMatchHexLikeStrings(t *testing.T) {
+	p := &packet.Packet{Layers: []packet.Layer{
+		{Name: "http", Fields: map[string]interface{}{"http.host": "ab-cd"}},
+		{Name: "dns", Fields: map[string]interface{}{"dns.qry.name": "ad.be"}},
+		{Name: "ssh", Fields: map[string]interface{}{"ssh.host": "ab"}},
+	}}
+	tests := []struct {
+		filter string
+		want   bool
+	}{
+		{`http.host == "ab-cd"`, true},
+		{`http.host contains "-"`, true},
+		{`http.host matches "^ab-cd$"`, true},
+		{"len(http.host) == 5", true},
+		{"http.host[0:2] == 61:62", true},
+		{"http.host == ab-cd", true},
+		{`dns.qry.name == "ad.be"`, true},
+		{`dns.qry.name matches "^ad\\.be$"`, true},
+		{`dns.qry.name contains "."`, true},
+		{"len(dns.qry.name) == 5", true},
+		{`ssh.host == "ab"`, true},
+		{"len(ssh.host) == 2", true},
+	}
+	for _, tt := range tests {
+		f, err := Compile(tt.filter)
+		if err != nil {
+			t.Errorf("%q: %v", tt.filter, err)
+			continue
+		}
+		if got := f.Match(p); got != tt.want {
+			t.Errorf("%q: got %v, want %v", tt.filter, got, tt.want)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		filter string
+		offset int
+	}{
+		{"tcp.port ==", 11},
+		{"tcp.port == 80 &&", 17},
+		{"(tcp", 4},
+		{`http.host == "unterminated`, 13},
+		{"tcp.port in {80", 15},
+		{"ip.src @ 1", 7},
+		{"tcp.payload[x] == 00", 12},
+		{`http.host matches "("`, 18},
+		{"sha256(tcp.payload)", 0},
+		{"10.0.0.1", 0},
+		{"tcp tcp", 4},
+	}
+	for _, tt := range tests {
+		_, err := Compile(tt.filter)
+		var invalid *sharkerrors.InvalidFilterError
+		if !errors.As(err, &invalid) {
+			t.Errorf("%q: expected InvalidFilterError, got %v", tt.filter, err)
+			continue
+		}
+		if invalid.Offset() != tt.offset {
+			t.Errorf("%q: offset %d, want %d (%v)", tt.filter, invalid.Offset(), tt.offset, err)
+		}
+	}
+}
+
+func TestCompileBuiltFilters(t *testing.T) {
+	p := testPacket()
+	expr := And(
+		Field("ip.src").Eq(Literal("10.0.0.1")),
+		Field("tcp.payload").Contains([]byte("GET")),
+		Not(Field("http.host").Eq("example.org")),
+		Field("tcp.port").In(80, 443),
+	)
+	f, err := Compile(expr.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !f.Match(p) {
+		t.Errorf("%s should match", expr)
+	}
+}
+
+func TestFilterApply(t *testing.T) {
+	data, err := os.ReadFile("../tshark_output.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkts, err := packet.ParsePackets(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := MustCompile("ip.src == 127.0.0.1 && tcp.srcport == 58894").Apply(pkts); len(got) != len(pkts) {
+		t.Errorf("expected every packet to match, got %d of %d", len(got), len(pkts))
+	}
+	if got := MustCompile("udp").Apply(pkts); len(got) != 0 {
+		t.Errorf("expected no matches, got %d", len(got))
+	}
+}
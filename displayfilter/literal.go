@@ -0,0 +1,129 @@
+package displayfilter
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Format renders a Go value as a display-filter literal:
+//
+//   - Value: the operand itself, so fields can be compared with fields
+//   - string, fmt.Stringer: a quoted, escaped string
+//   - []byte: colon-separated hex bytes, e.g. 47:45:54
+//   - integers and floats: decimal numbers
+//   - bool: 1 or 0
+//   - net.IP, netip.Addr, *net.IPNet, netip.Prefix, net.HardwareAddr:
+//     unquoted addresses and CIDR blocks
+//   - time.Time: a quoted absolute time in UTC
+//   - time.Duration: seconds, for relative-time fields such as
+//     frame.time_delta
+func Format(x interface{}) string {
+	switch v := x.(type) {
+	case Value:
+		return v.text
+	case string:
+		return Quote(v)
+	case []byte:
+		return bytesLiteral(v)
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case int:
+		return strconv.FormatInt(int64(v), 10)
+	case int8:
+		return strconv.FormatInt(int64(v), 10)
+	case int16:
+		return strconv.FormatInt(int64(v), 10)
+	case int32:
+		return strconv.FormatInt(int64(v), 10)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint8:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint16:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint32:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case net.IP:
+		return v.String()
+	case netip.Addr:
+		return v.String()
+	case *net.IPNet:
+		return v.String()
+	case netip.Prefix:
+		return v.String()
+	case net.HardwareAddr:
+		return v.String()
+	case time.Time:
+		return Quote(v.UTC().Format("Jan 02, 2006 15:04:05.000000000") + " UTC")
+	case time.Duration:
+		return strconv.FormatFloat(v.Seconds(), 'f', -1, 64)
+	case fmt.Stringer:
+		return Quote(v.String())
+	}
+	return Quote(fmt.Sprint(x))
+}
+
+// Quote returns s as a double-quoted display-filter string. Backslashes and
+// quotes are escaped, and bytes that are not printable UTF-8 are written as
+// \xNN escapes.
+func Quote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == utf8.RuneError && size == 1, r < 0x20, r == 0x7f:
+			fmt.Fprintf(&b, `\x%02x`, s[i])
+		default:
+			b.WriteString(s[i : i+size])
+		}
+		i += size
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+func bytesLiteral(data []byte) string {
+	switch len(data) {
+	case 0:
+		return `""`
+	case 1:
+		// A lone hex byte would read as a number.
+		return fmt.Sprintf(`"\x%02x"`, data[0])
+	}
+	parts := make([]string, len(data))
+	for i := range data {
+		parts[i] = hex.EncodeToString(data[i : i+1])
+	}
+	return strings.Join(parts, ":")
+}
+
+func itoa(n int) string {
+	return strconv.Itoa(n)
+}
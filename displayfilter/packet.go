@@ -0,0 +1,126 @@
+package displayfilter
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/p-vbordei/GoShark/packet"
+)
+
+// Conversation returns a filter matching both directions of a session, like
+// Wireshark's "Conversation Filter". Empty key fields are not constrained.
+func Conversation(k packet.SessionKey) Expr {
+	return Or(endpoints(k, k.SrcIP, k.SrcPort, k.DstIP, k.DstPort),
+		endpoints(k, k.DstIP, k.DstPort, k.SrcIP, k.SrcPort))
+}
+
+// endpoints matches packets sent from srcIP:srcPort to dstIP:dstPort.
+func endpoints(k packet.SessionKey, srcIP, srcPort, dstIP, dstPort string) Expr {
+	var exprs []Expr
+	if srcIP != "" {
+		exprs = append(exprs, Field(addrProto(srcIP)+".src").Eq(Literal(srcIP)))
+	}
+	if dstIP != "" {
+		exprs = append(exprs, Field(addrProto(dstIP)+".dst").Eq(Literal(dstIP)))
+	}
+	proto := strings.ToLower(k.Protocol)
+	if proto != "" && (srcPort != "" || dstPort != "") {
+		if srcPort != "" {
+			exprs = append(exprs, Field(proto+".srcport").Eq(Literal(srcPort)))
+		}
+		if dstPort != "" {
+			exprs = append(exprs, Field(proto+".dstport").Eq(Literal(dstPort)))
+		}
+	} else if proto != "" {
+		exprs = append(exprs, Field(proto).Exists())
+	}
+	return And(exprs...)
+}
+
+// addrProto returns the protocol whose src/dst fields hold addr.
+func addrProto(addr string) string {
+	if a, err := netip.ParseAddr(addr); err == nil && a.Is6() && !a.Is4In6() {
+		return "ipv6"
+	}
+	return "ip"
+}
+
+// ApplyAsFilter returns a filter matching the value field has in p, like
+// Wireshark's "Apply as Filter" on a packet-details row. field is a full
+// filter name such as "http.request.method"; when it occurs several times,
+// the first occurrence is used.
+func ApplyAsFilter(p *packet.Packet, field string) (Expr, error) {
+	for i := range p.Layers {
+		layer := p.GetLayerByIndex(i)
+		if v, ok := findField(layer.Fields, field); ok {
+			s, ok := fieldString(v)
+			if !ok {
+				return nil, fmt.Errorf("field %s has no single value to filter on", field)
+			}
+			return Field(field).Eq(Literal(literalFor(s))), nil
+		}
+	}
+	if p.HasLayer(field) {
+		return Field(field).Exists(), nil
+	}
+	return nil, fmt.Errorf("field %s not found in packet", field)
+}
+
+// findField looks name up in fields and the subtrees nested in them.
+func findField(fields map[string]interface{}, name string) (interface{}, bool) {
+	if v, ok := fields[name]; ok {
+		return v, true
+	}
+	for _, v := range fields {
+		if sub, ok := v.(map[string]interface{}); ok {
+			if found, ok := findField(sub, name); ok {
+				return found, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// fieldString returns the first value of a decoded field.
+func fieldString(v interface{}) (string, bool) {
+	switch x := v.(type) {
+	case string:
+		return x, true
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64), true
+	case bool:
+		if x {
+			return "1", true
+		}
+		return "0", true
+	case []interface{}:
+		if len(x) > 0 {
+			return fieldString(x[0])
+		}
+	}
+	return "", false
+}
+
+var (
+	numberLiteral = regexp.MustCompile(`^-?(0x[0-9a-fA-F]+|[0-9]+(\.[0-9]+)?)$`)
+	bytesPattern  = regexp.MustCompile(`^[0-9a-fA-F]{2}([:.-][0-9a-fA-F]{2})+$`)
+	// colonBytesPattern is how tshark renders byte fields.
+	colonBytesPattern = regexp.MustCompile(`^[0-9a-fA-F]{2}(:[0-9a-fA-F]{2})+$`)
+)
+
+// literalFor writes a value as tshark rendered it in a form the filter
+// compiler accepts: numbers, addresses and byte strings unquoted, anything
+// else as a quoted string.
+func literalFor(s string) string {
+	if numberLiteral.MatchString(s) || colonBytesPattern.MatchString(s) {
+		return s
+	}
+	if net.ParseIP(s) != nil {
+		return s
+	}
+	return Quote(s)
+}
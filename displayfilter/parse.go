@@ -0,0 +1,483 @@
+package displayfilter
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	sharkerrors "github.com/p-vbordei/GoShark/errors"
+)
+
+// token kinds.
+const (
+	tokEOF = iota
+	tokWord
+	tokString
+	tokOp
+	tokPunct // ( ) [ ] { } ,
+)
+
+type token struct {
+	kind int
+	text string // Word, operator or punctuation; unescaped string contents
+	pos  int
+}
+
+// operator spellings, longest first, and their canonical form.
+var operators = []struct{ text, op string }{
+	{"===", "==="}, {"!==", "!=="}, {"==", "=="}, {"!=", "!="}, {"~=", "~="},
+	{">=", ">="}, {"<=", "<="}, {"&&", "&&"}, {"||", "||"},
+	{">", ">"}, {"<", "<"}, {"!", "!"}, {"&", "&"}, {"~", "matches"},
+}
+
+// wordOperators are operators spelled as words.
+var wordOperators = map[string]string{
+	"eq": "==", "any_eq": "==", "ne": "!=", "all_ne": "!=", "all_eq": "===", "any_ne": "~=",
+	"gt": ">", "ge": ">=", "lt": "<", "le": "<=",
+	"contains": "contains", "matches": "matches", "bitand": "&", "in": "in",
+	"and": "&&", "or": "||", "not": "!",
+}
+
+func isWordChar(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_.:-/", r)
+}
+
+// lex splits a filter into tokens.
+func lex(filter string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(filter); {
+		c := filter[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case strings.IndexByte("()[]{},", c) >= 0:
+			tokens = append(tokens, token{tokPunct, string(c), i})
+			i++
+		case c == '"':
+			s, n, err := unquote(filter[i:])
+			if err != nil {
+				return nil, filterError(filter, err.Error(), i)
+			}
+			tokens = append(tokens, token{tokString, s, i})
+			i += n
+		case isWordChar(rune(c)) || c >= 0x80:
+			start := i
+			for i < len(filter) {
+				r := rune(filter[i])
+				if r >= 0x80 || isWordChar(r) {
+					i++
+					continue
+				}
+				break
+			}
+			word := filter[start:i]
+			if op, ok := wordOperators[strings.ToLower(word)]; ok {
+				tokens = append(tokens, token{tokOp, op, start})
+			} else {
+				tokens = append(tokens, token{tokWord, word, start})
+			}
+		default:
+			matched := false
+			for _, o := range operators {
+				if strings.HasPrefix(filter[i:], o.text) {
+					tokens = append(tokens, token{tokOp, o.op, i})
+					i += len(o.text)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, filterError(filter, fmt.Sprintf("unexpected character %q", c), i)
+			}
+		}
+	}
+	return append(tokens, token{tokEOF, "", len(filter)}), nil
+}
+
+// unquote decodes the double-quoted string at the start of s and returns it
+// with the number of bytes consumed.
+func unquote(s string) (string, int, error) {
+	var b strings.Builder
+	for i := 1; i < len(s); {
+		c := s[i]
+		switch c {
+		case '"':
+			return b.String(), i + 1, nil
+		case '\\':
+			if i+1 >= len(s) {
+				return "", 0, fmt.Errorf("unterminated string")
+			}
+			i++
+			switch e := s[i]; e {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case 'a':
+				b.WriteByte('\a')
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'v':
+				b.WriteByte('\v')
+			case 'x':
+				if i+2 >= len(s) {
+					return "", 0, fmt.Errorf("invalid \\x escape")
+				}
+				v, err := strconv.ParseUint(s[i+1:i+3], 16, 8)
+				if err != nil {
+					return "", 0, fmt.Errorf("invalid \\x escape")
+				}
+				b.WriteByte(byte(v))
+				i += 2
+			case '0', '1', '2', '3', '4', '5', '6', '7':
+				if i+2 >= len(s) {
+					return "", 0, fmt.Errorf("invalid octal escape")
+				}
+				v, err := strconv.ParseUint(s[i:i+3], 8, 8)
+				if err != nil {
+					return "", 0, fmt.Errorf("invalid octal escape")
+				}
+				b.WriteByte(byte(v))
+				i += 2
+			default:
+				b.WriteByte(e)
+			}
+			i++
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+func filterError(filter, detail string, pos int) error {
+	return sharkerrors.NewInvalidFilterErrorAt(filter, detail, pos)
+}
+
+// parser is a recursive-descent parser over the token stream:
+//
+//	expr     = and { "||" and }
+//	and      = unary { "&&" unary }
+//	unary    = "!" unary | "(" expr ")" | relation
+//	relation = operand [ cmpop operand | "in" set | "matches" string ]
+//	operand  = ( word | string | word "(" operand ")" ) [ slice ]
+type parser struct {
+	filter string
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token { return p.tokens[p.pos] }
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) errorf(t token, format string, args ...interface{}) error {
+	return filterError(p.filter, fmt.Sprintf(format, args...), t.pos)
+}
+
+func (p *parser) isOp(op string) bool {
+	t := p.peek()
+	return t.kind == tokOp && t.text == op
+}
+
+func (p *parser) isPunct(s string) bool {
+	t := p.peek()
+	return t.kind == tokPunct && t.text == s
+}
+
+func (p *parser) expect(s string) error {
+	if !p.isPunct(s) {
+		return p.errorf(p.peek(), "expected %q", s)
+	}
+	p.next()
+	return nil
+}
+
+func (p *parser) parseExpr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("||") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("&&") {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.isOp("!") {
+		p.next()
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{n}, nil
+	}
+	if p.isPunct("(") {
+		p.next()
+		n, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return n, nil
+	}
+	return p.parseRelation()
+}
+
+// comparisons are the binary comparison operators.
+var comparisons = map[string]bool{
+	"==": true, "!=": true, "===": true, "!==": true, "~=": true,
+	">": true, ">=": true, "<": true, "<=": true, "contains": true, "&": true,
+}
+
+func (p *parser) parseRelation() (node, error) {
+	start := p.peek()
+	left, err := p.parseOperand(false)
+	if err != nil {
+		return nil, err
+	}
+	t := p.peek()
+	if t.kind != tokOp || t.text == "&&" || t.text == "||" || t.text == "!" {
+		if _, ok := left.(literalOperand); ok {
+			return nil, p.errorf(start, "%q is neither a field nor a protocol name", start.text)
+		}
+		return existsNode{left}, nil
+	}
+	p.next()
+	switch {
+	case t.text == "in":
+		set, err := p.parseSet()
+		if err != nil {
+			return nil, err
+		}
+		return inNode{left, set}, nil
+	case t.text == "matches":
+		re := p.next()
+		if re.kind != tokString {
+			return nil, p.errorf(re, "matches requires a quoted regular expression")
+		}
+		// Wireshark's matches is case-insensitive.
+		compiled, err := regexp.Compile("(?i)" + re.text)
+		if err != nil {
+			return nil, p.errorf(re, "invalid regular expression: %v", err)
+		}
+		return matchNode{left, compiled}, nil
+	case comparisons[t.text]:
+		right, err := p.parseOperand(true)
+		if err != nil {
+			return nil, err
+		}
+		return cmpNode{t.text, left, right}, nil
+	}
+	return nil, p.errorf(t, "unexpected %q", t.text)
+}
+
+// functions are the supported display-filter functions.
+var functions = map[string]bool{"len": true, "count": true, "lower": true, "upper": true}
+
+// parseOperand parses an operand. On the right of a comparison, and in sets,
+// a bare word names a field only if it is dotted ("ip.dst"); otherwise it is
+// a literal such as an enumeration name, as fields are not known here.
+func (p *parser) parseOperand(rhs bool) (operand, error) {
+	t := p.next()
+	var op operand
+	switch t.kind {
+	case tokString:
+		op = literalOperand{value{kind: kindString, text: t.text}}
+	case tokWord:
+		name := strings.ToLower(t.text)
+		switch {
+		case p.isPunct("(") && functions[name]:
+			p.next()
+			arg, err := p.parseOperand(false)
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			if _, ok := arg.(fieldOperand); !ok && name == "count" {
+				return nil, p.errorf(t, "count() takes a field")
+			}
+			op = funcOperand{name, arg}
+		case p.isPunct("("):
+			return nil, p.errorf(t, "unsupported function %q", t.text)
+		case isFieldName(t.text) && (!rhs || strings.Contains(t.text, ".")):
+			op = fieldOperand{t.text}
+		default:
+			op = literalOperand{value{kind: kindRaw, text: t.text}}
+		}
+	default:
+		if t.kind == tokEOF {
+			return nil, p.errorf(t, "unexpected end of filter")
+		}
+		return nil, p.errorf(t, "unexpected %q", t.text)
+	}
+	if p.isPunct("[") {
+		ranges, err := p.parseSlice()
+		if err != nil {
+			return nil, err
+		}
+		op = sliceOperand{op, ranges}
+	}
+	return op, nil
+}
+
+// fieldNamePattern matches protocol and field names: a letter first, then
+// letters, digits, underscores, dashes and dots.
+var fieldNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_\-]*(\.[A-Za-z0-9_\-]+)*$`)
+
+// isFieldName reports whether a bare word names a field rather than being a
+// literal. Words that also read as hex bytes or numbers ("ab:cd", "ff") are
+// literals, as are the boolean names.
+func isFieldName(word string) bool {
+	if !fieldNamePattern.MatchString(word) {
+		return false
+	}
+	switch strings.ToLower(word) {
+	case "true", "false":
+		return false
+	}
+	_, isNum := parseNumber(word)
+	return !isNum
+}
+
+// sliceRange is one range of a slice: offset and length, with length -1
+// meaning through the end.
+type sliceRange struct {
+	offset, length int
+}
+
+var (
+	sliceOffsetLen = regexp.MustCompile(`^(-?\d+)?:(\d+)?$`)
+	sliceFirstLast = regexp.MustCompile(`^(-?\d+)-(-?\d+)$`)
+	sliceSingle    = regexp.MustCompile(`^-?\d+$`)
+)
+
+func (p *parser) parseSlice() ([]sliceRange, error) {
+	p.next() // [
+	var ranges []sliceRange
+	for {
+		t := p.next()
+		if t.kind != tokWord {
+			return nil, p.errorf(t, "invalid slice")
+		}
+		r, ok := parseSliceRange(t.text)
+		if !ok {
+			return nil, p.errorf(t, "invalid slice range %q", t.text)
+		}
+		ranges = append(ranges, r)
+		if p.isPunct(",") {
+			p.next()
+			continue
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		return ranges, nil
+	}
+}
+
+func parseSliceRange(s string) (sliceRange, bool) {
+	if m := sliceOffsetLen.FindStringSubmatch(s); m != nil {
+		r := sliceRange{length: -1}
+		if m[1] != "" {
+			r.offset, _ = strconv.Atoi(m[1])
+		}
+		if m[2] != "" {
+			r.length, _ = strconv.Atoi(m[2])
+		}
+		return r, true
+	}
+	if m := sliceFirstLast.FindStringSubmatch(s); m != nil {
+		first, _ := strconv.Atoi(m[1])
+		last, _ := strconv.Atoi(m[2])
+		if (first < 0) != (last < 0) || last < first {
+			return sliceRange{}, false
+		}
+		return sliceRange{offset: first, length: last - first + 1}, true
+	}
+	if sliceSingle.MatchString(s) {
+		n, _ := strconv.Atoi(s)
+		return sliceRange{offset: n, length: 1}, true
+	}
+	return sliceRange{}, false
+}
+
+// setElem is a member of an "in" set: a value or an inclusive range.
+type setElem struct {
+	lo, hi operand // hi is nil for a single value
+}
+
+func (p *parser) parseSet() ([]setElem, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var set []setElem
+	for !p.isPunct("}") {
+		if p.peek().kind == tokEOF {
+			return nil, p.errorf(p.peek(), "unterminated set")
+		}
+		if p.isPunct(",") {
+			p.next()
+			continue
+		}
+		t := p.peek()
+		if t.kind == tokWord && strings.Contains(t.text, "..") {
+			p.next()
+			lo, hi, _ := strings.Cut(t.text, "..")
+			set = append(set, setElem{
+				lo: literalOperand{value{kind: kindRaw, text: lo}},
+				hi: literalOperand{value{kind: kindRaw, text: hi}},
+			})
+			continue
+		}
+		elem, err := p.parseOperand(true)
+		if err != nil {
+			return nil, err
+		}
+		set = append(set, setElem{lo: elem})
+	}
+	p.next()
+	if len(set) == 0 {
+		return nil, p.errorf(p.peek(), "empty set")
+	}
+	return set, nil
+}
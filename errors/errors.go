@@ -84,6 +84,77 @@ func (e *TSharkNotFoundError) Path() string {
 	return e.path
 }
 
+// ToolError represents a failed run of a Wireshark command-line tool other
+// than TShark, such as editcap or mergecap
+type ToolError struct {
+	BaseError
+	tool     string
+	command  string
+	output   string
+	exitCode int
+}
+
+// NewToolError creates a new ToolError; exitCode is -1 if the tool did not
+// exit normally
+func NewToolError(tool string, command string, output string, exitCode int, cause error) *ToolError {
+	message := fmt.Sprintf("%s failed", tool)
+	if output != "" {
+		message += ": " + output
+	}
+	return &ToolError{
+		BaseError: BaseError{
+			message: message,
+			cause:   cause,
+		},
+		tool:     tool,
+		command:  command,
+		output:   output,
+		exitCode: exitCode,
+	}
+}
+
+// Tool returns the name of the tool that failed
+func (e *ToolError) Tool() string {
+	return e.tool
+}
+
+// Command returns the command that caused the error
+func (e *ToolError) Command() string {
+	return e.command
+}
+
+// Output returns the tool's error output
+func (e *ToolError) Output() string {
+	return e.output
+}
+
+// ExitCode returns the tool's exit status, or -1
+func (e *ToolError) ExitCode() int {
+	return e.exitCode
+}
+
+// ToolNotFoundError represents an error when a Wireshark command-line tool
+// is not found
+type ToolNotFoundError struct {
+	BaseError
+	tool string
+}
+
+// NewToolNotFoundError creates a new ToolNotFoundError
+func NewToolNotFoundError(tool string) *ToolNotFoundError {
+	return &ToolNotFoundError{
+		BaseError: BaseError{
+			message: fmt.Sprintf("%s executable not found", tool),
+		},
+		tool: tool,
+	}
+}
+
+// Tool returns the name of the tool that was searched for
+func (e *ToolNotFoundError) Tool() string {
+	return e.tool
+}
+
 // ParseError represents an error during packet parsing
 type ParseError struct {
 	BaseError
@@ -197,10 +268,35 @@ func (e *FileNotFoundError) FilePath() string {
 	return e.filePath
 }
 
+// FileFormatError represents an error when a capture file is empty,
+// truncated or corrupt
+type FileFormatError struct {
+	BaseError
+	filePath string
+}
+
+// NewFileFormatError creates a new FileFormatError
+func NewFileFormatError(filePath string, cause error) *FileFormatError {
+	return &FileFormatError{
+		BaseError: BaseError{
+			message: fmt.Sprintf("Invalid capture file: %s", filePath),
+			cause:   cause,
+		},
+		filePath: filePath,
+	}
+}
+
+// FilePath returns the path of the invalid file
+func (e *FileFormatError) FilePath() string {
+	return e.filePath
+}
+
 // InvalidFilterError represents an error when an invalid filter is specified
 type InvalidFilterError struct {
 	BaseError
 	filter string
+	detail string
+	offset int
 }
 
 // NewInvalidFilterError creates a new InvalidFilterError
@@ -211,6 +307,24 @@ func NewInvalidFilterError(filter string, cause error) *InvalidFilterError {
 			cause:   cause,
 		},
 		filter: filter,
+		offset: -1,
+	}
+}
+
+// NewInvalidFilterErrorAt creates an InvalidFilterError carrying the
+// validator's message and the byte offset in the filter it points at, or -1
+func NewInvalidFilterErrorAt(filter string, detail string, offset int) *InvalidFilterError {
+	message := fmt.Sprintf("Invalid filter: %s: %s", filter, detail)
+	if offset >= 0 {
+		message += fmt.Sprintf(" (at offset %d)", offset)
+	}
+	return &InvalidFilterError{
+		BaseError: BaseError{
+			message: message,
+		},
+		filter: filter,
+		detail: detail,
+		offset: offset,
 	}
 }
 
@@ -219,6 +333,16 @@ func (e *InvalidFilterError) Filter() string {
 	return e.filter
 }
 
+// Detail returns the validator's explanation, if any
+func (e *InvalidFilterError) Detail() string {
+	return e.detail
+}
+
+// Offset returns the byte offset of the error in the filter, or -1 if unknown
+func (e *InvalidFilterError) Offset() int {
+	return e.offset
+}
+
 // As attempts to convert an error to a specific type
 func As(err error, target interface{}) bool {
 	return errors.As(err, target)
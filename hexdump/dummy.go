@@ -0,0 +1,244 @@
+package hexdump
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"net/netip"
+)
+
+// DummyHeader selects the dummy headers Parse puts in front of each frame,
+// like text2pcap's -e, -i, -u, -T, -s and -S options.
+type DummyHeader int
+
+const (
+	// DummyNone leaves frames as dumped.
+	DummyNone DummyHeader = iota
+	// DummyEthernet adds an Ethernet header of Options.EtherType.
+	DummyEthernet
+	// DummyIP adds Ethernet and IP headers of Options.IPProtocol.
+	DummyIP
+	// DummyUDP adds Ethernet, IP and UDP headers.
+	DummyUDP
+	// DummyTCP adds Ethernet, IP and TCP headers. Sequence numbers advance
+	// with each frame, as one direction of a connection.
+	DummyTCP
+	// DummySCTP adds Ethernet, IP and an SCTP common header; the dump holds
+	// the SCTP chunks.
+	DummySCTP
+	// DummySCTPData also wraps the dump in an SCTP DATA chunk.
+	DummySCTPData
+)
+
+const (
+	linkTypeEthernet = 1
+
+	etherTypeIPv4 = 0x0800
+	etherTypeIPv6 = 0x86dd
+
+	protoTCP  = 6
+	protoUDP  = 17
+	protoSCTP = 132
+)
+
+// text2pcap's default addresses.
+var (
+	defaultSrcAddr = netip.AddrFrom4([4]byte{10, 1, 1, 1})
+	defaultDstAddr = netip.AddrFrom4([4]byte{10, 2, 2, 2})
+)
+
+func (o Options) validate() error {
+	if o.Dummy < DummyNone || o.Dummy > DummySCTPData {
+		return fmt.Errorf("hexdump: unknown dummy header %d", o.Dummy)
+	}
+	if o.Dummy < DummyIP {
+		return nil
+	}
+	src, dst := o.addrs()
+	if src.Is4() != dst.Is4() {
+		return fmt.Errorf("hexdump: source %s and destination %s are not the same IP version", src, dst)
+	}
+	if o.Dummy == DummyIP && o.IPProtocol == 0 {
+		return fmt.Errorf("hexdump: DummyIP needs an IP protocol")
+	}
+	return nil
+}
+
+func (o Options) addrs() (src, dst netip.Addr) {
+	src, dst = o.SrcAddr, o.DstAddr
+	if !src.IsValid() {
+		src = defaultSrcAddr
+	}
+	if !dst.IsValid() {
+		dst = defaultDstAddr
+	}
+	return src.Unmap(), dst.Unmap()
+}
+
+// encapsulator adds dummy headers, keeping the per-flow counters that
+// advance from frame to frame.
+type encapsulator struct {
+	opts     Options
+	src, dst netip.Addr
+	ipID     uint16
+	tcpSeq   uint32
+	sctpTSN  uint32
+	sctpSSN  uint16
+}
+
+func newEncapsulator(opts Options) *encapsulator {
+	e := &encapsulator{opts: opts}
+	e.src, e.dst = opts.addrs()
+	return e
+}
+
+// wrap returns payload behind the configured headers.
+func (e *encapsulator) wrap(payload []byte) []byte {
+	var proto uint8
+	switch e.opts.Dummy {
+	case DummyEthernet:
+		etherType := e.opts.EtherType
+		if etherType == 0 {
+			etherType = etherTypeIPv4
+		}
+		return ethernet(etherType, payload)
+	case DummyIP:
+		proto = e.opts.IPProtocol
+	case DummyUDP:
+		proto, payload = protoUDP, e.udp(payload)
+	case DummyTCP:
+		proto, payload = protoTCP, e.tcp(payload)
+	case DummySCTP, DummySCTPData:
+		proto, payload = protoSCTP, e.sctp(payload)
+	}
+	if e.src.Is4() {
+		return ethernet(etherTypeIPv4, e.ipv4(proto, payload))
+	}
+	return ethernet(etherTypeIPv6, e.ipv6(proto, payload))
+}
+
+func ethernet(etherType uint16, payload []byte) []byte {
+	b := []byte{
+		0x0a, 0x02, 0x02, 0x02, 0x02, 0x02, // destination
+		0x0a, 0x01, 0x01, 0x01, 0x01, 0x01, // source
+	}
+	b = binary.BigEndian.AppendUint16(b, etherType)
+	return append(b, payload...)
+}
+
+func (e *encapsulator) ipv4(proto uint8, payload []byte) []byte {
+	b := make([]byte, 20, 20+len(payload))
+	b[0] = 0x45 // version 4, 20-byte header
+	binary.BigEndian.PutUint16(b[2:], uint16(20+len(payload)))
+	binary.BigEndian.PutUint16(b[4:], e.ipID)
+	e.ipID++
+	b[8] = 64 // TTL
+	b[9] = proto
+	src, dst := e.src.As4(), e.dst.As4()
+	copy(b[12:], src[:])
+	copy(b[16:], dst[:])
+	binary.BigEndian.PutUint16(b[10:], checksum(0, b))
+	return append(b, payload...)
+}
+
+func (e *encapsulator) ipv6(proto uint8, payload []byte) []byte {
+	b := make([]byte, 40, 40+len(payload))
+	b[0] = 0x60 // version 6
+	binary.BigEndian.PutUint16(b[4:], uint16(len(payload)))
+	b[6] = proto
+	b[7] = 64 // hop limit
+	src, dst := e.src.As16(), e.dst.As16()
+	copy(b[8:], src[:])
+	copy(b[24:], dst[:])
+	return append(b, payload...)
+}
+
+func (e *encapsulator) udp(payload []byte) []byte {
+	b := binary.BigEndian.AppendUint16(nil, e.opts.SrcPort)
+	b = binary.BigEndian.AppendUint16(b, e.opts.DstPort)
+	b = binary.BigEndian.AppendUint16(b, uint16(8+len(payload)))
+	b = append(b, 0, 0)
+	b = append(b, payload...)
+	sum := checksum(e.pseudoHeaderSum(protoUDP, len(b)), b)
+	if sum == 0 {
+		sum = 0xffff // zero means no checksum
+	}
+	binary.BigEndian.PutUint16(b[6:], sum)
+	return b
+}
+
+func (e *encapsulator) tcp(payload []byte) []byte {
+	b := binary.BigEndian.AppendUint16(nil, e.opts.SrcPort)
+	b = binary.BigEndian.AppendUint16(b, e.opts.DstPort)
+	b = binary.BigEndian.AppendUint32(b, e.tcpSeq)
+	b = binary.BigEndian.AppendUint32(b, 0) // acknowledgment
+	b = append(b, 5<<4, 0x18)               // 20-byte header; PSH, ACK
+	b = binary.BigEndian.AppendUint16(b, 0x2000)
+	b = append(b, 0, 0, 0, 0) // checksum, urgent pointer
+	b = append(b, payload...)
+	binary.BigEndian.PutUint16(b[16:], checksum(e.pseudoHeaderSum(protoTCP, len(b)), b))
+	e.tcpSeq += uint32(len(payload))
+	return b
+}
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+func (e *encapsulator) sctp(payload []byte) []byte {
+	b := binary.BigEndian.AppendUint16(nil, e.opts.SrcPort)
+	b = binary.BigEndian.AppendUint16(b, e.opts.DstPort)
+	b = binary.BigEndian.AppendUint32(b, e.opts.SCTPTag)
+	b = append(b, 0, 0, 0, 0) // checksum
+	if e.opts.Dummy == DummySCTPData {
+		b = append(b, 0, 0x03) // DATA chunk, beginning and end of a message
+		b = binary.BigEndian.AppendUint16(b, uint16(16+len(payload)))
+		b = binary.BigEndian.AppendUint32(b, e.sctpTSN)
+		b = binary.BigEndian.AppendUint16(b, 0) // stream identifier
+		b = binary.BigEndian.AppendUint16(b, e.sctpSSN)
+		b = binary.BigEndian.AppendUint32(b, e.opts.SCTPPPI)
+		e.sctpTSN++
+		e.sctpSSN++
+	}
+	b = append(b, payload...)
+	for len(b)%4 != 0 {
+		b = append(b, 0)
+	}
+	// The CRC32c is stored least significant byte first (RFC 9260, B).
+	binary.LittleEndian.PutUint32(b[8:], crc32.Checksum(b, castagnoli))
+	return b
+}
+
+// pseudoHeaderSum is the unfolded sum of the IP pseudo-header covered by
+// the UDP and TCP checksums.
+func (e *encapsulator) pseudoHeaderSum(proto uint8, length int) uint32 {
+	var sum uint32
+	add := func(b []byte) {
+		for i := 0; i+1 < len(b); i += 2 {
+			sum += uint32(binary.BigEndian.Uint16(b[i:]))
+		}
+	}
+	if e.src.Is4() {
+		src, dst := e.src.As4(), e.dst.As4()
+		add(src[:])
+		add(dst[:])
+	} else {
+		src, dst := e.src.As16(), e.dst.As16()
+		add(src[:])
+		add(dst[:])
+	}
+	return sum + uint32(proto) + uint32(length)
+}
+
+// checksum returns the Internet checksum of b, starting from an unfolded
+// partial sum.
+func checksum(sum uint32, b []byte) uint16 {
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(b[i:]))
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	for sum > 0xffff {
+		sum = sum&0xffff + sum>>16
+	}
+	return ^uint16(sum)
+}
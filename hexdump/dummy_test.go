@@ -0,0 +1,107 @@
+package hexdump
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+const payloadDump = "0000 68 65 6c 6c 6f\n0000 77 6f 72 6c 64 21\n" // "hello", "world!"
+
+func TestDummyEthernet(t *testing.T) {
+	dump := mustParse(t, payloadDump, Options{Dummy: DummyEthernet, EtherType: 0x88b5, LinkType: 101})
+	f := dump.Frames[0].Data
+	if dump.LinkType != linkTypeEthernet || len(f) != 14+5 || binary.BigEndian.Uint16(f[12:]) != 0x88b5 || string(f[14:]) != "hello" {
+		t.Errorf("link type %d, frame %x", dump.LinkType, f)
+	}
+}
+
+func TestDummyUDPv4(t *testing.T) {
+	dump := mustParse(t, payloadDump, Options{Dummy: DummyUDP, SrcPort: 1234, DstPort: 5060})
+	for i, want := range []string{"hello", "world!"} {
+		f := dump.Frames[i].Data
+		if binary.BigEndian.Uint16(f[12:]) != etherTypeIPv4 {
+			t.Fatalf("frame %d ethertype = %x", i, f[12:14])
+		}
+		ip := f[14:]
+		if ip[0] != 0x45 || ip[9] != protoUDP || int(binary.BigEndian.Uint16(ip[2:])) != len(ip) || checksum(0, ip[:20]) != 0 {
+			t.Errorf("frame %d IPv4 header = %x", i, ip[:20])
+		}
+		if netip.AddrFrom4([4]byte(ip[12:16])) != defaultSrcAddr || netip.AddrFrom4([4]byte(ip[16:20])) != defaultDstAddr {
+			t.Errorf("frame %d addresses = %x", i, ip[12:20])
+		}
+		udp := ip[20:]
+		e := newEncapsulator(Options{})
+		if binary.BigEndian.Uint16(udp) != 1234 || binary.BigEndian.Uint16(udp[2:]) != 5060 ||
+			checksum(e.pseudoHeaderSum(protoUDP, len(udp)), udp) != 0 || string(udp[8:]) != want {
+			t.Errorf("frame %d UDP = %x", i, udp)
+		}
+	}
+	if id0, id1 := binary.BigEndian.Uint16(dump.Frames[0].Data[18:]), binary.BigEndian.Uint16(dump.Frames[1].Data[18:]); id1 != id0+1 {
+		t.Errorf("IP IDs = %d, %d", id0, id1)
+	}
+}
+
+func TestDummyTCPv6(t *testing.T) {
+	src, dst := netip.MustParseAddr("2001:db8::1"), netip.MustParseAddr("2001:db8::2")
+	opts := Options{Dummy: DummyTCP, SrcAddr: src, DstAddr: dst, SrcPort: 40000, DstPort: 80}
+	dump := mustParse(t, payloadDump, opts)
+	e := newEncapsulator(opts)
+	for i, seq := range []uint32{0, 5} {
+		f := dump.Frames[i].Data
+		ip := f[14:]
+		if binary.BigEndian.Uint16(f[12:]) != etherTypeIPv6 || ip[0]>>4 != 6 || ip[6] != protoTCP ||
+			int(binary.BigEndian.Uint16(ip[4:])) != len(ip)-40 || netip.AddrFrom16([16]byte(ip[24:40])) != dst {
+			t.Fatalf("frame %d IPv6 header = %x", i, ip[:40])
+		}
+		tcp := ip[40:]
+		if binary.BigEndian.Uint32(tcp[4:]) != seq || tcp[12] != 5<<4 || checksum(e.pseudoHeaderSum(protoTCP, len(tcp)), tcp) != 0 {
+			t.Errorf("frame %d TCP = %x", i, tcp)
+		}
+	}
+}
+
+func TestDummySCTPData(t *testing.T) {
+	dump := mustParse(t, payloadDump, Options{Dummy: DummySCTPData, SrcPort: 2905, DstPort: 2905, SCTPTag: 7, SCTPPPI: 3})
+	for i, f := range dump.Frames {
+		sctp := f.Data[14+20:]
+		if len(sctp)%4 != 0 || binary.BigEndian.Uint32(sctp[4:]) != 7 {
+			t.Fatalf("frame %d SCTP = %x", i, sctp)
+		}
+		sum := binary.LittleEndian.Uint32(sctp[8:])
+		zeroed := append([]byte(nil), sctp...)
+		copy(zeroed[8:12], []byte{0, 0, 0, 0})
+		if sum != crc32.Checksum(zeroed, castagnoli) {
+			t.Errorf("frame %d checksum = %08x", i, sum)
+		}
+		chunk := sctp[12:]
+		if chunk[0] != 0 || binary.BigEndian.Uint32(chunk[4:]) != uint32(i) || binary.BigEndian.Uint32(chunk[12:]) != 3 {
+			t.Errorf("frame %d DATA chunk = %x", i, chunk)
+		}
+		if n := int(binary.BigEndian.Uint16(chunk[2:])); n != 16+len("hello")+i {
+			t.Errorf("frame %d chunk length = %d", i, n)
+		}
+	}
+}
+
+func TestDummyOptionsInvalid(t *testing.T) {
+	for name, opts := range map[string]Options{
+		"mixed versions": {Dummy: DummyUDP, SrcAddr: netip.MustParseAddr("::1")},
+		"no protocol":    {Dummy: DummyIP},
+		"unknown header": {Dummy: DummySCTPData + 1},
+	} {
+		if _, err := Parse(strings.NewReader(payloadDump), opts); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	// A commonly used IPv4 header example, whose checksum is b861.
+	hdr := []byte{0x45, 0, 0, 0x73, 0, 0, 0x40, 0, 0x40, 0x11, 0, 0, 0xc0, 0xa8, 0, 1, 0xc0, 0xa8, 0, 0xc7}
+	if got := checksum(0, hdr); got != 0xb861 {
+		t.Errorf("checksum = %04x, want b861", got)
+	}
+}
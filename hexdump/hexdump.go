@@ -0,0 +1,315 @@
+// Package hexdump imports packets from text hex dumps, the way text2pcap
+// does, without needing Wireshark installed. It reads offset dumps as
+// printed by od -Ax -tx1, hexdump -C, tcpdump -x, router consoles and
+// Wireshark's "Copy as Hex + ASCII Dump", or one hex string per packet as
+// Wireshark's "Copy as Hex Stream" produces.
+//
+// Dumps taken above the link layer can be given dummy Ethernet, IP, UDP,
+// TCP or SCTP headers so tshark dissects them. The result can be written as
+// a pcap file or fed straight into a capture.InMemCapture:
+//
+//	dump, err := hexdump.Parse(f, hexdump.Options{Dummy: hexdump.DummyUDP, DstPort: 5060})
+//	pkts, err := dump.Feed(capture.NewInMemCapture())
+package hexdump
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/netip"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frame is one packet read from a dump.
+type Frame struct {
+	Data []byte
+	// Time is the timestamp matched by Options.TimeRegexp in the text before
+	// the packet, or zero.
+	Time time.Time
+}
+
+// Dump is the packets read from a hex dump, with any dummy headers added.
+type Dump struct {
+	// LinkType is the pcap link type of the frames: Ethernet when dummy
+	// headers were added, Options.LinkType otherwise.
+	LinkType int
+	Frames   []Frame
+}
+
+// Options configures Parse. The zero value reads offset dumps of Ethernet
+// frames without timestamps.
+type Options struct {
+	// Stream reads each line of hex digits as a whole packet, as in
+	// Wireshark's "Copy as Hex Stream", instead of reading offset dumps.
+	Stream bool
+
+	// TimeRegexp finds a packet's timestamp in the lines before it. Its
+	// first subexpression, or the whole match if it has none, is parsed
+	// with TimeLayout in Location (UTC by default). An empty TimeLayout
+	// parses seconds since the epoch, e.g. "1700000000.123456".
+	TimeRegexp *regexp.Regexp
+	TimeLayout string
+	Location   *time.Location
+
+	// LinkType is the link type of frames without dummy headers; Ethernet
+	// by default.
+	LinkType int
+
+	// Dummy selects the headers to put in front of each frame. Ethernet is
+	// always included; each level includes those below it.
+	Dummy DummyHeader
+	// EtherType is the Ethernet type for DummyEthernet; IPv4 by default.
+	EtherType uint16
+	// IPProtocol is the IP protocol number for DummyIP.
+	IPProtocol uint8
+	// SrcAddr and DstAddr are the IP addresses, both IPv4 or both IPv6;
+	// 10.1.1.1 and 10.2.2.2 by default, as text2pcap uses.
+	SrcAddr netip.Addr
+	DstAddr netip.Addr
+	// SrcPort and DstPort are the UDP, TCP or SCTP ports.
+	SrcPort uint16
+	DstPort uint16
+	// SCTPTag is the SCTP verification tag, and SCTPPPI the payload
+	// protocol identifier of DummySCTPData chunks.
+	SCTPTag uint32
+	SCTPPPI uint32
+}
+
+// Parse reads the packets of a hex dump. Text outside the dump, such as log
+// lines or an ASCII column, is skipped; see the package documentation for
+// the formats read.
+//
+// In an offset dump a line starts with the offset of its first byte, of
+// three or more hex digits, followed by bytes as pairs of hex digits or as
+// groups of four (tcpdump's 16-bit words, read in the order written). Offset
+// zero starts a packet, and a "*" line repeats the line before it up to
+// the next offset. A smaller offset than expected drops the bytes read
+// past it, which were an ASCII column taken for hex, as text2pcap does; a
+// larger one ends the packet, and the line is skipped.
+func Parse(r io.Reader, opts Options) (*Dump, error) {
+	p, err := newParser(opts)
+	if err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if err := p.line(scanner.Text()); err != nil {
+			return nil, fmt.Errorf("hexdump: line %d: %w", line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	p.finish()
+
+	dump := &Dump{LinkType: opts.LinkType, Frames: p.frames}
+	if dump.LinkType == 0 {
+		dump.LinkType = linkTypeEthernet
+	}
+	if opts.Dummy != DummyNone {
+		dump.LinkType = linkTypeEthernet
+		enc := newEncapsulator(opts)
+		for i := range dump.Frames {
+			dump.Frames[i].Data = enc.wrap(dump.Frames[i].Data)
+		}
+	}
+	return dump, nil
+}
+
+// parser accumulates frames line by line.
+type parser struct {
+	opts    Options
+	frames  []Frame
+	current *Frame
+	pending time.Time // timestamp for the next packet
+	last    []byte    // bytes of the previous dump line
+	repeat  bool      // a "*" line: od and hexdump squeeze repeated lines
+}
+
+func newParser(opts Options) (*parser, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	if opts.Location == nil {
+		opts.Location = time.UTC
+	}
+	return &parser{opts: opts}, nil
+}
+
+func (p *parser) line(text string) error {
+	if p.opts.Stream {
+		if data, ok := parseStreamLine(text); ok {
+			p.frames = append(p.frames, Frame{Data: data, Time: p.pending})
+			p.pending = time.Time{}
+			return nil
+		}
+		return p.matchTime(text)
+	}
+
+	if strings.TrimSpace(text) == "*" && p.current != nil {
+		p.repeat = true
+		return nil
+	}
+	offset, data, ok := parseDumpLine(text)
+	if ok && p.repeat && p.current != nil {
+		p.expand(offset)
+	}
+	p.repeat = false
+	switch {
+	case ok && offset == 0:
+		p.finish()
+		p.current = &Frame{Time: p.pending}
+		p.pending = time.Time{}
+	case ok && p.current != nil && offset <= len(p.current.Data):
+		p.current.Data = p.current.Data[:offset]
+	default:
+		p.finish()
+		return p.matchTime(text)
+	}
+	p.current.Data = append(p.current.Data, data...)
+	p.last = data
+	return nil
+}
+
+// expand repeats the last line up to offset, restoring the lines a "*"
+// stood for.
+func (p *parser) expand(offset int) {
+	n := len(p.last)
+	gap := offset - len(p.current.Data)
+	if n == 0 || gap <= 0 || gap%n != 0 {
+		return
+	}
+	for i := 0; i < gap/n; i++ {
+		p.current.Data = append(p.current.Data, p.last...)
+	}
+}
+
+// finish ends the current packet.
+func (p *parser) finish() {
+	if p.current != nil && len(p.current.Data) > 0 {
+		p.frames = append(p.frames, *p.current)
+	}
+	p.current = nil
+}
+
+func (p *parser) matchTime(text string) error {
+	if p.opts.TimeRegexp == nil {
+		return nil
+	}
+	m := p.opts.TimeRegexp.FindStringSubmatch(text)
+	if m == nil {
+		return nil
+	}
+	s := m[0]
+	if len(m) > 1 {
+		s = m[1]
+	}
+	t, err := parseTime(s, p.opts.TimeLayout, p.opts.Location)
+	if err != nil {
+		return err
+	}
+	p.pending = t
+	return nil
+}
+
+func parseTime(s, layout string, loc *time.Location) (time.Time, error) {
+	if layout != "" {
+		t, err := time.ParseInLocation(layout, s, loc)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid timestamp %q: %w", s, err)
+		}
+		return t, nil
+	}
+	sec, frac, _ := strings.Cut(s, ".")
+	secs, err := strconv.ParseInt(sec, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp %q", s)
+	}
+	var nanos int64
+	if frac != "" {
+		if nanos, err = strconv.ParseInt((frac + "000000000")[:9], 10, 64); err != nil {
+			return time.Time{}, fmt.Errorf("invalid timestamp %q", s)
+		}
+	}
+	return time.Unix(secs, nanos).UTC(), nil
+}
+
+// parseDumpLine reads an offset dump line. ok is false for lines that do
+// not start with an offset.
+func parseDumpLine(text string) (offset int, data []byte, ok bool) {
+	rest := strings.TrimLeft(text, " \t")
+	end := strings.IndexAny(rest, " \t")
+	if end < 0 {
+		end = len(rest)
+	}
+	tok := rest[:end]
+	tok = strings.TrimSuffix(tok, ":")
+	if t, found := strings.CutPrefix(tok, "0x"); found {
+		tok = t
+	} else if t, found := strings.CutPrefix(tok, "0X"); found {
+		tok = t
+	}
+	if len(tok) < 3 || !isHex(tok) {
+		return 0, nil, false
+	}
+	off, err := strconv.ParseUint(tok, 16, 31)
+	if err != nil {
+		return 0, nil, false
+	}
+
+	// Bytes run until a token that is not a group of hex digits of the
+	// first group's width, or a gap of three or more spaces after them,
+	// which sets off an ASCII column.
+	rest = rest[end:]
+	width := 0
+	for {
+		trimmed := strings.TrimLeft(rest, " \t")
+		gap := len(rest) - len(trimmed)
+		if trimmed == "" || (len(data) > 0 && gap >= 3) {
+			break
+		}
+		end := strings.IndexAny(trimmed, " \t")
+		if end < 0 {
+			end = len(trimmed)
+		}
+		group := trimmed[:end]
+		if width == 0 && (len(group) == 2 || len(group) == 4) {
+			width = len(group)
+		}
+		if len(group) != width || !isHex(group) {
+			break
+		}
+		b, _ := hex.DecodeString(group)
+		data = append(data, b...)
+		rest = trimmed[end:]
+	}
+	return int(off), data, true
+}
+
+// parseStreamLine reads a line holding a whole packet as hex digits.
+func parseStreamLine(text string) ([]byte, bool) {
+	s := strings.TrimSpace(text)
+	if t, found := strings.CutPrefix(s, "0x"); found {
+		s = t
+	}
+	if len(s) < 2 || len(s)%2 != 0 || !isHex(s) {
+		return nil, false
+	}
+	data, err := hex.DecodeString(s)
+	return data, err == nil
+}
+
+func isHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,160 @@
+package hexdump
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// frame1 and frame2 are the packets every dump format below holds.
+var (
+	frame1 = []byte("\x00\x1a\x2b\x3c\x4d\x5e\x00\x11\x22\x33\x44\x55\x08\x00GET / HTTP/1.1\r\n")
+	frame2 = []byte{0xde, 0xad, 0xbe, 0xef}
+)
+
+func mustParse(t *testing.T, text string, opts Options) *Dump {
+	t.Helper()
+	dump, err := Parse(strings.NewReader(text), opts)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	return dump
+}
+
+func checkFrames(t *testing.T, dump *Dump, want ...[]byte) {
+	t.Helper()
+	if len(dump.Frames) != len(want) {
+		t.Fatalf("got %d frames, want %d: %x", len(dump.Frames), len(want), dump.Frames)
+	}
+	for i := range want {
+		if !bytes.Equal(dump.Frames[i].Data, want[i]) {
+			t.Errorf("frame %d = %x\nwant %x", i, dump.Frames[i].Data, want[i])
+		}
+	}
+}
+
+func TestParseFormats(t *testing.T) {
+	for name, text := range map[string]string{
+		"od": `000000 00 1a 2b 3c 4d 5e 00 11 22 33 44 55 08 00 47 45
+000010 54 20 2f 20 48 54 54 50 2f 31 2e 31 0d 0a
+00001e
+000000 de ad be ef
+000004
+`,
+		"hexdump -C": `00000000  00 1a 2b 3c 4d 5e 00 11  22 33 44 55 08 00 47 45  |..+<M^.."3DU..GE|
+00000010  54 20 2f 20 48 54 54 50  2f 31 2e 31 0d 0a        |T / HTTP/1.1..|
+0000001e
+00000000  de ad be ef                                       |....|
+00000004
+`,
+		"wireshark": `0000   00 1a 2b 3c 4d 5e 00 11 22 33 44 55 08 00 47 45   ..+<M^.."3DU..GE
+0010   54 20 2f 20 48 54 54 50 2f 31 2e 31 0d 0a         T / HTTP/1.1..
+
+0000   de ad be ef                                       ....
+`,
+		"tcpdump": `12:00:00.000000 IP 10.0.0.1 > 10.0.0.2: length 16
+	0x0000:  001a 2b3c 4d5e 0011 2233 4455 0800 4745
+	0x0010:  5420 2f20 4854 5450 2f31 2e31 0d0a
+12:00:01.000000 IP 10.0.0.1 > 10.0.0.2: length 4
+	0x0000:  dead beef
+`,
+		// Older Wireshark releases separate the ASCII column by two spaces,
+		// so it is read as hex until the next offset rewinds it.
+		"ascii taken for hex": `0000  00 1a 2b 3c 4d 5e 00 11  22 33 44 55 08 00 47 45  ab cd
+0010  54 20 2f 20 48 54 54 50  2f 31 2e 31 0d 0a
+0000  de ad be ef
+`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			dump := mustParse(t, text, Options{})
+			checkFrames(t, dump, frame1, frame2)
+			if dump.LinkType != linkTypeEthernet {
+				t.Errorf("link type = %d", dump.LinkType)
+			}
+		})
+	}
+}
+
+func TestParseSqueezedLines(t *testing.T) {
+	text := `00000000  00 00 00 00 00 00 00 00  00 00 00 00 00 00 00 00  |................|
+*
+00000030  01 02                                             |..|
+00000032
+`
+	want := append(make([]byte, 48), 1, 2)
+	checkFrames(t, mustParse(t, text, Options{}), want)
+}
+
+func TestParseSkipsText(t *testing.T) {
+	text := `Router# show packet
+2024 packets dropped
+0000 de ad
+12 bytes of junk
+abc not a dump line
+0000 be ef
+`
+	checkFrames(t, mustParse(t, text, Options{}), []byte{0xde, 0xad}, []byte{0xbe, 0xef})
+}
+
+func TestParseStream(t *testing.T) {
+	text := "001a2b3c4d5e\n# comment\n0xdeadbeef\nnot hex\nabc\n"
+	checkFrames(t, mustParse(t, text, Options{Stream: true}),
+		[]byte{0x00, 0x1a, 0x2b, 0x3c, 0x4d, 0x5e}, frame2)
+}
+
+func TestParseTimestamps(t *testing.T) {
+	text := `*Mar  1 12:00:00.250: packet in
+0000 de ad
+no timestamp here
+0000 be ef
+*Mar  1 12:00:02.500: packet out
+0000 ca fe
+`
+	dump := mustParse(t, text, Options{
+		TimeRegexp: regexp.MustCompile(`^\*(\w+ +\d+ [\d:.]+):`),
+		TimeLayout: "Jan _2 15:04:05.000",
+	})
+	want := []time.Time{
+		time.Date(0, 3, 1, 12, 0, 0, 250e6, time.UTC),
+		{},
+		time.Date(0, 3, 1, 12, 0, 2, 500e6, time.UTC),
+	}
+	for i, f := range dump.Frames {
+		if !f.Time.Equal(want[i]) {
+			t.Errorf("frame %d time = %v, want %v", i, f.Time, want[i])
+		}
+	}
+
+	epoch := mustParse(t, "ts=1700000000.5\n0000 01\n", Options{TimeRegexp: regexp.MustCompile(`ts=([\d.]+)`)})
+	if !epoch.Frames[0].Time.Equal(time.Unix(1700000000, 500e6)) {
+		t.Errorf("epoch time = %v", epoch.Frames[0].Time)
+	}
+
+	_, err := Parse(strings.NewReader("ts=yesterday\n"), Options{TimeRegexp: regexp.MustCompile(`ts=(\w+)`)})
+	if err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("bad timestamp error = %v", err)
+	}
+}
+
+func TestParseDumpLine(t *testing.T) {
+	for _, tc := range []struct {
+		line   string
+		ok     bool
+		offset int
+		data   []byte
+	}{
+		{"0010 01 02 03", true, 16, []byte{1, 2, 3}},
+		{"0x0020:  0102 0304", true, 32, []byte{1, 2, 3, 4}},
+		{"00 01 02", false, 0, nil},             // offsets have three or more digits
+		{"zzzz 01 02", false, 0, nil},           // not hex
+		{"0000 01 0203 04", true, 0, []byte{1}}, // group widths may not change
+		{"0000   01 02   03", true, 0, []byte{1, 2}},
+	} {
+		offset, data, ok := parseDumpLine(tc.line)
+		if ok != tc.ok || offset != tc.offset || !bytes.Equal(data, tc.data) {
+			t.Errorf("parseDumpLine(%q) = %d, %x, %v", tc.line, offset, data, ok)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package hexdump
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/p-vbordei/GoShark/capture"
+	"github.com/p-vbordei/GoShark/packet"
+)
+
+// Times returns the timestamp of each frame. A frame without one is given
+// the previous frame's time plus a microsecond, starting from the Unix
+// epoch, so frames keep their order.
+func (d *Dump) Times() []time.Time {
+	times := make([]time.Time, len(d.Frames))
+	prev := time.Unix(0, 0).Add(-time.Microsecond)
+	for i, f := range d.Frames {
+		t := f.Time
+		if t.IsZero() {
+			t = prev.Add(time.Microsecond)
+		}
+		times[i], prev = t, t
+	}
+	return times
+}
+
+// WritePcap writes the frames to w as a pcap file with nanosecond
+// timestamps; see Times for frames without one.
+func (d *Dump) WritePcap(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	snapLen := 262144
+	for _, f := range d.Frames {
+		snapLen = max(snapLen, len(f.Data))
+	}
+	header := binary.LittleEndian.AppendUint32(nil, 0xa1b23c4d) // nanosecond pcap
+	header = binary.LittleEndian.AppendUint16(header, 2)
+	header = binary.LittleEndian.AppendUint16(header, 4)
+	header = append(header, 0, 0, 0, 0, 0, 0, 0, 0) // time zone, accuracy
+	header = binary.LittleEndian.AppendUint32(header, uint32(snapLen))
+	header = binary.LittleEndian.AppendUint32(header, uint32(d.LinkType))
+	if _, err := bw.Write(header); err != nil {
+		return fmt.Errorf("error writing PCAP header: %w", err)
+	}
+
+	for i, t := range d.Times() {
+		data := d.Frames[i].Data
+		rec := binary.LittleEndian.AppendUint32(nil, uint32(t.Unix()))
+		rec = binary.LittleEndian.AppendUint32(rec, uint32(t.Nanosecond()))
+		rec = binary.LittleEndian.AppendUint32(rec, uint32(len(data)))
+		rec = binary.LittleEndian.AppendUint32(rec, uint32(len(data)))
+		if _, err := bw.Write(rec); err != nil {
+			return fmt.Errorf("error writing packet header: %w", err)
+		}
+		if _, err := bw.Write(data); err != nil {
+			return fmt.Errorf("error writing packet data: %w", err)
+		}
+	}
+	return bw.Flush()
+}
+
+// WritePcapFile writes the frames to a new pcap file at path.
+func (d *Dump) WritePcapFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := d.WritePcap(f); err != nil {
+		f.Close()
+		os.Remove(path)
+		return err
+	}
+	return f.Close()
+}
+
+// Feed dissects the frames with c and returns the packets, which are also
+// added to c's packet list.
+func (d *Dump) Feed(c *capture.InMemCapture) ([]*packet.Packet, error) {
+	data := make([][]byte, len(d.Frames))
+	times := make([]*time.Time, len(d.Frames))
+	for i, t := range d.Times() {
+		data[i], times[i] = d.Frames[i].Data, &t
+	}
+	return c.FeedPackets(data, capture.LinkType(d.LinkType), times)
+}
@@ -0,0 +1,70 @@
+package hexdump
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/p-vbordei/GoShark/capture"
+	"github.com/p-vbordei/GoShark/pcapfile"
+	"github.com/p-vbordei/GoShark/tshark"
+)
+
+func TestTimes(t *testing.T) {
+	at := time.Unix(1700000000, 0)
+	dump := &Dump{Frames: []Frame{{}, {}, {Time: at}, {}}}
+	want := []time.Time{time.Unix(0, 0), time.Unix(0, 1000), at, at.Add(time.Microsecond)}
+	for i, got := range dump.Times() {
+		if !got.Equal(want[i]) {
+			t.Errorf("time %d = %v, want %v", i, got, want[i])
+		}
+	}
+}
+
+var timeRegexp = regexp.MustCompile(`ts=([\d.]+)`)
+
+func TestWritePcap(t *testing.T) {
+	dump := mustParse(t, "ts=1700000000.123456789\n0000 de ad be ef\n0000 01 02\n",
+		Options{TimeRegexp: timeRegexp, LinkType: 101})
+	path := filepath.Join(t.TempDir(), "dump.pcap")
+	if err := dump.WritePcapFile(path); err != nil {
+		t.Fatalf("WritePcapFile: %v", err)
+	}
+
+	info, err := pcapfile.FileInfo(path)
+	if err != nil {
+		t.Fatalf("FileInfo: %v", err)
+	}
+	if info.Format != pcapfile.FormatPcap || info.Packets != 2 || info.Interfaces[0].LinkType != 101 ||
+		info.Interfaces[0].TSResolution != time.Nanosecond || !info.FirstTime.Equal(time.Unix(1700000000, 123456789)) {
+		t.Errorf("info = %+v", info)
+	}
+
+	data, _ := os.ReadFile(path)
+	ix, err := pcapfile.NewIndex(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("NewIndex: %v", err)
+	}
+	if f := ix.Frames[0]; !bytes.Equal(data[f.Offset+16:f.Offset+f.Size], []byte{0xde, 0xad, 0xbe, 0xef}) {
+		t.Errorf("frame 1 = %x", data[f.Offset:f.Offset+f.Size])
+	}
+}
+
+func TestFeed(t *testing.T) {
+	if _, err := tshark.FindTShark(); err != nil {
+		t.Skip("tshark not found")
+	}
+	dump := mustParse(t, payloadDump, Options{Dummy: DummyUDP, SrcPort: 1234, DstPort: 53})
+	c := capture.NewInMemCapture()
+	defer c.Close()
+	pkts, err := dump.Feed(c)
+	if err != nil {
+		t.Fatalf("Feed: %v", err)
+	}
+	if len(pkts) != 2 || !pkts[0].HasLayer("udp") {
+		t.Errorf("packets = %v", pkts)
+	}
+}
@@ -0,0 +1,55 @@
+package packet
+
+import "sort"
+
+// Comments returns the comments stored with the frame in a pcapng file
+// (opt_comment), in file order. tshark reports them as frame.comment,
+// either in a "pkt_comment" layer of their own or inside the frame layer,
+// depending on its version.
+func (p *Packet) Comments() []string {
+	var comments []string
+	for i := range p.Layers {
+		if name := p.Layers[i].Name; name != "pkt_comment" && name != "frame" {
+			continue
+		}
+		comments = appendComments(comments, p.GetLayerByIndex(i).Fields)
+	}
+	return comments
+}
+
+// appendComments collects the frame.comment values in fields and its
+// subtrees. Repeated comments are merged into an array by
+// --no-duplicate-keys.
+func appendComments(comments []string, fields map[string]interface{}) []string {
+	if v, ok := fields["frame.comment"]; ok {
+		if list, ok := v.([]interface{}); ok {
+			for _, item := range list {
+				if s := coerceFieldString(item); s != "" {
+					comments = append(comments, s)
+				}
+			}
+		} else if s := coerceFieldString(v); s != "" {
+			comments = append(comments, s)
+		}
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		if k != "frame.comment" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		switch v := fields[k].(type) {
+		case map[string]interface{}:
+			comments = appendComments(comments, v)
+		case []interface{}:
+			for _, item := range v {
+				if sub, ok := item.(map[string]interface{}); ok {
+					comments = appendComments(comments, sub)
+				}
+			}
+		}
+	}
+	return comments
+}
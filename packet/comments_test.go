@@ -0,0 +1,42 @@
+package packet
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestComments(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		layers []Layer
+		want   []string
+	}{
+		{"none", []Layer{
+			{Name: "frame", Fields: map[string]interface{}{"frame.number": "1"}},
+		}, nil},
+		{"pkt_comment layers", []Layer{
+			{Name: "pkt_comment", Fields: map[string]interface{}{"frame.comment": "first"}},
+			{Name: "pkt_comment", Fields: map[string]interface{}{"frame.comment": "second"}},
+			{Name: "frame", Fields: map[string]interface{}{"frame.number": "1"}},
+		}, []string{"first", "second"}},
+		{"merged duplicates", []Layer{
+			{Name: "pkt_comment", Fields: map[string]interface{}{"frame.comment": []interface{}{"first", "second"}}},
+		}, []string{"first", "second"}},
+		{"frame subtree", []Layer{
+			{Name: "frame", Fields: map[string]interface{}{
+				"frame.number": "1",
+				"frame.comment_tree": map[string]interface{}{
+					"frame.comment": map[string]interface{}{"show": "reviewed"},
+				},
+			}},
+			{Name: "ip", Fields: map[string]interface{}{"frame.comment": "ignored"}},
+		}, []string{"reviewed"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p := &Packet{Layers: tc.layers}
+			if got := p.Comments(); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Comments() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
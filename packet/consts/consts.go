@@ -60,44 +60,36 @@ var ProtocolHierarchy = map[string]int{
 	"QUIC":    40,
 }
 
+// The lists above seed the protocol registry (see NewRegistry); the
+// Is*Layer helpers consult the default registry, so they also recognize
+// protocols loaded from tshark and match names case-insensitively.
+
 // IsTransportLayer checks if a protocol is a transport layer protocol
 func IsTransportLayer(protocol string) bool {
-	for _, p := range TransportLayers {
-		if p == protocol {
-			return true
-		}
-	}
-	return false
+	return Default().IsTransport(protocol)
 }
 
 // IsNetworkLayer checks if a protocol is a network layer protocol
 func IsNetworkLayer(protocol string) bool {
-	for _, p := range NetworkLayers {
-		if p == protocol {
-			return true
-		}
-	}
-	return false
+	return Default().IsNetwork(protocol)
 }
 
 // IsLinkLayer checks if a protocol is a link layer protocol
 func IsLinkLayer(protocol string) bool {
-	for _, p := range LinkLayers {
-		if p == protocol {
-			return true
-		}
-	}
-	return false
+	return Default().IsLink(protocol)
 }
 
 // IsApplicationLayer checks if a protocol is an application layer protocol
 func IsApplicationLayer(protocol string) bool {
-	for _, p := range ApplicationLayers {
-		if p == protocol {
-			return true
-		}
-	}
-	return false
+	return Default().IsApplication(protocol)
+}
+
+// roleLayers gives the ProtocolHierarchy rank of protocols known only by role.
+var roleLayers = map[Role]int{
+	RoleLink:        10,
+	RoleNetwork:     20,
+	RoleTransport:   30,
+	RoleApplication: 40,
 }
 
 // GetProtocolLayer returns the layer number of a protocol
@@ -105,5 +97,8 @@ func GetProtocolLayer(protocol string) int {
 	if layer, ok := ProtocolHierarchy[protocol]; ok {
 		return layer
 	}
+	if layer, ok := roleLayers[Default().Role(protocol)]; ok {
+		return layer
+	}
 	return -1
 }
@@ -0,0 +1,372 @@
+package consts
+
+import (
+	"bufio"
+	"io"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// Role is the OSI role a protocol plays inside a packet.
+type Role int
+
+const (
+	RoleUnknown Role = iota
+	RoleLink
+	RoleNetwork
+	RoleTransport
+	RoleApplication
+)
+
+// String returns the role name.
+func (r Role) String() string {
+	switch r {
+	case RoleLink:
+		return "link"
+	case RoleNetwork:
+		return "network"
+	case RoleTransport:
+		return "transport"
+	case RoleApplication:
+		return "application"
+	default:
+		return "unknown"
+	}
+}
+
+// Protocol is one entry of "tshark -G protocols".
+type Protocol struct {
+	Name       string // Full name, e.g. "Transmission Control Protocol"
+	ShortName  string // Short name, e.g. "TCP"
+	FilterName string // Display-filter name and layer name, e.g. "tcp"
+}
+
+// DissectorTable is one entry of "tshark -G dissector-tables".
+type DissectorTable struct {
+	Name     string // Table name, e.g. "tcp.port"
+	UIName   string // Human-readable name, e.g. "TCP port"
+	Type     string // Selector type, e.g. "FT_UINT16" or "FT_STRING"
+	Base     string // Selector display base, e.g. "BASE_DEC"
+	Protocol string // Filter name of the protocol owning the table
+	DecodeAs bool   // Whether the table accepts decode-as (-d) rules
+}
+
+// Heuristic is one entry of "tshark -G heuristic-decodes".
+type Heuristic struct {
+	Parent  string // Protocol whose payload the heuristic inspects, e.g. "tcp"
+	Name    string // Heuristic short name, e.g. "tls_tcp"
+	Enabled bool   // Whether the heuristic is enabled by default
+}
+
+// metaLayers are pseudo-protocols tshark emits that carry no protocol of
+// their own; they never count as a packet's highest layer.
+var metaLayers = map[string]bool{
+	"data":               true,
+	"fake-field-wrapper": true,
+	"geninfo":            true,
+	"pkt_comment":        true,
+}
+
+// carrierTableRoles assigns a role to protocols registered in well-known
+// dissector tables, used by Classify.
+var carrierTableRoles = map[string]Role{
+	"wtap_encap": RoleLink,
+	"ethertype":  RoleNetwork,
+}
+
+// Registry classifies protocols by OSI role and records which dissector
+// tables and heuristics each protocol is reachable from. NewRegistry seeds it
+// from the static lists in this package; the tshark package fills it from
+// "tshark -G protocols", "-G dissector-tables", "-G decodes" and
+// "-G heuristic-decodes". A Registry is not safe for concurrent mutation, but
+// a fully loaded one may be read concurrently.
+type Registry struct {
+	protocols  map[string]Protocol       // by filter name
+	byShort    map[string]string         // lowercase short name -> filter name
+	roles      map[string]Role           // by lowercase filter name
+	tables     map[string]DissectorTable // by table name
+	carriers   map[string][]string       // filter name -> tables it is registered in
+	registered map[string][]string       // table name -> protocols registered in it
+	heuristics map[string][]Heuristic    // parent filter name -> heuristics
+}
+
+// NewRegistry creates a registry seeded with the static layer lists.
+func NewRegistry() *Registry {
+	r := &Registry{
+		protocols:  make(map[string]Protocol),
+		byShort:    make(map[string]string),
+		roles:      make(map[string]Role),
+		tables:     make(map[string]DissectorTable),
+		carriers:   make(map[string][]string),
+		registered: make(map[string][]string),
+		heuristics: make(map[string][]Heuristic),
+	}
+	seed := []struct {
+		names []string
+		role  Role
+	}{
+		{LinkLayers, RoleLink},
+		{NetworkLayers, RoleNetwork},
+		{TransportLayers, RoleTransport},
+		{ApplicationLayers, RoleApplication},
+	}
+	for _, s := range seed {
+		for _, name := range s.names {
+			r.roles[strings.ToLower(name)] = s.role
+		}
+	}
+	return r
+}
+
+var defaultRegistry atomic.Pointer[Registry]
+
+func init() {
+	defaultRegistry.Store(NewRegistry())
+}
+
+// Default returns the registry used by packet classification
+// (Packet.TransportLayer, Packet.HighestLayer, session keys).
+func Default() *Registry {
+	return defaultRegistry.Load()
+}
+
+// SetDefault replaces the registry used by packet classification, typically
+// with one loaded from tshark via tshark.LoadProtocolRegistry.
+func SetDefault(r *Registry) {
+	if r != nil {
+		defaultRegistry.Store(r)
+	}
+}
+
+// splitReportLines yields the tab-separated columns of each non-empty line.
+func splitReportLines(rd io.Reader, fn func(cols []string)) error {
+	scanner := bufio.NewScanner(rd)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		fn(strings.Split(line, "\t"))
+	}
+	return scanner.Err()
+}
+
+// LoadProtocols reads "tshark -G protocols" output.
+func (r *Registry) LoadProtocols(rd io.Reader) error {
+	return splitReportLines(rd, func(cols []string) {
+		if len(cols) < 3 {
+			return
+		}
+		p := Protocol{Name: cols[0], ShortName: cols[1], FilterName: cols[2]}
+		r.protocols[p.FilterName] = p
+		r.byShort[strings.ToLower(p.ShortName)] = p.FilterName
+	})
+}
+
+// LoadDissectorTables reads "tshark -G dissector-tables" output.
+func (r *Registry) LoadDissectorTables(rd io.Reader) error {
+	return splitReportLines(rd, func(cols []string) {
+		if len(cols) < 2 {
+			return
+		}
+		t := DissectorTable{Name: cols[0], UIName: cols[1]}
+		if len(cols) > 2 {
+			t.Type = cols[2]
+		}
+		if len(cols) > 3 {
+			t.Base = cols[3]
+		}
+		if len(cols) > 4 {
+			t.Protocol = r.filterName(cols[4])
+		}
+		// Older releases omit the column; assume decode-as is supported then.
+		t.DecodeAs = len(cols) <= 5 || cols[5] == "Decode As supported"
+		r.tables[t.Name] = t
+	})
+}
+
+// LoadDecodes reads "tshark -G decodes" output: the selector registrations
+// of every protocol in every dissector table.
+func (r *Registry) LoadDecodes(rd io.Reader) error {
+	return splitReportLines(rd, func(cols []string) {
+		if len(cols) < 3 {
+			return
+		}
+		r.addRegistration(cols[0], cols[2])
+	})
+}
+
+// LoadHeuristics reads "tshark -G heuristic-decodes" output.
+func (r *Registry) LoadHeuristics(rd io.Reader) error {
+	return splitReportLines(rd, func(cols []string) {
+		if len(cols) < 2 {
+			return
+		}
+		h := Heuristic{Parent: cols[0], Name: cols[1], Enabled: true}
+		if len(cols) > 2 {
+			h.Enabled = cols[2] == "T"
+		}
+		r.heuristics[h.Parent] = append(r.heuristics[h.Parent], h)
+	})
+}
+
+// addRegistration records that proto is registered in table, once.
+func (r *Registry) addRegistration(table, proto string) {
+	for _, t := range r.carriers[proto] {
+		if t == table {
+			return
+		}
+	}
+	r.carriers[proto] = append(r.carriers[proto], table)
+	r.registered[table] = append(r.registered[table], proto)
+}
+
+// filterName resolves a protocol short name (as used by the dissector-tables
+// report) to its filter name, falling back to the lowercased name.
+func (r *Registry) filterName(name string) string {
+	if f, ok := r.byShort[strings.ToLower(name)]; ok {
+		return f
+	}
+	return strings.ToLower(name)
+}
+
+// Classify assigns roles to loaded protocols that the static lists do not
+// cover. A protocol registered under ip.proto/ipv6.nxt that owns a port table
+// is a transport; other ip.proto protocols and ethertype protocols are network
+// protocols; wtap_encap protocols are link layers; protocols reachable through
+// a port table or a transport heuristic are application protocols.
+func (r *Registry) Classify() {
+	portOwners := map[string]bool{}
+	for _, t := range r.tables {
+		if strings.HasSuffix(t.Name, ".port") && t.Protocol != "" {
+			portOwners[t.Protocol] = true
+		}
+	}
+
+	assign := func(proto string, role Role) {
+		if _, ok := r.roles[proto]; !ok {
+			r.roles[proto] = role
+		}
+	}
+
+	for _, table := range []string{"ip.proto", "ipv6.nxt"} {
+		for _, proto := range r.registered[table] {
+			if portOwners[proto] {
+				assign(proto, RoleTransport)
+			} else {
+				assign(proto, RoleNetwork)
+			}
+		}
+	}
+	for table, role := range carrierTableRoles {
+		for _, proto := range r.registered[table] {
+			assign(proto, role)
+		}
+	}
+	for table, protos := range r.registered {
+		owner := strings.TrimSuffix(table, ".port")
+		if owner == table || r.roles[owner] != RoleTransport {
+			continue
+		}
+		for _, proto := range protos {
+			assign(proto, RoleApplication)
+		}
+	}
+	for parent, hs := range r.heuristics {
+		if r.roles[parent] != RoleTransport {
+			continue
+		}
+		for _, h := range hs {
+			// Heuristic names are usually "<proto>_<parent>" (e.g. "tls_tcp").
+			assign(strings.TrimSuffix(h.Name, "_"+parent), RoleApplication)
+		}
+	}
+}
+
+// SetRole overrides the role of a protocol.
+func (r *Registry) SetRole(name string, role Role) {
+	r.roles[strings.ToLower(name)] = role
+}
+
+// Role returns the OSI role of a protocol (case-insensitive).
+func (r *Registry) Role(name string) Role {
+	return r.roles[strings.ToLower(name)]
+}
+
+// IsLink reports whether the protocol is a link layer.
+func (r *Registry) IsLink(name string) bool { return r.Role(name) == RoleLink }
+
+// IsNetwork reports whether the protocol is a network layer.
+func (r *Registry) IsNetwork(name string) bool { return r.Role(name) == RoleNetwork }
+
+// IsTransport reports whether the protocol is a transport layer.
+func (r *Registry) IsTransport(name string) bool { return r.Role(name) == RoleTransport }
+
+// IsApplication reports whether the protocol is an application layer.
+func (r *Registry) IsApplication(name string) bool { return r.Role(name) == RoleApplication }
+
+// IsMeta reports whether a layer name is a tshark pseudo-protocol ("data",
+// "_ws.*" expert/malformed layers, PDML wrappers) rather than a real protocol.
+func (r *Registry) IsMeta(name string) bool {
+	name = strings.ToLower(name)
+	return metaLayers[name] || strings.HasPrefix(name, "_ws.")
+}
+
+// HasProtocol reports whether tshark knows a protocol by this filter name.
+func (r *Registry) HasProtocol(name string) bool {
+	_, ok := r.protocols[name]
+	return ok
+}
+
+// Protocol returns the protocol with the given filter name.
+func (r *Registry) Protocol(name string) (Protocol, bool) {
+	p, ok := r.protocols[name]
+	return p, ok
+}
+
+// Protocols returns every loaded protocol sorted by filter name.
+func (r *Registry) Protocols() []Protocol {
+	out := make([]Protocol, 0, len(r.protocols))
+	for _, p := range r.protocols {
+		out = append(out, p)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].FilterName < out[j].FilterName })
+	return out
+}
+
+// Table returns the dissector table with the given name.
+func (r *Registry) Table(name string) (DissectorTable, bool) {
+	t, ok := r.tables[name]
+	return t, ok
+}
+
+// CarrierTables returns the dissector tables a protocol is registered in,
+// i.e. what it runs over (e.g. "tcp.port" and "udp.port" for dns).
+func (r *Registry) CarrierTables(proto string) []string {
+	return r.carriers[proto]
+}
+
+// ProtocolsIn returns the protocols registered in a dissector table.
+func (r *Registry) ProtocolsIn(table string) []string {
+	return r.registered[table]
+}
+
+// Heuristics returns the heuristic dissectors that inspect a parent
+// protocol's payload.
+func (r *Registry) Heuristics(parent string) []Heuristic {
+	return r.heuristics[parent]
+}
+
+// Heuristic looks a heuristic dissector up by its short name.
+func (r *Registry) Heuristic(name string) (Heuristic, bool) {
+	for _, hs := range r.heuristics {
+		for _, h := range hs {
+			if h.Name == name {
+				return h, true
+			}
+		}
+	}
+	return Heuristic{}, false
+}
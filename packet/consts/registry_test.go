@@ -0,0 +1,140 @@
+package consts
+
+import (
+	"strings"
+	"testing"
+)
+
+const testProtocols = "Transmission Control Protocol\tTCP\ttcp\n" +
+	"User Datagram Protocol\tUDP\tudp\n" +
+	"Stream Control Transmission Protocol\tSCTP\tsctp\n" +
+	"Internet Protocol Version 4\tIPv4\tip\n" +
+	"Internet Control Message Protocol\tICMP\ticmp\n" +
+	"Generic Routing Encapsulation\tGRE\tgre\n" +
+	"Address Resolution Protocol\tARP/RARP\tarp\n" +
+	"Ethernet\tEthernet\teth\n" +
+	"Domain Name System\tDNS\tdns\n" +
+	"Diameter Protocol\tDIAMETER\tdiameter\n" +
+	"MQ Telemetry Transport Protocol\tMQTT\tmqtt\n"
+
+const testDissectorTables = "ip.proto\tIP protocol\tFT_UINT8\tBASE_DEC\tIPv4\tDecode As not supported\n" +
+	"tcp.port\tTCP port\tFT_UINT16\tBASE_PT_TCP\tTCP\tDecode As supported\n" +
+	"udp.port\tUDP port\tFT_UINT16\tBASE_PT_UDP\tUDP\tDecode As supported\n" +
+	"sctp.port\tSCTP port\tFT_UINT16\tBASE_PT_SCTP\tSCTP\tDecode As supported\n" +
+	"ethertype\tEthertype\tFT_UINT16\tBASE_HEX\tEthernet\tDecode As supported\n"
+
+const testDecodes = "ip.proto\t6\ttcp\n" +
+	"ip.proto\t17\tudp\n" +
+	"ip.proto\t132\tsctp\n" +
+	"ip.proto\t1\ticmp\n" +
+	"ip.proto\t47\tgre\n" +
+	"ethertype\t2048\tip\n" +
+	"ethertype\t2054\tarp\n" +
+	"wtap_encap\t1\teth\n" +
+	"udp.port\t53\tdns\n" +
+	"tcp.port\t53\tdns\n" +
+	"sctp.port\t3868\tdiameter\n"
+
+const testHeuristics = "tcp\tmqtt_tcp\tT\n" +
+	"udp\tstun_udp\tF\n"
+
+func loadTestRegistry(t *testing.T) *Registry {
+	t.Helper()
+	r := NewRegistry()
+	loaders := []struct {
+		load func(*Registry, string) error
+		data string
+	}{
+		{func(r *Registry, s string) error { return r.LoadProtocols(strings.NewReader(s)) }, testProtocols},
+		{func(r *Registry, s string) error { return r.LoadDissectorTables(strings.NewReader(s)) }, testDissectorTables},
+		{func(r *Registry, s string) error { return r.LoadDecodes(strings.NewReader(s)) }, testDecodes},
+		{func(r *Registry, s string) error { return r.LoadHeuristics(strings.NewReader(s)) }, testHeuristics},
+	}
+	for _, l := range loaders {
+		if err := l.load(r, l.data); err != nil {
+			t.Fatalf("load: %v", err)
+		}
+	}
+	r.Classify()
+	return r
+}
+
+func TestRegistryClassify(t *testing.T) {
+	r := loadTestRegistry(t)
+
+	tests := map[string]Role{
+		"tcp":      RoleTransport,
+		"sctp":     RoleTransport,
+		"icmp":     RoleNetwork,
+		"gre":      RoleNetwork,
+		"ip":       RoleNetwork,
+		"arp":      RoleNetwork,
+		"eth":      RoleLink,
+		"dns":      RoleApplication,
+		"diameter": RoleApplication,
+		"mqtt":     RoleApplication,
+		"stun":     RoleApplication, // only reachable through the stun_udp heuristic
+		"frame":    RoleUnknown,
+	}
+	for name, want := range tests {
+		if got := r.Role(name); got != want {
+			t.Errorf("Role(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestRegistryTables(t *testing.T) {
+	r := loadTestRegistry(t)
+
+	table, ok := r.Table("tcp.port")
+	if !ok {
+		t.Fatal("tcp.port table not loaded")
+	}
+	if table.Protocol != "tcp" || !table.DecodeAs || table.Type != "FT_UINT16" {
+		t.Errorf("tcp.port = %+v", table)
+	}
+	if table, _ := r.Table("ip.proto"); table.DecodeAs || table.Protocol != "ip" {
+		t.Errorf("ip.proto = %+v", table)
+	}
+
+	if got := strings.Join(r.CarrierTables("dns"), ","); got != "udp.port,tcp.port" {
+		t.Errorf("CarrierTables(dns) = %q", got)
+	}
+	if got := strings.Join(r.ProtocolsIn("ethertype"), ","); got != "ip,arp" {
+		t.Errorf("ProtocolsIn(ethertype) = %q", got)
+	}
+
+	h, ok := r.Heuristic("stun_udp")
+	if !ok || h.Parent != "udp" || h.Enabled {
+		t.Errorf("Heuristic(stun_udp) = %+v, %v", h, ok)
+	}
+	if p, ok := r.Protocol("arp"); !ok || p.ShortName != "ARP/RARP" {
+		t.Errorf("Protocol(arp) = %+v, %v", p, ok)
+	}
+}
+
+func TestRegistryMetaAndDefault(t *testing.T) {
+	r := NewRegistry()
+	for _, name := range []string{"data", "_ws.malformed", "_ws.expert", "fake-field-wrapper"} {
+		if !r.IsMeta(name) {
+			t.Errorf("IsMeta(%q) = false, want true", name)
+		}
+	}
+	if r.IsMeta("tcp") {
+		t.Error("IsMeta(tcp) = true, want false")
+	}
+
+	if !IsTransportLayer("TCP") || !IsTransportLayer("udp") {
+		t.Error("static transport layers should be seeded into the default registry")
+	}
+
+	old := Default()
+	defer SetDefault(old)
+	SetDefault(loadTestRegistry(t))
+	if !IsApplicationLayer("mqtt") {
+		t.Error("IsApplicationLayer(mqtt) should use the loaded default registry")
+	}
+	if got := GetProtocolLayer("gre"); got != 20 {
+		t.Errorf("GetProtocolLayer(gre) = %d, want 20", got)
+	}
+}
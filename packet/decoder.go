@@ -0,0 +1,438 @@
+package packet
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// DecoderOptions configure a Decoder.
+type DecoderOptions struct {
+	// LazyLayers defers decoding each layer's fields until the layer is first
+	// accessed (GetLayer, Layer.GetField, ...), so layers a caller never looks
+	// at cost almost nothing. The frame layer is always decoded eagerly.
+	// Until then a layer's Fields and Offsets are nil: code reading
+	// Layer.Fields directly from Packet.Layers must call Packet.DecodeAll
+	// first. Accessors decode under a lock, so goroutines may share a
+	// packet.
+	LazyLayers bool
+}
+
+// Decoder reads tshark -T json output (a JSON array of packets, or a stream
+// of packet objects) and decodes each packet in a single pass with a
+// purpose-built tokenizer, interning field names across packets. It is much
+// cheaper than encoding/json, which validates every packet, re-tokenizes the
+// layers and allocates each field name anew.
+//
+// NextRaw and DecodePacket split framing from decoding, so packets can be
+// framed on one goroutine and decoded on others (one Decoder per goroutine).
+type Decoder struct {
+	r      *bufio.Reader
+	opts   DecoderOptions
+	buf    []byte
+	intern map[string]string
+
+	started bool
+	inArray bool
+	done    bool
+}
+
+// NewDecoder creates a decoder reading from r. r may be nil for a Decoder
+// used only through DecodePacket.
+func NewDecoder(r io.Reader, opts DecoderOptions) *Decoder {
+	d := &Decoder{opts: opts, intern: make(map[string]string)}
+	if r != nil {
+		d.r = bufio.NewReaderSize(r, 64*1024)
+	}
+	return d
+}
+
+// Next decodes the next packet. It returns io.EOF after the last packet.
+func (d *Decoder) Next() (*Packet, error) {
+	raw, err := d.NextRaw()
+	if err != nil {
+		return nil, err
+	}
+	return d.DecodePacket(raw)
+}
+
+// readByteSkippingSpace returns the next non-whitespace byte.
+func (d *Decoder) readByteSkippingSpace() (byte, error) {
+	for {
+		c, err := d.r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		switch c {
+		case ' ', '\t', '\n', '\r':
+			continue
+		}
+		return c, nil
+	}
+}
+
+// NextRaw returns the bytes of the next packet object without decoding it.
+// The slice is only valid until the next call. It returns io.EOF after the
+// last packet and io.ErrUnexpectedEOF if the input ends mid-array or
+// mid-packet.
+func (d *Decoder) NextRaw() ([]byte, error) {
+	if d.r == nil {
+		return nil, fmt.Errorf("decoder has no input")
+	}
+	if d.done {
+		return nil, io.EOF
+	}
+
+	c, err := d.readByteSkippingSpace()
+	if !d.started {
+		if err != nil {
+			return nil, err // empty input: plain io.EOF
+		}
+		d.started = true
+		if c == '[' {
+			d.inArray = true
+			if c, err = d.readByteSkippingSpace(); err == nil && c == ']' {
+				d.done = true
+				return nil, io.EOF
+			}
+		}
+	} else if d.inArray && err == nil {
+		// Between elements: expect ',' or the closing ']'.
+		switch c {
+		case ']':
+			d.done = true
+			return nil, io.EOF
+		case ',':
+			c, err = d.readByteSkippingSpace()
+		default:
+			return nil, fmt.Errorf("json: unexpected %q between packets", c)
+		}
+	}
+	if err == io.EOF {
+		if d.inArray {
+			return nil, io.ErrUnexpectedEOF
+		}
+		d.done = true
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, err
+	}
+	if c != '{' {
+		return nil, fmt.Errorf("json: unexpected %q, expected packet object", c)
+	}
+	return d.readObject()
+}
+
+// readObject reads the rest of an object whose '{' was consumed, scanning
+// buffered chunks rather than single bytes.
+func (d *Decoder) readObject() ([]byte, error) {
+	d.buf = append(d.buf[:0], '{')
+	depth := 1
+	inString, escaped := false, false
+	for {
+		chunk, err := d.r.Peek(d.r.Buffered())
+		if len(chunk) == 0 {
+			if _, err = d.r.Peek(1); err != nil {
+				if err == io.EOF {
+					err = io.ErrUnexpectedEOF
+				}
+				return nil, err
+			}
+			continue
+		}
+		for i := 0; i < len(chunk); i++ {
+			c := chunk[i]
+			switch {
+			case escaped:
+				escaped = false
+			case inString:
+				// Jump to the next quote or backslash.
+				j := bytes.IndexAny(chunk[i:], "\"\\")
+				if j < 0 {
+					i = len(chunk)
+					continue
+				}
+				i += j
+				if chunk[i] == '\\' {
+					escaped = true
+				} else {
+					inString = false
+				}
+			case c == '"':
+				inString = true
+			case c == '{' || c == '[':
+				depth++
+			case c == '}' || c == ']':
+				depth--
+				if depth == 0 {
+					d.buf = append(d.buf, chunk[:i+1]...)
+					_, _ = d.r.Discard(i + 1)
+					return d.buf, nil
+				}
+			}
+		}
+		d.buf = append(d.buf, chunk...)
+		_, _ = d.r.Discard(len(chunk))
+	}
+}
+
+// DecodePacket decodes one packet object, e.g. one returned by NextRaw. With
+// LazyLayers the bytes are copied, since undecoded layers keep referencing
+// them.
+func (d *Decoder) DecodePacket(raw []byte) (*Packet, error) {
+	if d.opts.LazyLayers {
+		raw = append([]byte(nil), raw...)
+	}
+	p := &Packet{}
+	s := &jsonScanner{data: raw, intern: d.intern}
+	if err := decodePacket(s, p, d.opts.LazyLayers); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// lazyLayer holds an undecoded layer. It is shared by pointer, so copies of
+// the Layer (e.g. from GetMultipleLayers) decode only once. mu guards the
+// decoding and the first assignment of each copy's Fields and Offsets, so
+// several goroutines may read the same packet.
+type lazyLayer struct {
+	mu      sync.Mutex
+	decoded bool
+	raw     []byte
+	fields  map[string]interface{}
+	offsets map[string]*FieldOffset
+	err     error
+}
+
+// ensureDecoded decodes a lazily decoded layer's fields on first use and
+// stores them in the layer. The lazy state is never cleared, so concurrent
+// callers only ever read l.lazy, and each layer's Fields is written once,
+// under the lock, before any caller reads it.
+func (l *Layer) ensureDecoded() {
+	lz := l.lazy
+	if lz == nil {
+		return
+	}
+	lz.mu.Lock()
+	defer lz.mu.Unlock()
+	if !lz.decoded {
+		s := &jsonScanner{data: lz.raw}
+		lz.fields, lz.err = s.readObject()
+		if lz.fields == nil {
+			lz.fields = make(map[string]interface{})
+		}
+		lz.offsets = make(map[string]*FieldOffset)
+		extractOffsets(lz.fields, lz.offsets)
+		lz.raw = nil
+		lz.decoded = true
+	}
+	if l.Fields == nil {
+		l.Fields = lz.fields
+		l.Offsets = lz.offsets
+	}
+}
+
+// decodeErr returns the error decoding a lazily decoded layer, if any.
+func (l *Layer) decodeErr() error {
+	lz := l.lazy
+	if lz == nil {
+		return nil
+	}
+	lz.mu.Lock()
+	defer lz.mu.Unlock()
+	return lz.err
+}
+
+// IsDecoded reports whether the layer's fields have been decoded; it is
+// false only for untouched layers of a Decoder with LazyLayers.
+func (l *Layer) IsDecoded() bool {
+	lz := l.lazy
+	if lz == nil {
+		return true
+	}
+	lz.mu.Lock()
+	defer lz.mu.Unlock()
+	return lz.decoded && l.Fields != nil
+}
+
+// DecodeAll decodes every lazily decoded layer, returning the first error.
+// Afterwards Layer.Fields can be read directly.
+func (p *Packet) DecodeAll() error {
+	var firstErr error
+	for i := range p.Layers {
+		p.Layers[i].ensureDecoded()
+		if err := p.Layers[i].decodeErr(); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to decode %s layer: %w", p.Layers[i].Name, err)
+		}
+	}
+	return firstErr
+}
+
+// decodePacket decodes a tshark packet object into p.
+func decodePacket(s *jsonScanner, p *Packet, lazy bool) error {
+	sawLayers := false
+	err := s.eachMember(func(key string) error {
+		switch key {
+		case "_index":
+			v, err := s.readValue()
+			if err != nil {
+				return err
+			}
+			// _index is usually a string, occasionally an object.
+			switch idx := v.(type) {
+			case string:
+				p.Index.ProtocolID = idx
+			case map[string]interface{}:
+				if id, ok := idx["protocol_id"].(string); ok {
+					p.Index.ProtocolID = id
+				}
+			}
+			return nil
+		case "_source":
+			return s.eachMember(func(key string) error {
+				if key != "layers" {
+					_, _, err := s.skipValue()
+					return err
+				}
+				sawLayers = true
+				if err := decodeLayers(s, p, lazy); err != nil {
+					return fmt.Errorf("failed to decode layers: %w", err)
+				}
+				return nil
+			})
+		default:
+			_, _, err := s.skipValue()
+			return err
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if !sawLayers {
+		return fmt.Errorf("failed to decode layers: packet has no _source.layers")
+	}
+	return nil
+}
+
+// decodeLayers decodes the _source.layers object, preserving document order.
+// Duplicate layer keys merged into an array by --no-duplicate-keys become
+// separate layers; "<layer>_raw" siblings supply RawData and layer positions.
+func decodeLayers(s *jsonScanner, p *Packet, lazy bool) error {
+	rawByBase := map[string]interface{}{}
+	p.Layers = make([]Layer, 0, 8)
+
+	addLayer := func(layer Layer) {
+		if layer.lazy == nil {
+			layer.Offsets = make(map[string]*FieldOffset)
+			extractOffsets(layer.Fields, layer.Offsets)
+		}
+		if layer.Name == "frame" {
+			p.FrameNumber = coerceFieldString(layer.Fields["frame.number"])
+			p.FrameLen = coerceFieldString(layer.Fields["frame.len"])
+			p.FrameCapLen = coerceFieldString(layer.Fields["frame.cap_len"])
+			p.FrameTimeEpoch = coerceFieldString(layer.Fields["frame.time_epoch"])
+			p.FrameTime = coerceFieldString(layer.Fields["frame.time"])
+		}
+		p.Layers = append(p.Layers, layer)
+	}
+	// readLayer decodes (or, when lazy, only delimits) one layer object.
+	readLayer := func(name string) (Layer, error) {
+		if s.peek() != '{' {
+			return Layer{}, fmt.Errorf("failed to unmarshal %s layer: expected object", name)
+		}
+		if lazy && name != "frame" {
+			start, end, err := s.skipValue()
+			return Layer{Name: name, lazy: &lazyLayer{raw: s.data[start:end]}}, err
+		}
+		fields, err := s.readObject()
+		if err != nil {
+			return Layer{}, fmt.Errorf("failed to unmarshal %s layer: %w", name, err)
+		}
+		return Layer{Name: name, Fields: fields}, nil
+	}
+
+	err := s.eachMember(func(name string) error {
+		if strings.HasSuffix(name, "_raw") {
+			v, err := s.readValue()
+			if err != nil {
+				return err
+			}
+			rawByBase[strings.TrimSuffix(name, "_raw")] = v
+			return nil
+		}
+
+		if s.peek() != '[' {
+			layer, err := readLayer(name)
+			if err != nil {
+				return err
+			}
+			addLayer(layer)
+			return nil
+		}
+		// An array of objects is a set of duplicate layers.
+		var dups []Layer
+		err := s.eachElement(func() error {
+			layer, err := readLayer(name)
+			dups = append(dups, layer)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		if len(dups) == 0 {
+			return fmt.Errorf("failed to unmarshal %s layer: expected object", name)
+		}
+		for _, layer := range dups {
+			addLayer(layer)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if fr, ok := rawByBase["frame"]; ok {
+		if hexStr := hexFromRawValue(fr); hexStr != "" {
+			hexStr = strings.ReplaceAll(hexStr, ":", "")
+			if rawData, err := hex.DecodeString(hexStr); err == nil {
+				p.RawData = rawData
+			}
+		}
+	}
+
+	// Layer byte position/length from the matching _raw sibling.
+	for i := range p.Layers {
+		if rawArr, ok := rawByBase[p.Layers[i].Name].([]interface{}); ok && len(rawArr) >= 3 {
+			if pos, ok := parseInt(rawArr[1]); ok {
+				p.Layers[i].Pos = pos
+			}
+			if length, ok := parseInt(rawArr[2]); ok {
+				p.Layers[i].Len = length
+			}
+		}
+	}
+	return nil
+}
+
+// hexFromRawValue extracts the hex string of a "<layer>_raw" value, which is
+// a [hex, pos, size, ...] array or an object with a "value" key.
+func hexFromRawValue(v interface{}) string {
+	switch x := v.(type) {
+	case []interface{}:
+		if len(x) > 0 {
+			if s, ok := x[0].(string); ok {
+				return s
+			}
+		}
+	case map[string]interface{}:
+		if s, ok := x["value"].(string); ok {
+			return s
+		}
+	}
+	return ""
+}
@@ -0,0 +1,294 @@
+package packet
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const decoderTestJSON = `[
+  {"_index": "packets-1", "_source": {"layers": {
+    "frame_raw": ["0001020304050607", 0, 8, 0, 1],
+    "frame": {"frame.number": "1", "frame.len": "8", "frame.time_epoch": "1700000000.5"},
+    "ip_raw": ["0405060708", 2, 5, 0, 1],
+    "ip": {"ip.src": "10.0.0.1", "ip.flags_tree": {"ip.flags.df": "1"}, "ip.ttl_raw": ["40", 3, 1, 0, 4]},
+    "dns": [{"dns.qry.name": "a\"b\\cé"}, {"dns.qry.name": "x"}]
+  }}},
+  {"_index": {"protocol_id": "p2"}, "_type": "doc", "_score": null, "_source": {"layers": {
+    "frame": {"frame.number": "2"}, "tcp": {"tcp.srcport": "80", "tcp.analysis": {}}
+  }}}
+]`
+
+func decodeAllPackets(t *testing.T, input string, opts DecoderOptions) []*Packet {
+	t.Helper()
+	d := NewDecoder(strings.NewReader(input), opts)
+	var pkts []*Packet
+	for {
+		p, err := d.Next()
+		if err == io.EOF {
+			return pkts
+		}
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		pkts = append(pkts, p)
+	}
+}
+
+func TestDecoderPackets(t *testing.T) {
+	pkts := decodeAllPackets(t, decoderTestJSON, DecoderOptions{})
+	if len(pkts) != 2 {
+		t.Fatalf("decoded %d packets, want 2", len(pkts))
+	}
+
+	p := pkts[0]
+	if p.Index.ProtocolID != "packets-1" || p.FrameNumber != "1" || p.FrameTimeEpoch != "1700000000.5" {
+		t.Errorf("metadata = %q %q %q", p.Index.ProtocolID, p.FrameNumber, p.FrameTimeEpoch)
+	}
+	if !bytes.Equal(p.RawData, []byte{0, 1, 2, 3, 4, 5, 6, 7}) {
+		t.Errorf("RawData = %x", p.RawData)
+	}
+	var names []string
+	for _, l := range p.Layers {
+		names = append(names, l.Name)
+	}
+	if got := strings.Join(names, ","); got != "frame,ip,dns,dns" {
+		t.Errorf("layers = %q", got)
+	}
+	ip := p.GetLayer("ip")
+	if ip.Pos != 2 || ip.Len != 5 {
+		t.Errorf("ip Pos/Len = %d/%d", ip.Pos, ip.Len)
+	}
+	if off := ip.GetFieldOffset("ip.ttl"); off == nil || off.Start != 3 || off.Length != 1 {
+		t.Errorf("ip.ttl offset = %+v", off)
+	}
+	if tree, ok := ip.Fields["ip.flags_tree"].(map[string]interface{}); !ok || tree["ip.flags.df"] != "1" {
+		t.Errorf("ip.flags_tree = %#v", ip.Fields["ip.flags_tree"])
+	}
+	if got := p.GetMultipleLayers("dns")[0].GetField("dns.qry.name"); got != "a\"b\\cé" {
+		t.Errorf("escaped string = %q", got)
+	}
+
+	if pkts[1].Index.ProtocolID != "p2" || pkts[1].GetLayer("tcp").GetField("tcp.srcport") != "80" {
+		t.Errorf("second packet = %+v", pkts[1])
+	}
+}
+
+// TestDecoderMatchesEncodingJSON verifies the tokenizer produces exactly the
+// field values encoding/json would for real tshark output.
+func TestDecoderMatchesEncodingJSON(t *testing.T) {
+	data, err := os.ReadFile("../tshark_output.json")
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	pkts := decodeAllPackets(t, string(data), DecoderOptions{})
+
+	var generic []struct {
+		Source struct {
+			Layers map[string]map[string]interface{} `json:"layers"`
+		} `json:"_source"`
+	}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		t.Fatalf("encoding/json: %v", err)
+	}
+	if len(pkts) != len(generic) {
+		t.Fatalf("decoded %d packets, encoding/json %d", len(pkts), len(generic))
+	}
+	for i, p := range pkts {
+		for _, l := range p.Layers {
+			want, _ := json.Marshal(generic[i].Source.Layers[l.Name])
+			got, _ := json.Marshal(l.Fields)
+			if !bytes.Equal(got, want) {
+				t.Errorf("packet %d layer %s differs:\n got %s\nwant %s", i, l.Name, got, want)
+			}
+		}
+	}
+}
+
+func TestDecoderLazyLayers(t *testing.T) {
+	pkts := decodeAllPackets(t, decoderTestJSON, DecoderOptions{LazyLayers: true})
+	p := pkts[0]
+
+	if !p.Layers[0].IsDecoded() || p.FrameNumber != "1" {
+		t.Error("frame layer must be decoded eagerly")
+	}
+	if p.Layers[1].IsDecoded() || p.Layers[1].Fields != nil {
+		t.Error("ip layer should not be decoded before access")
+	}
+	if p.HighestLayer() != "dns" || p.GetLayerByIndex(1).Pos != 2 {
+		t.Error("layer names and positions must be available without decoding")
+	}
+
+	if got := p.GetLayer("ip").GetField("ip.src"); got != "10.0.0.1" {
+		t.Errorf("lazy ip.src = %v", got)
+	}
+	if !p.Layers[1].IsDecoded() || p.Layers[1].Fields["ip.src"] != "10.0.0.1" {
+		t.Error("GetLayer should decode the layer in place")
+	}
+
+	dns := p.GetMultipleLayers("dns")
+	if len(dns) != 2 || dns[1].GetField("dns.qry.name") != "x" {
+		t.Errorf("lazy duplicate layers = %+v", dns)
+	}
+
+	if err := pkts[1].DecodeAll(); err != nil {
+		t.Fatalf("DecodeAll: %v", err)
+	}
+	if pkts[1].Layers[1].Fields["tcp.srcport"] != "80" {
+		t.Error("DecodeAll should decode every layer")
+	}
+}
+
+func TestDecoderLazyLayersConcurrentAccess(t *testing.T) {
+	p := decodeAllPackets(t, decoderTestJSON, DecoderOptions{LazyLayers: true})[0]
+	dns := p.GetMultipleLayers("dns") // copies made before any goroutine decodes
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if got := p.GetLayer("ip").GetField("ip.src"); got != "10.0.0.1" {
+				t.Errorf("concurrent ip.src = %v", got)
+			}
+			if !p.GetLayer("ip").HasField("ip.src") || p.Layers[1].decodeErr() != nil {
+				t.Error("concurrent HasField failed")
+			}
+			if got := dns[1].GetField("dns.qry.name"); got != "x" {
+				t.Errorf("concurrent dns.qry.name = %v", got)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestDecoderStreamAndErrors(t *testing.T) {
+	// A stream of bare packet objects (no enclosing array) is accepted.
+	objects := `{"_source":{"layers":{"frame":{"frame.number":"1"}}}}
+{"_source":{"layers":{"frame":{"frame.number":"2"}}}}`
+	if pkts := decodeAllPackets(t, objects, DecoderOptions{}); len(pkts) != 2 || pkts[1].FrameNumber != "2" {
+		t.Errorf("object stream decoded %d packets", len(pkts))
+	}
+	if pkts := decodeAllPackets(t, " [ ] ", DecoderOptions{}); len(pkts) != 0 {
+		t.Errorf("empty array decoded %d packets", len(pkts))
+	}
+
+	d := NewDecoder(strings.NewReader(`[{"_source":{"layers":{"frame":{}}}}, {"_source":{"lay`), DecoderOptions{})
+	if _, err := d.Next(); err != nil {
+		t.Fatalf("first packet: %v", err)
+	}
+	if _, err := d.Next(); err != io.ErrUnexpectedEOF {
+		t.Errorf("truncated packet error = %v, want io.ErrUnexpectedEOF", err)
+	}
+
+	bad := []string{
+		`{"_source":{"layers":{"ip":"not an object"}}}`,
+		`{"_source":{}}`,
+		`{"_source":{"layers":{"ip":{"ip.src":}}}}`,
+	}
+	for _, in := range bad {
+		var p Packet
+		if err := p.UnmarshalJSON([]byte(in)); err == nil {
+			t.Errorf("UnmarshalJSON(%s) succeeded, want error", in)
+		}
+	}
+}
+
+// benchmarkCorpus replicates the test.pcap packet in tshark_output.json into
+// a stream of n packets.
+func benchmarkCorpus(b *testing.B, n int) []byte {
+	b.Helper()
+	data, err := os.ReadFile("../tshark_output.json")
+	if err != nil {
+		b.Fatalf("read fixture: %v", err)
+	}
+	var pkts []json.RawMessage
+	if err := json.Unmarshal(data, &pkts); err != nil {
+		b.Fatalf("fixture: %v", err)
+	}
+	var buf bytes.Buffer
+	buf.WriteString("[\n")
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			buf.WriteString(",\n")
+		}
+		buf.Write(pkts[i%len(pkts)])
+	}
+	buf.WriteString("\n]\n")
+	return buf.Bytes()
+}
+
+func benchmarkDecoder(b *testing.B, opts DecoderOptions, touch func(*Packet)) {
+	corpus := benchmarkCorpus(b, 1000)
+	b.SetBytes(int64(len(corpus)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		d := NewDecoder(bytes.NewReader(corpus), opts)
+		for {
+			p, err := d.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				b.Fatal(err)
+			}
+			touch(p)
+		}
+	}
+}
+
+// touchTCP reads one field, as a typical port-based filter would.
+func touchTCP(p *Packet) {
+	if tcp := p.GetLayer("tcp"); tcp != nil {
+		_ = tcp.GetField("tcp.srcport")
+	}
+}
+
+func BenchmarkDecoderEager(b *testing.B) {
+	benchmarkDecoder(b, DecoderOptions{}, touchTCP)
+}
+
+func BenchmarkDecoderLazy(b *testing.B) {
+	benchmarkDecoder(b, DecoderOptions{LazyLayers: true}, touchTCP)
+}
+
+// BenchmarkDecoderLazyUntouched only reads frame metadata, so no layer
+// besides frame is ever decoded.
+func BenchmarkDecoderLazyUntouched(b *testing.B) {
+	benchmarkDecoder(b, DecoderOptions{LazyLayers: true}, func(p *Packet) { _ = p.FrameNumber })
+}
+
+// BenchmarkEncodingJSON is the baseline: encoding/json streaming each packet
+// and unmarshalling every layer into a map, as sniffStream used to.
+func BenchmarkEncodingJSON(b *testing.B) {
+	corpus := benchmarkCorpus(b, 1000)
+	b.SetBytes(int64(len(corpus)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		dec := json.NewDecoder(bytes.NewReader(corpus))
+		if _, err := dec.Token(); err != nil {
+			b.Fatal(err)
+		}
+		for dec.More() {
+			var pkt struct {
+				Source struct {
+					Layers map[string]json.RawMessage `json:"layers"`
+				} `json:"_source"`
+			}
+			if err := dec.Decode(&pkt); err != nil {
+				b.Fatal(err)
+			}
+			for _, raw := range pkt.Source.Layers {
+				var fields map[string]interface{}
+				if err := json.Unmarshal(raw, &fields); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	}
+}
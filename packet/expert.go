@@ -0,0 +1,202 @@
+package packet
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ExpertSeverity is the severity of an expert info entry. The values are
+// Wireshark's, so severities order from Comment (lowest) to Error.
+type ExpertSeverity int
+
+const (
+	SeverityComment ExpertSeverity = 0x00100000
+	SeverityChat    ExpertSeverity = 0x00200000
+	SeverityNote    ExpertSeverity = 0x00400000
+	SeverityWarning ExpertSeverity = 0x00600000
+	SeverityError   ExpertSeverity = 0x00800000
+)
+
+var severityNames = map[ExpertSeverity]string{
+	SeverityComment: "Comment",
+	SeverityChat:    "Chat",
+	SeverityNote:    "Note",
+	SeverityWarning: "Warning",
+	SeverityError:   "Error",
+}
+
+// String returns the severity's name as Wireshark shows it, e.g. "Warning".
+func (s ExpertSeverity) String() string {
+	if name, ok := severityNames[s]; ok {
+		return name
+	}
+	return "Unknown"
+}
+
+// expertGroups names Wireshark's expert groups by value.
+var expertGroups = map[int64]string{
+	0x01000000: "Checksum",
+	0x02000000: "Sequence",
+	0x03000000: "Response code",
+	0x04000000: "Request code",
+	0x05000000: "Undecoded",
+	0x06000000: "Reassemble",
+	0x07000000: "Malformed",
+	0x08000000: "Debug",
+	0x09000000: "Protocol",
+	0x0a000000: "Security",
+	0x0b000000: "Comments group",
+	0x0c000000: "Decryption",
+	0x0d000000: "Assumption",
+	0x0e000000: "Deprecated",
+	0x0f000000: "Receive",
+	0x10000000: "Interface",
+	0x11000000: "Dissector bug",
+}
+
+// ExpertInfo is one expert info entry tshark attached to a packet, e.g. a
+// TCP retransmission or a malformed-packet exception.
+type ExpertInfo struct {
+	Severity ExpertSeverity
+	Group    string // e.g. "Sequence", "Malformed"
+	Message  string
+	// Field is the expert field that raised the entry, e.g.
+	// "tcp.analysis.retransmission" or "_ws.malformed.expert".
+	Field string
+	// Layer is the protocol layer the entry belongs to. Entries tshark puts
+	// in a "_ws.*" pseudo-layer are attributed to the layer before it.
+	Layer string
+}
+
+// ExpertInfo returns the expert info entries of the packet, in layer order.
+// They are read from the "_ws.expert" subtrees of JSON and PDML output; EK
+// output does not carry them.
+func (p *Packet) ExpertInfo() []ExpertInfo {
+	var infos []ExpertInfo
+	owner := ""
+	for i := range p.Layers {
+		layer := p.GetLayerByIndex(i)
+		if !strings.HasPrefix(layer.Name, "_ws.") || owner == "" {
+			owner = layer.Name
+		}
+		infos = appendExpertInfo(infos, layer.Fields, owner)
+	}
+	return infos
+}
+
+// appendExpertInfo collects the "_ws.expert" subtrees nested in fields. The
+// layer itself is searched too, since tshark may emit "_ws.expert" as a
+// layer of its own.
+func appendExpertInfo(infos []ExpertInfo, fields map[string]interface{}, layer string) []ExpertInfo {
+	if _, ok := fields["_ws.expert.message"]; ok {
+		infos = append(infos, newExpertInfo(fields, layer))
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		switch v := fields[k].(type) {
+		case map[string]interface{}:
+			infos = appendExpertInfo(infos, v, layer)
+		case []interface{}:
+			for _, item := range v {
+				if sub, ok := item.(map[string]interface{}); ok {
+					infos = appendExpertInfo(infos, sub, layer)
+				}
+			}
+		}
+	}
+	return infos
+}
+
+func newExpertInfo(expert map[string]interface{}, layer string) ExpertInfo {
+	info := ExpertInfo{
+		Severity: parseSeverity(coerceFieldString(expert["_ws.expert.severity"])),
+		Group:    parseExpertGroup(coerceFieldString(expert["_ws.expert.group"])),
+		Message:  coerceFieldString(expert["_ws.expert.message"]),
+		Layer:    layer,
+	}
+	var fields []string
+	for k := range expert {
+		if !strings.HasPrefix(k, "_ws.expert") && k != "_value" {
+			fields = append(fields, k)
+		}
+	}
+	if len(fields) > 0 {
+		sort.Strings(fields)
+		info.Field = fields[0]
+	}
+	return info
+}
+
+// parseSeverity accepts the numeric value tshark emits or a severity name.
+func parseSeverity(s string) ExpertSeverity {
+	if n, err := strconv.ParseInt(s, 0, 64); err == nil {
+		return ExpertSeverity(n)
+	}
+	switch strings.ToLower(s) {
+	case "warn":
+		return SeverityWarning
+	case "comments":
+		return SeverityComment
+	}
+	for sev, name := range severityNames {
+		if strings.EqualFold(s, name) {
+			return sev
+		}
+	}
+	return 0
+}
+
+// parseExpertGroup names a numeric group; names are kept as they are.
+func parseExpertGroup(s string) string {
+	if n, err := strconv.ParseInt(s, 0, 64); err == nil {
+		if name, ok := expertGroups[n]; ok {
+			return name
+		}
+	}
+	return s
+}
+
+// IsMalformed reports whether tshark hit an exception dissecting the packet,
+// shown by Wireshark as "[Malformed Packet]" and matched by the _ws.malformed
+// display filter. The marker is usually a layer of its own, but is nested in
+// the enclosing protocol when the exception occurred inside it.
+func (p *Packet) IsMalformed() bool {
+	for i := range p.Layers {
+		if p.Layers[i].Name == "_ws.malformed" {
+			return true
+		}
+	}
+	for i := range p.Layers {
+		if hasNestedField(p.GetLayerByIndex(i).Fields, "_ws.malformed") {
+			return true
+		}
+	}
+	return false
+}
+
+// hasNestedField reports whether name occurs in fields or a subtree of it.
+func hasNestedField(fields map[string]interface{}, name string) bool {
+	if _, ok := fields[name]; ok {
+		return true
+	}
+	for _, v := range fields {
+		switch x := v.(type) {
+		case map[string]interface{}:
+			if hasNestedField(x, name) {
+				return true
+			}
+		case []interface{}:
+			for _, item := range x {
+				if sub, ok := item.(map[string]interface{}); ok && hasNestedField(sub, name) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package packet
+
+import "testing"
+
+func expertPacket() *Packet {
+	return &Packet{Layers: []Layer{
+		{Name: "ip", Fields: map[string]interface{}{"ip.src": "10.0.0.1"}},
+		{Name: "tcp", Fields: map[string]interface{}{
+			"tcp.srcport": "80",
+			"tcp.analysis": map[string]interface{}{
+				"tcp.analysis.flags_tree": map[string]interface{}{
+					"_ws.expert": map[string]interface{}{
+						"tcp.analysis.retransmission": "",
+						"_ws.expert.message":          "This frame is a (suspected) retransmission",
+						"_ws.expert.severity":         "6291456",
+						"_ws.expert.group":            "33554432",
+					},
+				},
+			},
+		}},
+		{Name: "dns", Fields: map[string]interface{}{"dns.id": "0x1234"}},
+		{Name: "_ws.malformed", Fields: map[string]interface{}{
+			"_ws.expert": map[string]interface{}{
+				"_ws.malformed.expert": "",
+				"_ws.expert.message":   "Malformed Packet (Exception occurred)",
+				"_ws.expert.severity":  "8388608",
+				"_ws.expert.group":     "117440512",
+			},
+		}},
+	}}
+}
+
+func TestExpertInfo(t *testing.T) {
+	infos := expertPacket().ExpertInfo()
+	if len(infos) != 2 {
+		t.Fatalf("got %d entries, want 2: %+v", len(infos), infos)
+	}
+	want := []ExpertInfo{
+		{Severity: SeverityWarning, Group: "Sequence", Message: "This frame is a (suspected) retransmission",
+			Field: "tcp.analysis.retransmission", Layer: "tcp"},
+		{Severity: SeverityError, Group: "Malformed", Message: "Malformed Packet (Exception occurred)",
+			Field: "_ws.malformed.expert", Layer: "dns"},
+	}
+	for i := range want {
+		if infos[i] != want[i] {
+			t.Errorf("entry %d = %+v, want %+v", i, infos[i], want[i])
+		}
+	}
+	if infos[0].Severity.String() != "Warning" || !(infos[1].Severity > infos[0].Severity) {
+		t.Errorf("severities = %s, %s", infos[0].Severity, infos[1].Severity)
+	}
+}
+
+func TestExpertInfoRepeatedAndNamed(t *testing.T) {
+	// --no-duplicate-keys turns repeated subtrees into arrays, and PDML
+	// keeps the subtree's own value under "_value".
+	p := &Packet{Layers: []Layer{{Name: "http", Fields: map[string]interface{}{
+		"_ws.expert": []interface{}{
+			map[string]interface{}{"_value": "Expert Info", "http.chat": "",
+				"_ws.expert.message": "GET / HTTP/1.1\\r\\n", "_ws.expert.severity": "Chat", "_ws.expert.group": "Sequence"},
+			map[string]interface{}{"http.bad_header_name": "",
+				"_ws.expert.message": "Illegal characters", "_ws.expert.severity": "0x00400000", "_ws.expert.group": "0x09000000"},
+		},
+	}}}}
+	infos := p.ExpertInfo()
+	if len(infos) != 2 {
+		t.Fatalf("got %d entries, want 2", len(infos))
+	}
+	if infos[0].Severity != SeverityChat || infos[0].Group != "Sequence" || infos[0].Field != "http.chat" {
+		t.Errorf("entry 0 = %+v", infos[0])
+	}
+	if infos[1].Severity != SeverityNote || infos[1].Group != "Protocol" || infos[1].Field != "http.bad_header_name" {
+		t.Errorf("entry 1 = %+v", infos[1])
+	}
+}
+
+func TestIsMalformed(t *testing.T) {
+	p := expertPacket()
+	if !p.IsMalformed() {
+		t.Error("packet with a _ws.malformed layer is not malformed")
+	}
+	if p.HighestLayer() != "dns" {
+		t.Errorf("HighestLayer = %q, want dns", p.HighestLayer())
+	}
+
+	nested := &Packet{Layers: []Layer{{Name: "dns", Fields: map[string]interface{}{
+		"dns.qry.name": "x", "_ws.malformed": map[string]interface{}{"_ws.malformed.expert": ""},
+	}}}}
+	if !nested.IsMalformed() {
+		t.Error("packet with a nested _ws.malformed marker is not malformed")
+	}
+
+	p.Layers = p.Layers[:3]
+	if p.IsMalformed() {
+		t.Error("packet without a marker is malformed")
+	}
+}
@@ -0,0 +1,394 @@
+package packet
+
+import (
+	"fmt"
+	"strconv"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Interning limits: field names come from a fixed schema and are always
+// interned; short values ("0", "1", "0x00", ...) repeat across packets and are
+// interned until the table reaches maxInternedEntries.
+const (
+	maxInternedValueLen = 4
+	maxInternedEntries  = 1 << 16
+)
+
+// jsonScanner is a small JSON tokenizer over an in-memory document, used
+// instead of encoding/json to decode tshark output in a single pass. Objects
+// become map[string]interface{}, arrays []interface{}, numbers float64 — the
+// same shapes encoding/json produces — so decoded layers look identical.
+type jsonScanner struct {
+	data   []byte
+	pos    int
+	intern map[string]string // nil disables interning
+}
+
+func (s *jsonScanner) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("json: "+format+" at offset %d", append(args, s.pos)...)
+}
+
+func (s *jsonScanner) skipSpace() {
+	for s.pos < len(s.data) {
+		switch s.data[s.pos] {
+		case ' ', '\t', '\n', '\r':
+			s.pos++
+		default:
+			return
+		}
+	}
+}
+
+// peek returns the next non-space byte without consuming it, or 0 at the end.
+func (s *jsonScanner) peek() byte {
+	s.skipSpace()
+	if s.pos >= len(s.data) {
+		return 0
+	}
+	return s.data[s.pos]
+}
+
+func (s *jsonScanner) expect(c byte) error {
+	if s.peek() != c {
+		if s.pos >= len(s.data) {
+			return s.errorf("unexpected end of input, expected %q", c)
+		}
+		return s.errorf("unexpected %q, expected %q", s.data[s.pos], c)
+	}
+	s.pos++
+	return nil
+}
+
+// internBytes returns b as a string, reusing a previous copy when possible.
+// The map lookup with a converted []byte key does not allocate.
+func (s *jsonScanner) internBytes(b []byte, always bool) string {
+	if s.intern == nil || (!always && len(b) > maxInternedValueLen) {
+		return string(b)
+	}
+	if v, ok := s.intern[string(b)]; ok {
+		return v
+	}
+	v := string(b)
+	if len(s.intern) < maxInternedEntries {
+		s.intern[v] = v
+	}
+	return v
+}
+
+// readString reads a JSON string. Keys are always interned.
+func (s *jsonScanner) readString(key bool) (string, error) {
+	if err := s.expect('"'); err != nil {
+		return "", err
+	}
+	start := s.pos
+	for s.pos < len(s.data) {
+		c := s.data[s.pos]
+		if c == '"' {
+			str := s.internBytes(s.data[start:s.pos], key)
+			s.pos++
+			return str, nil
+		}
+		if c == '\\' {
+			return s.readEscapedString(start)
+		}
+		s.pos++
+	}
+	return "", s.errorf("unterminated string")
+}
+
+// readEscapedString finishes a string containing escape sequences, starting
+// over from its first byte.
+func (s *jsonScanner) readEscapedString(start int) (string, error) {
+	buf := make([]byte, 0, s.pos-start+16)
+	buf = append(buf, s.data[start:s.pos]...)
+	for s.pos < len(s.data) {
+		c := s.data[s.pos]
+		switch {
+		case c == '"':
+			s.pos++
+			return string(buf), nil
+		case c != '\\':
+			buf = append(buf, c)
+			s.pos++
+			continue
+		}
+		s.pos++
+		if s.pos >= len(s.data) {
+			break
+		}
+		e := s.data[s.pos]
+		s.pos++
+		switch e {
+		case '"', '\\', '/':
+			buf = append(buf, e)
+		case 'b':
+			buf = append(buf, '\b')
+		case 'f':
+			buf = append(buf, '\f')
+		case 'n':
+			buf = append(buf, '\n')
+		case 'r':
+			buf = append(buf, '\r')
+		case 't':
+			buf = append(buf, '\t')
+		case 'u':
+			r, err := s.readHex4()
+			if err != nil {
+				return "", err
+			}
+			if utf16.IsSurrogate(r) {
+				r2 := utf8.RuneError
+				if s.pos+1 < len(s.data) && s.data[s.pos] == '\\' && s.data[s.pos+1] == 'u' {
+					s.pos += 2
+					if r2, err = s.readHex4(); err != nil {
+						return "", err
+					}
+				}
+				r = utf16.DecodeRune(r, r2)
+			}
+			buf = utf8.AppendRune(buf, r)
+		default:
+			return "", s.errorf("invalid escape %q", e)
+		}
+	}
+	return "", s.errorf("unterminated string")
+}
+
+func (s *jsonScanner) readHex4() (rune, error) {
+	if s.pos+4 > len(s.data) {
+		return 0, s.errorf("truncated unicode escape")
+	}
+	var r rune
+	for _, c := range s.data[s.pos : s.pos+4] {
+		r <<= 4
+		switch {
+		case c >= '0' && c <= '9':
+			r |= rune(c - '0')
+		case c >= 'a' && c <= 'f':
+			r |= rune(c - 'a' + 10)
+		case c >= 'A' && c <= 'F':
+			r |= rune(c - 'A' + 10)
+		default:
+			return 0, s.errorf("invalid unicode escape")
+		}
+	}
+	s.pos += 4
+	return r, nil
+}
+
+// readNumber reads a JSON number as float64. Plain integers, the only numbers
+// tshark emits (in _raw position arrays), are converted without strconv.
+func (s *jsonScanner) readNumber() (float64, error) {
+	start := s.pos
+	simple := true
+	if s.pos < len(s.data) && s.data[s.pos] == '-' {
+		s.pos++
+	}
+	for s.pos < len(s.data) {
+		c := s.data[s.pos]
+		if c >= '0' && c <= '9' {
+			s.pos++
+			continue
+		}
+		if c == '.' || c == 'e' || c == 'E' || c == '+' || c == '-' {
+			simple = false
+			s.pos++
+			continue
+		}
+		break
+	}
+	digits := s.data[start:s.pos]
+	if len(digits) == 0 || (len(digits) == 1 && digits[0] == '-') {
+		return 0, s.errorf("invalid number")
+	}
+	if simple && len(digits) < 16 {
+		neg := digits[0] == '-'
+		if neg {
+			digits = digits[1:]
+		}
+		var n int64
+		for _, c := range digits {
+			n = n*10 + int64(c-'0')
+		}
+		if neg {
+			n = -n
+		}
+		return float64(n), nil
+	}
+	f, err := strconv.ParseFloat(string(digits), 64)
+	if err != nil {
+		return 0, s.errorf("invalid number %q", digits)
+	}
+	return f, nil
+}
+
+func (s *jsonScanner) readLiteral(lit string) error {
+	if len(s.data)-s.pos < len(lit) || string(s.data[s.pos:s.pos+len(lit)]) != lit {
+		return s.errorf("invalid literal")
+	}
+	s.pos += len(lit)
+	return nil
+}
+
+// readValue reads any JSON value.
+func (s *jsonScanner) readValue() (interface{}, error) {
+	switch c := s.peek(); {
+	case c == '{':
+		return s.readObject()
+	case c == '[':
+		return s.readArray()
+	case c == '"':
+		return s.readString(false)
+	case c == 't':
+		return true, s.readLiteral("true")
+	case c == 'f':
+		return false, s.readLiteral("false")
+	case c == 'n':
+		return nil, s.readLiteral("null")
+	case c == '-' || (c >= '0' && c <= '9'):
+		return s.readNumber()
+	case c == 0:
+		return nil, s.errorf("unexpected end of input")
+	default:
+		return nil, s.errorf("unexpected %q", c)
+	}
+}
+
+func (s *jsonScanner) readObject() (map[string]interface{}, error) {
+	obj := make(map[string]interface{})
+	err := s.eachMember(func(key string) error {
+		v, err := s.readValue()
+		if err != nil {
+			return err
+		}
+		obj[key] = v
+		return nil
+	})
+	return obj, err
+}
+
+func (s *jsonScanner) readArray() ([]interface{}, error) {
+	arr := make([]interface{}, 0, 4)
+	err := s.eachElement(func() error {
+		v, err := s.readValue()
+		if err != nil {
+			return err
+		}
+		arr = append(arr, v)
+		return nil
+	})
+	return arr, err
+}
+
+// eachMember iterates an object, calling fn with each key positioned at its
+// value; fn must consume the value.
+func (s *jsonScanner) eachMember(fn func(key string) error) error {
+	if err := s.expect('{'); err != nil {
+		return err
+	}
+	if s.peek() == '}' {
+		s.pos++
+		return nil
+	}
+	for {
+		key, err := s.readString(true)
+		if err != nil {
+			return err
+		}
+		if err := s.expect(':'); err != nil {
+			return err
+		}
+		if err := fn(key); err != nil {
+			return err
+		}
+		switch s.peek() {
+		case ',':
+			s.pos++
+		case '}':
+			s.pos++
+			return nil
+		default:
+			return s.errorf("expected ',' or '}' in object")
+		}
+	}
+}
+
+// eachElement iterates an array, calling fn positioned at each element; fn
+// must consume the element.
+func (s *jsonScanner) eachElement(fn func() error) error {
+	if err := s.expect('['); err != nil {
+		return err
+	}
+	if s.peek() == ']' {
+		s.pos++
+		return nil
+	}
+	for {
+		if err := fn(); err != nil {
+			return err
+		}
+		switch s.peek() {
+		case ',':
+			s.pos++
+		case ']':
+			s.pos++
+			return nil
+		default:
+			return s.errorf("expected ',' or ']' in array")
+		}
+	}
+}
+
+// skipValue consumes one value without materializing it and returns its
+// byte range.
+func (s *jsonScanner) skipValue() (int, int, error) {
+	c := s.peek()
+	start := s.pos
+	switch {
+	case c == '{' || c == '[':
+		depth := 0
+		for s.pos < len(s.data) {
+			switch s.data[s.pos] {
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+				if depth == 0 {
+					s.pos++
+					return start, s.pos, nil
+				}
+			case '"':
+				if err := s.skipString(); err != nil {
+					return 0, 0, err
+				}
+				continue
+			}
+			s.pos++
+		}
+		return 0, 0, s.errorf("unexpected end of input")
+	case c == '"':
+		err := s.skipString()
+		return start, s.pos, err
+	default:
+		_, err := s.readValue()
+		return start, s.pos, err
+	}
+}
+
+// skipString consumes a string starting at its opening quote.
+func (s *jsonScanner) skipString() error {
+	s.pos++
+	for s.pos < len(s.data) {
+		switch s.data[s.pos] {
+		case '\\':
+			s.pos += 2
+			continue
+		case '"':
+			s.pos++
+			return nil
+		}
+		s.pos++
+	}
+	return s.errorf("unterminated string")
+}
@@ -1,7 +1,6 @@
 package packet
 
 import (
-	"bytes"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
@@ -9,6 +8,8 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/p-vbordei/GoShark/packet/consts"
 )
 
 // FieldOffset represents the position and size of a field in the raw packet data.
@@ -30,17 +31,20 @@ type Field struct {
 // Layer represents a generic protocol layer with dynamic fields.
 type Layer struct {
 	Name      string                  `json:"-"`       // The name of the layer (e.g., "eth", "ip")
-	Fields    map[string]interface{}  `json:",inline"` // All fields of the layer
+	Fields    map[string]interface{}  `json:",inline"` // All fields of the layer; nil until decoded with LazyLayers
 	Offsets   map[string]*FieldOffset `json:"-"`       // Field offsets for raw data access
 	Pos       int                     `json:"-"`       // Position of this layer in the packet (byte offset)
 	Len       int                     `json:"-"`       // Length of this layer in bytes
 	JSONLayer interface{}             `json:"-"`       // Concrete layers.JSONLayer representation
 	XMLLayer  interface{}             `json:"-"`       // Concrete layers.XMLLayer representation
 	EKLayer   interface{}             `json:"-"`       // Concrete layers.EKLayer representation
+
+	lazy *lazyLayer // Undecoded fields (Decoder with LazyLayers); nil once decoded
 }
 
 // GetField retrieves a field's value from the layer by its name.
 func (l *Layer) GetField(name string) interface{} {
+	l.ensureDecoded()
 	return l.Fields[name]
 }
 
@@ -48,6 +52,7 @@ func (l *Layer) GetField(name string) interface{} {
 // "srcport" on a "tcp" layer resolves "tcp.srcport"; a name that already
 // contains a "." is used verbatim. This mirrors pyshark's attribute access.
 func (l *Layer) Field(name string) interface{} {
+	l.ensureDecoded()
 	if v, ok := l.Fields[name]; ok {
 		return v
 	}
@@ -104,11 +109,13 @@ func (l *Layer) GetFieldInt(name string) (int64, error) {
 
 // GetFieldOffset retrieves the offset information for a field.
 func (l *Layer) GetFieldOffset(name string) *FieldOffset {
+	l.ensureDecoded()
 	return l.Offsets[name]
 }
 
 // FieldNames returns a slice of all field names in the layer.
 func (l *Layer) FieldNames() []string {
+	l.ensureDecoded()
 	names := make([]string, 0, len(l.Fields))
 	for name := range l.Fields {
 		names = append(names, name)
@@ -118,12 +125,14 @@ func (l *Layer) FieldNames() []string {
 
 // HasField checks if a field with the given name exists in the layer.
 func (l *Layer) HasField(name string) bool {
+	l.ensureDecoded()
 	_, ok := l.Fields[name]
 	return ok
 }
 
 // Get retrieves a field's value from the layer by its name, returning a defaultValue if not found.
 func (l *Layer) Get(name string, defaultValue interface{}) interface{} {
+	l.ensureDecoded()
 	if val, ok := l.Fields[name]; ok {
 		return val
 	}
@@ -184,24 +193,6 @@ func parseInt(v interface{}) (int, bool) {
 	return 0, false
 }
 
-func extractHexFromFrameRaw(raw json.RawMessage) (string, error) {
-	// Try array first
-	var arr []interface{}
-	if err := json.Unmarshal(raw, &arr); err == nil && len(arr) > 0 {
-		if hexStr, ok := arr[0].(string); ok {
-			return hexStr, nil
-		}
-	}
-	// Try object with "value"
-	var obj struct {
-		Value string `json:"value"`
-	}
-	if err := json.Unmarshal(raw, &obj); err == nil && obj.Value != "" {
-		return obj.Value, nil
-	}
-	return "", fmt.Errorf("failed to extract hex from frame_raw")
-}
-
 func extractOffsets(fields map[string]interface{}, offsets map[string]*FieldOffset) {
 	for k, v := range fields {
 		if strings.HasSuffix(k, "_raw") {
@@ -229,66 +220,6 @@ func extractOffsets(fields map[string]interface{}, offsets map[string]*FieldOffs
 	}
 }
 
-// orderedLayer is one entry from the _source.layers object, in document order.
-type orderedLayer struct {
-	name string
-	raw  json.RawMessage
-}
-
-// decodeOrderedLayers walks a JSON object preserving key order. When a key's
-// value is an array (tshark merges duplicate layer keys under --no-duplicate-keys),
-// each element becomes its own entry so GetMultipleLayers keeps working.
-func decodeOrderedLayers(raw json.RawMessage) ([]orderedLayer, error) {
-	dec := json.NewDecoder(bytes.NewReader(raw))
-	t, err := dec.Token()
-	if err != nil {
-		return nil, err
-	}
-	if d, ok := t.(json.Delim); !ok || d != '{' {
-		return nil, fmt.Errorf("layers: expected JSON object")
-	}
-
-	var out []orderedLayer
-	for dec.More() {
-		keyTok, err := dec.Token()
-		if err != nil {
-			return nil, err
-		}
-		key, _ := keyTok.(string)
-
-		var val json.RawMessage
-		if err := dec.Decode(&val); err != nil {
-			return nil, err
-		}
-
-		// Duplicate layer keys are merged by --no-duplicate-keys into an array
-		// of layer objects, which must be split back into separate layers.
-		// A "_raw" key instead carries a [hex, pos, size, ...] position array
-		// that must NOT be split — keep those as a single entry.
-		trimmed := bytes.TrimSpace(val)
-		if len(trimmed) > 0 && trimmed[0] == '[' && !strings.HasSuffix(key, "_raw") {
-			var arr []json.RawMessage
-			if err := json.Unmarshal(trimmed, &arr); err == nil && len(arr) > 0 {
-				allObjects := true
-				for _, el := range arr {
-					if e := bytes.TrimSpace(el); len(e) == 0 || e[0] != '{' {
-						allObjects = false
-						break
-					}
-				}
-				if allObjects {
-					for _, el := range arr {
-						out = append(out, orderedLayer{name: key, raw: el})
-					}
-					continue
-				}
-			}
-		}
-		out = append(out, orderedLayer{name: key, raw: val})
-	}
-	return out, nil
-}
-
 // coerceFieldString turns a tshark JSON field value into its string form.
 // Real tshark -T json emits plain strings; it also accepts a one-element array
 // or an object with a value/show key for robustness across output modes.
@@ -317,83 +248,8 @@ func coerceFieldString(v interface{}) string {
 // UnmarshalJSON custom unmarshaler for Packet. It parses real tshark -T json
 // output, preserving the document order of protocol layers.
 func (p *Packet) UnmarshalJSON(data []byte) error {
-	aux := struct {
-		Index  json.RawMessage `json:"_index"`
-		Source struct {
-			Layers json.RawMessage `json:"layers"`
-		} `json:"_source"`
-	}{}
-	if err := json.Unmarshal(data, &aux); err != nil {
-		return err
-	}
-
-	// _index is usually a string, occasionally an object.
-	var indexStr string
-	if err := json.Unmarshal(aux.Index, &indexStr); err == nil {
-		p.Index.ProtocolID = indexStr
-	} else {
-		_ = json.Unmarshal(aux.Index, &p.Index)
-	}
-
-	ordered, err := decodeOrderedLayers(aux.Source.Layers)
-	if err != nil {
-		return fmt.Errorf("failed to decode layers: %w", err)
-	}
-
-	// Index raw siblings (frame_raw, ip_raw, ...) by base name for offsets.
-	rawByBase := map[string]json.RawMessage{}
-	for _, ol := range ordered {
-		if strings.HasSuffix(ol.name, "_raw") {
-			rawByBase[strings.TrimSuffix(ol.name, "_raw")] = ol.raw
-		}
-	}
-	if fr, ok := rawByBase["frame"]; ok {
-		if hexStr, err := extractHexFromFrameRaw(fr); err == nil {
-			hexStr = strings.ReplaceAll(hexStr, ":", "")
-			if rawData, err := hex.DecodeString(hexStr); err == nil {
-				p.RawData = rawData
-			}
-		}
-	}
-
-	p.Layers = make([]Layer, 0, len(ordered))
-	for _, ol := range ordered {
-		if strings.HasSuffix(ol.name, "_raw") {
-			continue
-		}
-
-		layer := Layer{Name: ol.name, Offsets: make(map[string]*FieldOffset)}
-		if err := json.Unmarshal(ol.raw, &layer.Fields); err != nil {
-			return fmt.Errorf("failed to unmarshal %s layer: %w", ol.name, err)
-		}
-
-		// Layer byte position/length from the matching _raw sibling.
-		if rawBytes, ok := rawByBase[ol.name]; ok {
-			var rawArr []interface{}
-			if err := json.Unmarshal(rawBytes, &rawArr); err == nil && len(rawArr) >= 3 {
-				if pos, ok1 := parseInt(rawArr[1]); ok1 {
-					layer.Pos = pos
-				}
-				if length, ok2 := parseInt(rawArr[2]); ok2 {
-					layer.Len = length
-				}
-			}
-		}
-
-		extractOffsets(layer.Fields, layer.Offsets)
-
-		if ol.name == "frame" {
-			p.FrameNumber = coerceFieldString(layer.Fields["frame.number"])
-			p.FrameLen = coerceFieldString(layer.Fields["frame.len"])
-			p.FrameCapLen = coerceFieldString(layer.Fields["frame.cap_len"])
-			p.FrameTimeEpoch = coerceFieldString(layer.Fields["frame.time_epoch"])
-			p.FrameTime = coerceFieldString(layer.Fields["frame.time"])
-		}
-
-		p.Layers = append(p.Layers, layer)
-	}
-
-	return nil
+	*p = Packet{}
+	return decodePacket(&jsonScanner{data: data}, p, false)
 }
 
 // SniffTimestamp returns the raw capture timestamp string (frame.time_epoch).
@@ -433,6 +289,7 @@ func (p *Packet) GetLayer(name string) *Layer {
 	for i := range p.Layers {
 		// TShark layer names are typically lowercase
 		if p.Layers[i].Name == name {
+			p.Layers[i].ensureDecoded()
 			return &p.Layers[i]
 		}
 	}
@@ -481,6 +338,7 @@ func (p *Packet) PrettyPrint() string {
 // GetLayerByIndex retrieves a layer by its index.
 func (p *Packet) GetLayerByIndex(index int) *Layer {
 	if index >= 0 && index < len(p.Layers) {
+		p.Layers[index].ensureDecoded()
 		return &p.Layers[index]
 	}
 	return nil
@@ -494,33 +352,53 @@ func (p *Packet) HasLayer(name string) bool {
 // GetMultipleLayers retrieves all layers of a specific type (case-insensitive).
 func (p *Packet) GetMultipleLayers(name string) []Layer {
 	var matchingLayers []Layer
-	for _, layer := range p.Layers {
-		if layer.Name == name {
-			matchingLayers = append(matchingLayers, layer)
+	for i := range p.Layers {
+		if p.Layers[i].Name == name {
+			p.Layers[i].ensureDecoded()
+			matchingLayers = append(matchingLayers, p.Layers[i])
 		}
 	}
 	return matchingLayers
 }
 
-// HighestLayer returns the name of the highest (last) layer in the packet.
+// HighestLayer returns the name of the highest protocol layer in the packet.
+// Pseudo-layers such as "data" and tshark's "_ws.*" expert/malformed layers
+// are skipped; if the packet has nothing else, the last layer is returned.
 func (p *Packet) HighestLayer() string {
 	if len(p.Layers) == 0 {
 		return ""
 	}
+	registry := consts.Default()
+	for i := len(p.Layers) - 1; i >= 0; i-- {
+		if !registry.IsMeta(p.Layers[i].Name) {
+			return p.Layers[i].Name
+		}
+	}
 	return p.Layers[len(p.Layers)-1].Name
 }
 
-// TransportLayer returns the name of the transport layer (tcp, udp, sctp, dccp) if present.
+// TransportLayer returns the name of the first transport-layer protocol in the
+// packet (tcp, udp, sctp, dccp, or any protocol the protocol registry
+// classifies as transport), or "" if there is none.
 func (p *Packet) TransportLayer() string {
-	transportLayers := []string{"tcp", "udp", "sctp", "dccp"}
-	for _, layerName := range transportLayers {
-		if p.HasLayer(layerName) {
-			return layerName
-		}
+	if i := p.transportIndex(); i >= 0 {
+		return p.Layers[i].Name
 	}
 	return ""
 }
 
+// transportIndex returns the index of the first transport-role layer in
+// packet order, or -1.
+func (p *Packet) transportIndex() int {
+	registry := consts.Default()
+	for i := range p.Layers {
+		if registry.IsTransport(p.Layers[i].Name) {
+			return i
+		}
+	}
+	return -1
+}
+
 // GetRawPacket returns the raw packet data as bytes.
 // Returns nil if raw data is not available.
 func (p *Packet) GetRawPacket() []byte {
@@ -46,8 +46,8 @@ func TestPacketJSONLayerOrderAndFrame(t *testing.T) {
 	if p.FrameLen != "119" {
 		t.Errorf("FrameLen = %q, want %q", p.FrameLen, "119")
 	}
-	if p.HighestLayer() != "data" {
-		t.Errorf("HighestLayer = %q, want %q", p.HighestLayer(), "data")
+	if p.HighestLayer() != "tcp" {
+		t.Errorf("HighestLayer = %q, want %q", p.HighestLayer(), "tcp")
 	}
 	if p.TransportLayer() != "tcp" {
 		t.Errorf("TransportLayer = %q, want %q", p.TransportLayer(), "tcp")
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+
+	"github.com/p-vbordei/GoShark/packet/consts"
 )
 
 // SessionKey represents a unique identifier for a network session or conversation.
@@ -23,10 +25,10 @@ func (k SessionKey) String() string {
 // Normalized returns a normalized version of the SessionKey where source and destination
 // are ordered to ensure that the same session is identified regardless of direction.
 func (k SessionKey) Normalized() SessionKey {
-	// For TCP/UDP sessions, we want to normalize the key so that the "smaller" address
-	// is always the source. This ensures that the same session is identified regardless
-	// of packet direction.
-	if k.Protocol == "tcp" || k.Protocol == "udp" {
+	// For transport sessions (TCP, UDP, SCTP, ...), we want to normalize the key so
+	// that the "smaller" address is always the source. This ensures that the same
+	// session is identified regardless of packet direction.
+	if consts.Default().IsTransport(k.Protocol) {
 		// Compare IPs first
 		cmpIP := strings.Compare(k.SrcIP, k.DstIP)
 		if cmpIP > 0 {
@@ -226,42 +228,51 @@ func (t *SessionTracker) GetSessionCount() int {
 	return len(t.Sessions)
 }
 
-// ExtractSessionKey extracts a session key from a packet.
+// ExtractSessionKey extracts a session key from a packet. The protocol is the
+// packet's transport layer as classified by the protocol registry, the
+// addresses come from the innermost network layer carrying src/dst fields
+// below it (so tunnelled traffic is keyed by its inner addresses), and the
+// ports from the transport layer's "<proto>.srcport"/"<proto>.dstport" fields.
 func ExtractSessionKey(packet *Packet) (SessionKey, error) {
 	// Initialize empty key
 	key := SessionKey{}
 
 	// Extract transport protocol
-	transportLayer := packet.TransportLayer()
-	if transportLayer == "" {
+	transportIdx := packet.transportIndex()
+	if transportIdx < 0 {
 		// If no transport layer, try to use the highest layer as the protocol
 		key.Protocol = strings.ToLower(packet.HighestLayer())
 	} else {
-		key.Protocol = transportLayer
+		key.Protocol = packet.Layers[transportIdx].Name
 	}
 
-	// Extract IP addresses
-	ipLayer := packet.GetLayer("ip")
-	if ipLayer == nil {
-		// Try IPv6
-		ipLayer = packet.GetLayer("ipv6")
+	// Extract IP addresses from the last network layer before the transport
+	// layer (or anywhere in the packet if there is no transport layer)
+	limit := len(packet.Layers)
+	if transportIdx >= 0 {
+		limit = transportIdx
 	}
-
-	if ipLayer == nil {
-		return key, fmt.Errorf("no IP layer found in packet")
-	}
-
-	// Extract source and destination IP addresses
+	registry := consts.Default()
 	var srcIP, dstIP interface{}
-	if ipLayer.Name == "ip" {
-		srcIP = ipLayer.GetField("ip.src")
-		dstIP = ipLayer.GetField("ip.dst")
-	} else {
-		// IPv6
-		srcIP = ipLayer.GetField("ipv6.src")
-		dstIP = ipLayer.GetField("ipv6.dst")
+	found := false
+	for i := limit - 1; i >= 0; i-- {
+		layer := &packet.Layers[i]
+		if !registry.IsNetwork(layer.Name) {
+			continue
+		}
+		src := layer.GetField(layer.Name + ".src")
+		dst := layer.GetField(layer.Name + ".dst")
+		if src == nil && dst == nil {
+			continue
+		}
+		srcIP, dstIP = src, dst
+		found = true
+		break
 	}
 
+	if !found {
+		return key, fmt.Errorf("no IP layer found in packet")
+	}
 	if srcIP == nil || dstIP == nil {
 		return key, fmt.Errorf("missing IP address information")
 	}
@@ -270,31 +281,15 @@ func ExtractSessionKey(packet *Packet) (SessionKey, error) {
 	key.DstIP = fmt.Sprintf("%v", dstIP)
 
 	// Extract port information if available
-	tcpLayer := packet.GetLayer("tcp")
-	udpLayer := packet.GetLayer("udp")
-
-	if tcpLayer != nil {
-		// Extract TCP ports
-		srcPort := tcpLayer.GetField("tcp.srcport")
-		dstPort := tcpLayer.GetField("tcp.dstport")
-
-		if srcPort != nil && dstPort != nil {
-			key.SrcPort = fmt.Sprintf("%v", srcPort)
-			key.DstPort = fmt.Sprintf("%v", dstPort)
-		}
-	} else if udpLayer != nil {
-		// Extract UDP ports
-		srcPort := udpLayer.GetField("udp.srcport")
-		dstPort := udpLayer.GetField("udp.dstport")
+	if transportIdx >= 0 {
+		layer := &packet.Layers[transportIdx]
+		srcPort := layer.GetField(layer.Name + ".srcport")
+		dstPort := layer.GetField(layer.Name + ".dstport")
 
 		if srcPort != nil && dstPort != nil {
 			key.SrcPort = fmt.Sprintf("%v", srcPort)
 			key.DstPort = fmt.Sprintf("%v", dstPort)
 		}
-	} else {
-		// No port information available, use empty strings
-		key.SrcPort = ""
-		key.DstPort = ""
 	}
 
 	return key, nil
@@ -144,3 +144,27 @@ func TestSessionTracker(t *testing.T) {
 		t.Errorf("Session count should be 1 after adding a packet, got %d", tracker.GetSessionCount())
 	}
 }
+
+// TestExtractSessionKeyRegistry verifies session keys use the registry's
+// transport classification (not just tcp/udp) and the innermost network layer.
+func TestExtractSessionKeyRegistry(t *testing.T) {
+	pkt := &Packet{Layers: []Layer{
+		{Name: "ip", Fields: map[string]interface{}{"ip.src": "10.0.0.1", "ip.dst": "10.0.0.2"}},
+		{Name: "gre", Fields: map[string]interface{}{}},
+		{Name: "ip", Fields: map[string]interface{}{"ip.src": "192.168.0.2", "ip.dst": "192.168.0.1"}},
+		{Name: "sctp", Fields: map[string]interface{}{"sctp.srcport": "3868", "sctp.dstport": "5000"}},
+		{Name: "diameter", Fields: map[string]interface{}{}},
+	}}
+
+	key, err := ExtractSessionKey(pkt)
+	if err != nil {
+		t.Fatalf("ExtractSessionKey: %v", err)
+	}
+	want := SessionKey{Protocol: "sctp", SrcIP: "192.168.0.2", DstIP: "192.168.0.1", SrcPort: "3868", DstPort: "5000"}
+	if key != want {
+		t.Errorf("key = %+v, want %+v", key, want)
+	}
+	if norm := key.Normalized(); norm.SrcIP != "192.168.0.1" || norm.SrcPort != "5000" {
+		t.Errorf("Normalized = %+v, want swapped endpoints", norm)
+	}
+}
@@ -0,0 +1,238 @@
+package pcapfile
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// optComment is the opt_comment option code, valid in every pcapng block.
+const optComment = 1
+
+// AnnotateFile writes a copy of the capture file at src to dst with
+// comments added to frames; see Annotate. dst is removed if writing fails.
+func AnnotateFile(src, dst string, comments map[int][]string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if err := Annotate(out, in, comments); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(dst)
+		return err
+	}
+	return nil
+}
+
+// Annotate copies the pcap or pcapng stream src to dst as pcapng, adding
+// comments (opt_comment options) to frames. comments maps tshark frame
+// numbers, starting at 1, to the comments to add; they follow any comments
+// the frame already has. All other blocks and options are copied unchanged.
+//
+// pcap input is converted to pcapng with a single interface. A Simple Packet
+// Block has no room for options, so one that is annotated is rewritten as an
+// Enhanced Packet Block on interface 0 with a zero timestamp, as the original
+// carried none. An error is returned if a frame number is beyond the end
+// of the capture.
+func Annotate(dst io.Writer, src io.Reader, comments map[int][]string) error {
+	for n, cs := range comments {
+		if n < 1 {
+			return fmt.Errorf("pcapfile: invalid frame number %d", n)
+		}
+		for _, c := range cs {
+			if len(c) > 0xffff {
+				return fmt.Errorf("pcapfile: comment on frame %d exceeds 65535 bytes", n)
+			}
+		}
+	}
+	rd, err := NewReader(src)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriterSize(dst, 64*1024)
+	frame := 0
+	for {
+		rec, err := rd.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		var block []byte
+		switch {
+		case rd.Format() == FormatPcap && !rec.IsFrame:
+			block = pcapToPcapNGHeader(rd.order, rec.Data)
+		case rd.Format() == FormatPcap:
+			frame++
+			block = pcapRecordToEPB(rd.order, rd.units, rec.Data, comments[frame])
+		case rec.IsFrame:
+			frame++
+			block = rec.Data
+			if len(comments[frame]) > 0 {
+				if block, err = annotateBlock(rd.order, rec, comments[frame]); err != nil {
+					return fmt.Errorf("pcapfile: frame %d: %v", frame, err)
+				}
+			}
+		default:
+			block = rec.Data
+		}
+		if _, err := w.Write(block); err != nil {
+			return err
+		}
+	}
+	if missing := missingFrames(comments, frame); len(missing) > 0 {
+		return fmt.Errorf("pcapfile: cannot annotate frame %d; the capture has %d frames", missing[0], frame)
+	}
+	return w.Flush()
+}
+
+// missingFrames returns the annotated frame numbers above count, in order.
+func missingFrames(comments map[int][]string, count int) []int {
+	var missing []int
+	for n, c := range comments {
+		if n > count && len(c) > 0 {
+			missing = append(missing, n)
+		}
+	}
+	sort.Ints(missing)
+	return missing
+}
+
+// annotateBlock returns a copy of a pcapng packet block with comments added
+// after its existing options.
+func annotateBlock(order binary.ByteOrder, rec *Record, comments []string) ([]byte, error) {
+	data := rec.Data
+	typ := order.Uint32(data[0:4])
+	if typ == blockSPB {
+		head := make([]byte, 20)
+		order.PutUint32(head[12:16], uint32(rec.Frame.CapLen))
+		order.PutUint32(head[16:20], uint32(rec.Frame.OrigLen))
+		return packetBlock(order, blockEPB, head, data[12:12+rec.Frame.CapLen], nil, comments), nil
+	}
+
+	// Enhanced and obsolete Packet Blocks share their layout: a 20-byte
+	// header after the block type and length, the padded packet data, then
+	// options.
+	capLen := rec.Frame.CapLen
+	optStart := 28 + pad4(capLen)
+	if optStart > len(data)-4 {
+		return nil, fmt.Errorf("packet data overruns its block")
+	}
+	opts, err := stripEndOfOpt(order, data[optStart:len(data)-4])
+	if err != nil {
+		return nil, err
+	}
+	return packetBlock(order, typ, data[8:28], data[28:28+capLen], opts, comments), nil
+}
+
+// stripEndOfOpt returns the options before opt_endofopt.
+func stripEndOfOpt(order binary.ByteOrder, opts []byte) ([]byte, error) {
+	pos := 0
+	for pos+4 <= len(opts) {
+		code, n := order.Uint16(opts[pos:pos+2]), int(order.Uint16(opts[pos+2:pos+4]))
+		if code == optEndOfOpt {
+			break
+		}
+		if pos+4+n > len(opts) {
+			return nil, fmt.Errorf("malformed option %d", code)
+		}
+		pos += 4 + pad4(n)
+	}
+	return opts[:min(pos, len(opts))], nil
+}
+
+// packetBlock encodes a packet block from its fixed header (the 20 bytes
+// after the block type and length), packet data, existing options without
+// opt_endofopt, and comments to append.
+func packetBlock(order binary.ByteOrder, typ uint32, head, packet, opts []byte, comments []string) []byte {
+	size := 8 + len(head) + pad4(len(packet)) + len(opts) + 4 + 4
+	for _, c := range comments {
+		size += 4 + pad4(len(c))
+	}
+	// Both byte orders a file can use implement AppendByteOrder.
+	app := order.(binary.AppendByteOrder)
+	b := make([]byte, 0, size)
+	b = app.AppendUint32(b, typ)
+	b = app.AppendUint32(b, uint32(size))
+	b = append(b, head...)
+	b = appendPadded(b, packet)
+	b = append(b, opts...)
+	for _, c := range comments {
+		b = app.AppendUint16(b, optComment)
+		b = app.AppendUint16(b, uint16(len(c)))
+		b = appendPadded(b, []byte(c))
+	}
+	b = append(b, 0, 0, 0, 0) // opt_endofopt
+	return app.AppendUint32(b, uint32(size))
+}
+
+// pcapToPcapNGHeader converts a pcap file header to a Section Header Block
+// and an Interface Description Block with the same link type, snapshot
+// length and timestamp resolution.
+func pcapToPcapNGHeader(order binary.ByteOrder, header []byte) []byte {
+	le := binary.LittleEndian
+	shb := le.AppendUint32(nil, byteOrderMagic)
+	shb = le.AppendUint16(shb, 1) // version 1.0
+	shb = le.AppendUint16(shb, 0)
+	shb = le.AppendUint64(shb, ^uint64(0)) // section length unknown
+
+	idb := le.AppendUint16(nil, uint16(order.Uint32(header[20:24])))
+	idb = append(idb, 0, 0)
+	idb = le.AppendUint32(idb, order.Uint32(header[16:20]))
+	if order.Uint32(header[0:4]) == magicNanos {
+		idb = le.AppendUint16(idb, optTSResol)
+		idb = le.AppendUint16(idb, 1)
+		idb = append(idb, 9, 0, 0, 0)
+	}
+	idb = append(idb, 0, 0, 0, 0) // opt_endofopt
+
+	return append(simpleBlock(blockSHB, shb), simpleBlock(blockIDB, idb)...)
+}
+
+// pcapRecordToEPB converts a pcap record to a little-endian Enhanced Packet
+// Block on interface 0.
+func pcapRecordToEPB(order binary.ByteOrder, units tsUnits, record []byte, comments []string) []byte {
+	le := binary.LittleEndian
+	ts := uint64(order.Uint32(record[0:4]))*units.perSecond + uint64(order.Uint32(record[4:8]))
+	head := le.AppendUint32(nil, 0)
+	head = le.AppendUint32(head, uint32(ts>>32))
+	head = le.AppendUint32(head, uint32(ts))
+	head = le.AppendUint32(head, order.Uint32(record[8:12]))
+	head = le.AppendUint32(head, order.Uint32(record[12:16]))
+	return packetBlock(le, blockEPB, head, record[pcapRecordHeaderLen:], nil, comments)
+}
+
+// simpleBlock encodes a little-endian block whose body is already padded.
+func simpleBlock(typ uint32, body []byte) []byte {
+	le := binary.LittleEndian
+	size := uint32(12 + len(body))
+	b := le.AppendUint32(nil, typ)
+	b = le.AppendUint32(b, size)
+	b = append(b, body...)
+	return le.AppendUint32(b, size)
+}
+
+func appendPadded(b, data []byte) []byte {
+	b = append(b, data...)
+	for i := len(data); i%4 != 0; i++ {
+		b = append(b, 0)
+	}
+	return b
+}
+
+func pad4(n int) int {
+	return (n + 3) &^ 3
+}
@@ -0,0 +1,189 @@
+package pcapfile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// frameComments reads the opt_comment options of each frame of a pcapng
+// stream.
+func frameComments(t *testing.T, data []byte) [][]string {
+	t.Helper()
+	rd, err := NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	var all [][]string
+	for {
+		rec, err := rd.Next()
+		if err == io.EOF {
+			return all
+		}
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		if !rec.IsFrame {
+			continue
+		}
+		var comments []string
+		if typ := rd.order.Uint32(rec.Data); typ != blockSPB {
+			opts := rec.Data[28+pad4(rec.Frame.CapLen) : len(rec.Data)-4]
+			for len(opts) >= 4 {
+				code, n := rd.order.Uint16(opts), int(rd.order.Uint16(opts[2:]))
+				if code == optEndOfOpt {
+					break
+				}
+				if code == optComment {
+					comments = append(comments, string(opts[4:4+n]))
+				}
+				opts = opts[4+pad4(n):]
+			}
+		}
+		all = append(all, comments)
+	}
+}
+
+// ngEPBWithComment is ngEPB with an opt_comment and opt_endofopt.
+func ngEPBWithComment(ts uint64, data []byte, comment string) []byte {
+	body := binary.LittleEndian.AppendUint32(nil, 0)
+	body = binary.LittleEndian.AppendUint32(body, uint32(ts>>32))
+	body = binary.LittleEndian.AppendUint32(body, uint32(ts))
+	body = binary.LittleEndian.AppendUint32(body, uint32(len(data)))
+	body = binary.LittleEndian.AppendUint32(body, uint32(len(data)))
+	body = appendPadded(body, data)
+	body = binary.LittleEndian.AppendUint16(body, optComment)
+	body = binary.LittleEndian.AppendUint16(body, uint16(len(comment)))
+	body = appendPadded(body, []byte(comment))
+	body = append(body, 0, 0, 0, 0)
+	return ngBlock(blockEPB, body)
+}
+
+func TestAnnotatePcapNG(t *testing.T) {
+	data := concat(
+		ngSHB(), ngIDB(-1, 0),
+		ngEPB(0, 1700000000_000001, []byte{1}),
+		ngEPBWithComment(1700000000_000002, []byte{2, 2}, "existing"),
+		ngSPB([]byte{3, 3, 3}),
+		ngEPB(0, 1700000000_000004, []byte{4, 4, 4, 4}),
+	)
+	var out bytes.Buffer
+	err := Annotate(&out, bytes.NewReader(data), map[int][]string{
+		1: {"first", "second"},
+		2: {"added"},
+		3: {"simple"},
+	})
+	if err != nil {
+		t.Fatalf("Annotate: %v", err)
+	}
+
+	want := [][]string{{"first", "second"}, {"existing", "added"}, {"simple"}, nil}
+	if got := frameComments(t, out.Bytes()); !reflect.DeepEqual(got, want) {
+		t.Errorf("comments = %q, want %q", got, want)
+	}
+	src, dst := mustIndex(t, data), mustIndex(t, out.Bytes())
+	if dst.Len() != src.Len() {
+		t.Fatalf("annotated capture has %d frames, want %d", dst.Len(), src.Len())
+	}
+	for i, f := range dst.Frames {
+		if f.CapLen != src.Frames[i].CapLen || f.OrigLen != src.Frames[i].OrigLen {
+			t.Errorf("frame %d = %+v, want %+v", i, f, src.Frames[i])
+		}
+		// The annotated Simple Packet Block gains a zero timestamp.
+		if i != 2 && !f.Time.Equal(src.Frames[i].Time) {
+			t.Errorf("frame %d time = %v, want %v", i, f.Time, src.Frames[i].Time)
+		}
+	}
+	if !dst.Frames[2].Time.Equal(time.Unix(0, 0)) {
+		t.Errorf("converted simple packet time = %v", dst.Frames[2].Time)
+	}
+	// Unannotated blocks are copied byte for byte.
+	if !bytes.HasPrefix(out.Bytes(), concat(ngSHB(), ngIDB(-1, 0))) ||
+		!bytes.HasSuffix(out.Bytes(), ngEPB(0, 1700000000_000004, []byte{4, 4, 4, 4})) {
+		t.Errorf("unannotated blocks were modified")
+	}
+}
+
+func TestAnnotatePcap(t *testing.T) {
+	base := time.Unix(1700000000, 0)
+	times := []time.Time{base, base.Add(1500 * time.Microsecond), base.Add(2 * time.Second)}
+	for _, tc := range []struct {
+		name  string
+		order binary.ByteOrder
+		nanos bool
+	}{
+		{"little-endian-micros", binary.LittleEndian, false},
+		{"big-endian-nanos", binary.BigEndian, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := Annotate(&out, bytes.NewReader(pcapBytes(tc.order, tc.nanos, times)), map[int][]string{2: {"look here"}}); err != nil {
+				t.Fatalf("Annotate: %v", err)
+			}
+			ix := mustIndex(t, out.Bytes())
+			if ix.Format != FormatPcapNG || ix.Len() != len(times) {
+				t.Fatalf("format %v, %d frames", ix.Format, ix.Len())
+			}
+			for i, f := range ix.Frames {
+				if !f.Time.Equal(times[i]) || f.CapLen != i+1 || f.OrigLen != i+101 {
+					t.Errorf("frame %d = %+v", i, f)
+				}
+			}
+			want := [][]string{nil, {"look here"}, nil}
+			if got := frameComments(t, out.Bytes()); !reflect.DeepEqual(got, want) {
+				t.Errorf("comments = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestAnnotateErrors(t *testing.T) {
+	data := concat(ngSHB(), ngIDB(-1, 0), ngEPB(0, 1, []byte{1}))
+	for _, tc := range []struct {
+		name     string
+		comments map[int][]string
+		want     string
+	}{
+		{"frame zero", map[int][]string{0: {"x"}}, "invalid frame number 0"},
+		{"past the end", map[int][]string{1: {"x"}, 3: {"y"}}, "cannot annotate frame 3"},
+		{"too long", map[int][]string{1: {strings.Repeat("x", 70000)}}, "exceeds 65535 bytes"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Annotate(io.Discard, bytes.NewReader(data), tc.comments)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("Annotate error = %v, want %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestAnnotateFile(t *testing.T) {
+	dir := t.TempDir()
+	dst := filepath.Join(dir, "annotated.pcapng")
+	if err := AnnotateFile("../test.pcap", dst, map[int][]string{1: {"reviewed"}}); err != nil {
+		t.Fatalf("AnnotateFile: %v", err)
+	}
+	data, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	comments := frameComments(t, data)
+	if len(comments) != 5 || !reflect.DeepEqual(comments[0], []string{"reviewed"}) {
+		t.Errorf("comments = %q", comments)
+	}
+
+	// A failed annotation leaves no output behind.
+	bad := filepath.Join(dir, "bad.pcapng")
+	if err := AnnotateFile("../test.pcap", bad, map[int][]string{99: {"x"}}); err == nil {
+		t.Fatal("expected an error for a missing frame")
+	}
+	if _, err := os.Stat(bad); !os.IsNotExist(err) {
+		t.Errorf("output left behind after failure: %v", err)
+	}
+}
@@ -0,0 +1,166 @@
+package pcapfile
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+)
+
+// maxBlockSize bounds a single record or block; anything larger is treated
+// as corruption rather than allocated.
+const maxBlockSize = 1 << 28
+
+// Frame locates one frame (a pcap record or pcapng packet block) in a file.
+type Frame struct {
+	Offset    int64     // File offset of the record or block
+	Size      int64     // Record or block size, headers included
+	Time      time.Time // Capture time; zero for pcapng Simple Packet Blocks
+	CapLen    int       // Captured length
+	OrigLen   int       // Original length on the wire
+	Interface int       // pcapng interface ID; 0 for pcap
+
+	section int // pcapng section the frame belongs to
+}
+
+// byteRange is a span of the file copied verbatim.
+type byteRange struct {
+	offset, size int64
+}
+
+// Index records where each frame of a capture file starts and when it was
+// captured, so frames can be extracted without reading the whole file.
+// Frame i of the index is tshark's frame number i+1.
+type Index struct {
+	Format Format
+	Frames []Frame
+
+	header   []byte        // pcap file header
+	sections [][]byteRange // pcapng: the non-packet blocks of each section
+	sorted   bool          // Frames are in non-decreasing time order
+}
+
+// BuildIndex scans the capture file at path once and indexes its frames.
+func BuildIndex(path string) (*Index, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return NewIndex(f)
+}
+
+// NewIndex scans a pcap or pcapng stream and indexes its frames. If the
+// stream ends inside a frame, the index of the complete frames is returned
+// together with an error wrapping io.ErrUnexpectedEOF.
+func NewIndex(r io.Reader) (*Index, error) {
+	rd, err := NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	ix := &Index{Format: rd.Format(), sorted: true}
+	for {
+		rec, err := rd.Next()
+		if err == io.EOF {
+			return ix, nil
+		}
+		if err != nil {
+			if ix.Format == FormatPcap && ix.header == nil {
+				return nil, err
+			}
+			return ix, err
+		}
+		switch {
+		case rec.IsFrame:
+			ix.add(rec.Frame)
+		case ix.Format == FormatPcap:
+			ix.header = append([]byte(nil), rec.Data...)
+		default:
+			if rec.Frame.section == len(ix.sections) {
+				ix.sections = append(ix.sections, nil)
+			}
+			b := byteRange{rec.Frame.Offset, rec.Frame.Size}
+			ix.sections[rec.Frame.section] = append(ix.sections[rec.Frame.section], b)
+		}
+	}
+}
+
+func (ix *Index) add(f Frame) {
+	if n := len(ix.Frames); n > 0 && f.Time.Before(ix.Frames[n-1].Time) {
+		ix.sorted = false
+	}
+	ix.Frames = append(ix.Frames, f)
+}
+
+// Len returns the number of frames.
+func (ix *Index) Len() int {
+	return len(ix.Frames)
+}
+
+// SeekTime returns the index of the first frame captured at or after t, or
+// Len() if there is none. Files whose timestamps go backwards are searched
+// linearly, returning the first such frame in file order.
+func (ix *Index) SeekTime(t time.Time) int {
+	if ix.sorted {
+		return sort.Search(len(ix.Frames), func(i int) bool {
+			return !ix.Frames[i].Time.Before(t)
+		})
+	}
+	for i, f := range ix.Frames {
+		if !f.Time.Before(t) {
+			return i
+		}
+	}
+	return len(ix.Frames)
+}
+
+// WriteFrames writes a capture file holding frames [from, to) of src, the
+// file the index was built from, in the same format. pcapng section headers,
+// interface descriptions and other non-packet blocks are repeated ahead of
+// the frames of each section they precede, so the output decodes like the
+// original.
+func (ix *Index) WriteFrames(dst io.Writer, src io.ReaderAt, from, to int) error {
+	if from < 0 || to > len(ix.Frames) || from > to {
+		return fmt.Errorf("pcapfile: frame range [%d, %d) out of bounds [0, %d)", from, to, len(ix.Frames))
+	}
+	if ix.Format == FormatPcap {
+		if _, err := dst.Write(ix.header); err != nil {
+			return err
+		}
+	}
+
+	// Adjacent frames are coalesced into one copy.
+	var pending byteRange
+	flush := func() error {
+		if pending.size == 0 {
+			return nil
+		}
+		_, err := io.Copy(dst, io.NewSectionReader(src, pending.offset, pending.size))
+		pending = byteRange{}
+		return err
+	}
+	section := -1
+	for _, f := range ix.Frames[from:to] {
+		if ix.Format == FormatPcapNG && f.section != section {
+			if err := flush(); err != nil {
+				return err
+			}
+			for _, b := range ix.sections[f.section] {
+				if _, err := io.Copy(dst, io.NewSectionReader(src, b.offset, b.size)); err != nil {
+					return err
+				}
+			}
+			section = f.section
+		}
+		if pending.size > 0 && pending.offset+pending.size == f.Offset {
+			pending.size += f.Size
+			continue
+		}
+		if err := flush(); err != nil {
+			return err
+		}
+		pending = byteRange{f.Offset, f.Size}
+	}
+	return flush()
+}
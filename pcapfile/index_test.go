@@ -0,0 +1,312 @@
+package pcapfile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"testing"
+	"time"
+)
+
+// pcapBytes builds a pcap file with one record per timestamp; each record's
+// payload is n bytes of value n.
+func pcapBytes(order binary.ByteOrder, nanos bool, times []time.Time) []byte {
+	var buf bytes.Buffer
+	magic := magicMicros
+	if nanos {
+		magic = magicNanos
+	}
+	hdr := make([]byte, pcapHeaderLen)
+	order.PutUint32(hdr[0:], magic)
+	order.PutUint16(hdr[4:], 2)
+	order.PutUint16(hdr[6:], 4)
+	order.PutUint32(hdr[16:], 65535)
+	order.PutUint32(hdr[20:], 1)
+	buf.Write(hdr)
+	for i, t := range times {
+		n := i + 1
+		rec := make([]byte, pcapRecordHeaderLen)
+		order.PutUint32(rec[0:], uint32(t.Unix()))
+		sub := uint32(t.Nanosecond() / 1000)
+		if nanos {
+			sub = uint32(t.Nanosecond())
+		}
+		order.PutUint32(rec[4:], sub)
+		order.PutUint32(rec[8:], uint32(n))
+		order.PutUint32(rec[12:], uint32(n+100))
+		buf.Write(rec)
+		buf.Write(bytes.Repeat([]byte{byte(n)}, n))
+	}
+	return buf.Bytes()
+}
+
+// ngBlock encodes a little-endian pcapng block, padding the body.
+func ngBlock(typ uint32, body []byte) []byte {
+	padded := append([]byte(nil), body...)
+	for len(padded)%4 != 0 {
+		padded = append(padded, 0)
+	}
+	size := uint32(12 + len(padded))
+	b := binary.LittleEndian.AppendUint32(nil, typ)
+	b = binary.LittleEndian.AppendUint32(b, size)
+	b = append(b, padded...)
+	return binary.LittleEndian.AppendUint32(b, size)
+}
+
+func ngSHB() []byte {
+	body := binary.LittleEndian.AppendUint32(nil, byteOrderMagic)
+	body = append(body, 1, 0, 0, 0)                           // version 1.0
+	body = binary.LittleEndian.AppendUint64(body, ^uint64(0)) // section length unknown
+	return ngBlock(blockSHB, body)
+}
+
+// ngIDB is an Ethernet interface; tsresol < 0 omits the if_tsresol option.
+func ngIDB(tsresol int, snapLen uint32) []byte {
+	body := binary.LittleEndian.AppendUint16(nil, 1)
+	body = append(body, 0, 0)
+	body = binary.LittleEndian.AppendUint32(body, snapLen)
+	if tsresol >= 0 {
+		body = binary.LittleEndian.AppendUint16(body, optTSResol)
+		body = binary.LittleEndian.AppendUint16(body, 1)
+		body = append(body, byte(tsresol), 0, 0, 0)
+	}
+	body = append(body, 0, 0, 0, 0) // opt_endofopt
+	return ngBlock(blockIDB, body)
+}
+
+func ngEPB(iface uint32, ts uint64, data []byte) []byte {
+	body := binary.LittleEndian.AppendUint32(nil, iface)
+	body = binary.LittleEndian.AppendUint32(body, uint32(ts>>32))
+	body = binary.LittleEndian.AppendUint32(body, uint32(ts))
+	body = binary.LittleEndian.AppendUint32(body, uint32(len(data)))
+	body = binary.LittleEndian.AppendUint32(body, uint32(len(data)))
+	return ngBlock(blockEPB, append(body, data...))
+}
+
+func ngSPB(data []byte) []byte {
+	body := binary.LittleEndian.AppendUint32(nil, uint32(len(data)))
+	return ngBlock(blockSPB, append(body, data...))
+}
+
+func concat(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+func mustIndex(t *testing.T, data []byte) *Index {
+	t.Helper()
+	ix, err := NewIndex(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("NewIndex: %v", err)
+	}
+	return ix
+}
+
+func TestIndexPcap(t *testing.T) {
+	base := time.Unix(1700000000, 0)
+	times := []time.Time{base, base.Add(1500 * time.Microsecond), base.Add(2 * time.Second), base.Add(3 * time.Second)}
+	for _, tc := range []struct {
+		name  string
+		order binary.ByteOrder
+		nanos bool
+	}{
+		{"little-endian-micros", binary.LittleEndian, false},
+		{"big-endian-nanos", binary.BigEndian, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			data := pcapBytes(tc.order, tc.nanos, times)
+			ix := mustIndex(t, data)
+			if ix.Format != FormatPcap || ix.Len() != len(times) {
+				t.Fatalf("format %v, %d frames", ix.Format, ix.Len())
+			}
+			for i, f := range ix.Frames {
+				if !f.Time.Equal(times[i]) || f.CapLen != i+1 || f.OrigLen != i+101 {
+					t.Errorf("frame %d = %+v", i, f)
+				}
+				payload := data[f.Offset+pcapRecordHeaderLen : f.Offset+f.Size]
+				if !bytes.Equal(payload, bytes.Repeat([]byte{byte(i + 1)}, i+1)) {
+					t.Errorf("frame %d payload = %x", i, payload)
+				}
+			}
+
+			var out bytes.Buffer
+			if err := ix.WriteFrames(&out, bytes.NewReader(data), 1, 3); err != nil {
+				t.Fatalf("WriteFrames: %v", err)
+			}
+			sub := mustIndex(t, out.Bytes())
+			if sub.Len() != 2 || !sub.Frames[0].Time.Equal(times[1]) || sub.Frames[1].CapLen != 3 {
+				t.Errorf("extracted frames = %+v", sub.Frames)
+			}
+		})
+	}
+}
+
+func TestIndexPcapNG(t *testing.T) {
+	data := concat(
+		ngSHB(), ngIDB(-1, 0), ngIDB(9, 0),
+		ngEPB(0, 1700000000_000001, []byte{1}), // microseconds (default)
+		ngEPB(1, 1700000001_000000002, []byte{2, 2}),
+		ngSPB([]byte{3, 3, 3}),
+		// A second section with a single interface, 2^-10 s resolution.
+		ngSHB(), ngIDB(0x80|10, 2),
+		ngEPB(0, (1700000002<<10)+512, []byte{4, 4, 4, 4}),
+	)
+	ix := mustIndex(t, data)
+	if ix.Format != FormatPcapNG || ix.Len() != 4 {
+		t.Fatalf("format %v, %d frames", ix.Format, ix.Len())
+	}
+	want := []struct {
+		time    time.Time
+		capLen  int
+		iface   int
+		section int
+	}{
+		{time.Unix(1700000000, 1000), 1, 0, 0},
+		{time.Unix(1700000001, 2), 2, 1, 0},
+		{time.Time{}, 3, 0, 0},
+		{time.Unix(1700000002, 500000000), 4, 0, 1},
+	}
+	for i, w := range want {
+		f := ix.Frames[i]
+		if !f.Time.Equal(w.time) || f.CapLen != w.capLen || f.Interface != w.iface || f.section != w.section {
+			t.Errorf("frame %d = %+v, want %+v", i, f, w)
+		}
+	}
+
+	// Extracting a frame of the second section repeats only its preamble.
+	var out bytes.Buffer
+	if err := ix.WriteFrames(&out, bytes.NewReader(data), 3, 4); err != nil {
+		t.Fatalf("WriteFrames: %v", err)
+	}
+	if wantOut := concat(ngSHB(), ngIDB(0x80|10, 2), ngEPB(0, (1700000002<<10)+512, []byte{4, 4, 4, 4})); !bytes.Equal(out.Bytes(), wantOut) {
+		t.Errorf("extracted %x\nwant %x", out.Bytes(), wantOut)
+	}
+
+	out.Reset()
+	if err := ix.WriteFrames(&out, bytes.NewReader(data), 1, 4); err != nil {
+		t.Fatalf("WriteFrames: %v", err)
+	}
+	sub := mustIndex(t, out.Bytes())
+	if sub.Len() != 3 || !sub.Frames[0].Time.Equal(want[1].time) || !sub.Frames[2].Time.Equal(want[3].time) {
+		t.Errorf("extracted frames = %+v", sub.Frames)
+	}
+}
+
+func TestIndexTestPcap(t *testing.T) {
+	ix, err := BuildIndex("../test.pcap")
+	if err != nil {
+		t.Fatalf("BuildIndex: %v", err)
+	}
+	if ix.Format != FormatPcapNG || ix.Len() != 5 {
+		t.Fatalf("format %v, %d frames; test.pcap is pcapng with 5 frames", ix.Format, ix.Len())
+	}
+	for i := 1; i < ix.Len(); i++ {
+		if ix.Frames[i].Time.Before(ix.Frames[i-1].Time) {
+			t.Errorf("frame %d goes back in time", i)
+		}
+	}
+	if got := ix.SeekTime(ix.Frames[2].Time); got != 2 {
+		t.Errorf("SeekTime(frame 2) = %d", got)
+	}
+
+	data, err := os.ReadFile("../test.pcap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := ix.WriteFrames(&out, bytes.NewReader(data), 0, ix.Len()); err != nil {
+		t.Fatalf("WriteFrames: %v", err)
+	}
+	if sub := mustIndex(t, out.Bytes()); sub.Len() != 5 || !sub.Frames[4].Time.Equal(ix.Frames[4].Time) {
+		t.Errorf("round trip = %+v", sub.Frames)
+	}
+}
+
+func TestSeekTime(t *testing.T) {
+	base := time.Unix(1700000000, 0)
+	sorted := mustIndex(t, pcapBytes(binary.LittleEndian, false,
+		[]time.Time{base, base.Add(time.Second), base.Add(time.Second), base.Add(3 * time.Second)}))
+	for _, tc := range []struct {
+		t    time.Time
+		want int
+	}{
+		{base.Add(-time.Hour), 0},
+		{base, 0},
+		{base.Add(time.Millisecond), 1},
+		{base.Add(time.Second), 1},
+		{base.Add(2 * time.Second), 3},
+		{base.Add(time.Hour), 4},
+	} {
+		if got := sorted.SeekTime(tc.t); got != tc.want {
+			t.Errorf("SeekTime(%v) = %d, want %d", tc.t.Sub(base), got, tc.want)
+		}
+	}
+
+	unsorted := mustIndex(t, pcapBytes(binary.LittleEndian, false,
+		[]time.Time{base.Add(5 * time.Second), base, base.Add(2 * time.Second)}))
+	if got := unsorted.SeekTime(base.Add(time.Second)); got != 0 {
+		t.Errorf("unsorted SeekTime = %d, want first frame in file order", got)
+	}
+}
+
+func TestIndexErrors(t *testing.T) {
+	if _, err := NewIndex(bytes.NewReader([]byte("not a capture file"))); err != ErrUnknownFormat {
+		t.Errorf("garbage: err = %v", err)
+	}
+	if _, err := NewIndex(bytes.NewReader(nil)); err != ErrUnknownFormat {
+		t.Errorf("empty: err = %v", err)
+	}
+
+	data := pcapBytes(binary.LittleEndian, false, []time.Time{time.Unix(1, 0), time.Unix(2, 0)})
+	ix, err := NewIndex(bytes.NewReader(data[:len(data)-1]))
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("truncated: err = %v", err)
+	}
+	if ix == nil || ix.Len() != 1 {
+		t.Errorf("truncated file should index its complete frames")
+	}
+
+	full := mustIndex(t, data)
+	if err := full.WriteFrames(io.Discard, bytes.NewReader(data), 1, 3); err == nil {
+		t.Error("WriteFrames accepted an out-of-range frame")
+	}
+}
+
+func TestReaderRecords(t *testing.T) {
+	data := concat(ngSHB(), ngIDB(-1, 0), ngEPB(0, 1, []byte{1}), ngEPB(0, 2, []byte{2}))
+	r, err := NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	if r.Format() != FormatPcapNG {
+		t.Fatalf("format = %v", r.Format())
+	}
+
+	// Dropping a frame but keeping every non-frame record stays valid.
+	var out bytes.Buffer
+	frames := 0
+	for {
+		rec, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		if rec.IsFrame {
+			frames++
+			if frames == 1 {
+				continue
+			}
+		}
+		out.Write(rec.Data)
+	}
+	if frames != 2 {
+		t.Errorf("read %d frames, want 2", frames)
+	}
+	ix := mustIndex(t, out.Bytes())
+	if ix.Len() != 1 || ix.Frames[0].CapLen != 1 || !ix.Frames[0].Time.Equal(time.Unix(0, 2000)) {
+		t.Errorf("rewritten file frames = %+v", ix.Frames)
+	}
+}
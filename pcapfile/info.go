@@ -0,0 +1,294 @@
+package pcapfile
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// pcapng Interface Description Block options read by FileInfo.
+const (
+	optIfName        = 2
+	optIfDescription = 3
+)
+
+// Info is a capture file's metadata, as reported by capinfos.
+type Info struct {
+	Path     string
+	Format   Format
+	FileSize int64
+	// Encapsulation names the link type of the file's interfaces, e.g.
+	// "Ethernet", or is "Per packet" when they differ.
+	Encapsulation string
+	// SnapLen is the snapshot length of the file's interfaces, or 0 when
+	// they differ or do not limit packet size.
+	SnapLen    int
+	Interfaces []Interface
+	Sections   int // pcapng sections; 1 for pcap
+
+	Packets int
+	// DataSize is the number of packet bytes captured, excluding headers.
+	DataSize int64
+	// FirstTime and LastTime are the earliest and latest packet timestamps;
+	// packets without one (pcapng Simple Packet Blocks) are not counted.
+	FirstTime time.Time
+	LastTime  time.Time
+	// TimeOrdered reports whether packet timestamps never go backwards.
+	TimeOrdered bool
+
+	SHA256 string // Hex-encoded digest of the whole file
+	SHA1   string
+}
+
+// Interface is a capture interface described by a file: the pcap file
+// header, or a pcapng Interface Description Block.
+type Interface struct {
+	LinkType      int
+	Encapsulation string // e.g. "Ethernet"
+	SnapLen       int
+	Name          string // pcapng if_name, if set
+	Description   string // pcapng if_description, if set
+	// TSResolution is the timestamp precision, e.g. time.Microsecond.
+	TSResolution time.Duration
+	Packets      int
+}
+
+// Duration returns the time between the first and last packet.
+func (i *Info) Duration() time.Duration {
+	return i.LastTime.Sub(i.FirstTime)
+}
+
+// DataByteRate returns the captured bytes per second, or 0 when the
+// duration is zero.
+func (i *Info) DataByteRate() float64 {
+	if d := i.Duration().Seconds(); d > 0 {
+		return float64(i.DataSize) / d
+	}
+	return 0
+}
+
+// DataBitRate returns the captured bits per second.
+func (i *Info) DataBitRate() float64 {
+	return 8 * i.DataByteRate()
+}
+
+// AveragePacketSize returns the mean captured length of a packet.
+func (i *Info) AveragePacketSize() float64 {
+	if i.Packets == 0 {
+		return 0
+	}
+	return float64(i.DataSize) / float64(i.Packets)
+}
+
+// AveragePacketRate returns the packets per second, or 0 when the duration
+// is zero.
+func (i *Info) AveragePacketRate() float64 {
+	if d := i.Duration().Seconds(); d > 0 {
+		return float64(i.Packets) / d
+	}
+	return 0
+}
+
+// linkTypeNames are the encapsulation names capinfos shows for common link
+// types.
+var linkTypeNames = map[int]string{
+	0:   "NULL/Loopback",
+	1:   "Ethernet",
+	6:   "Token Ring",
+	9:   "PPP",
+	101: "Raw IP",
+	105: "IEEE 802.11 Wireless LAN",
+	108: "OpenBSD loopback",
+	113: "Linux cooked-mode capture v1",
+	127: "IEEE 802.11 plus radiotap radio header",
+	195: "IEEE 802.15.4 Wireless PAN",
+	201: "Bluetooth H4 with linux header",
+	220: "USB packets with Linux header and padding",
+	228: "Raw IPv4",
+	229: "Raw IPv6",
+	252: "Wireshark Upper PDU export",
+	276: "Linux cooked-mode capture v2",
+}
+
+// LinkTypeName returns the encapsulation name of a pcap link type.
+func LinkTypeName(linkType int) string {
+	if name, ok := linkTypeNames[linkType]; ok {
+		return name
+	}
+	return fmt.Sprintf("LINKTYPE %d", linkType)
+}
+
+// FileInfo reads the capture file at path once and returns its metadata.
+// It returns ErrUnknownFormat for files that are neither pcap nor pcapng.
+// If the file ends inside a frame, the metadata of the complete frames is
+// returned together with an error wrapping io.ErrUnexpectedEOF.
+func FileInfo(path string) (*Info, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	// Every byte passes through the hashes once, whether the reader
+	// consumes it or it is drained below.
+	h256, h1 := sha256.New(), sha1.New()
+	tee := io.TeeReader(f, io.MultiWriter(h256, h1))
+	info, scanErr := scanInfo(tee)
+	if info == nil {
+		return nil, scanErr
+	}
+	if _, err := io.Copy(io.Discard, tee); err != nil {
+		return nil, err
+	}
+	info.Path = path
+	info.FileSize = st.Size()
+	info.SHA256 = hex.EncodeToString(h256.Sum(nil))
+	info.SHA1 = hex.EncodeToString(h1.Sum(nil))
+	return info, scanErr
+}
+
+// scanInfo gathers everything but the file's name, size and hashes.
+func scanInfo(r io.Reader) (*Info, error) {
+	rd, err := NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	info := &Info{Format: rd.Format(), TimeOrdered: true}
+	base := 0 // index in info.Interfaces of the current section's first interface
+	for {
+		rec, err := rd.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if len(info.Interfaces) == 0 {
+				return nil, err
+			}
+			info.summarize()
+			return info, err
+		}
+		switch {
+		case rec.IsFrame:
+			info.addFrame(rec.Frame, base)
+		case rd.Format() == FormatPcap:
+			info.Sections = 1
+			info.Interfaces = append(info.Interfaces, pcapInterface(rd.order, rec.Data))
+		default:
+			switch rd.order.Uint32(rec.Data[0:4]) {
+			case blockSHB:
+				info.Sections++
+				base = len(info.Interfaces)
+			case blockIDB:
+				info.Interfaces = append(info.Interfaces, idbInterface(rd.order, rec.Data))
+			}
+		}
+	}
+	info.summarize()
+	return info, nil
+}
+
+func (i *Info) addFrame(f Frame, base int) {
+	i.Packets++
+	i.DataSize += int64(f.CapLen)
+	if n := base + f.Interface; n < len(i.Interfaces) {
+		i.Interfaces[n].Packets++
+	}
+	if f.Time.IsZero() {
+		return
+	}
+	if !i.LastTime.IsZero() && f.Time.Before(i.LastTime) {
+		i.TimeOrdered = false
+	}
+	if i.FirstTime.IsZero() || f.Time.Before(i.FirstTime) {
+		i.FirstTime = f.Time
+	}
+	if f.Time.After(i.LastTime) {
+		i.LastTime = f.Time
+	}
+}
+
+// summarize sets the file-wide encapsulation and snapshot length from the
+// interfaces.
+func (i *Info) summarize() {
+	if len(i.Interfaces) == 0 {
+		return
+	}
+	first := i.Interfaces[0]
+	i.Encapsulation, i.SnapLen = first.Encapsulation, first.SnapLen
+	for _, iface := range i.Interfaces[1:] {
+		if iface.LinkType != first.LinkType {
+			i.Encapsulation = "Per packet"
+		}
+		if iface.SnapLen != first.SnapLen {
+			i.SnapLen = 0
+		}
+	}
+}
+
+// pcapInterface describes the interface of a pcap file header.
+func pcapInterface(order binary.ByteOrder, header []byte) Interface {
+	// The upper bits of the link type field carry FCS information.
+	linkType := int(order.Uint32(header[20:24]) & 0xffff)
+	iface := Interface{
+		LinkType:      linkType,
+		Encapsulation: LinkTypeName(linkType),
+		SnapLen:       int(order.Uint32(header[16:20])),
+		TSResolution:  time.Microsecond,
+	}
+	if order.Uint32(header[0:4]) == magicNanos {
+		iface.TSResolution = time.Nanosecond
+	}
+	return iface
+}
+
+// idbInterface describes the interface of an Interface Description Block.
+func idbInterface(order binary.ByteOrder, block []byte) Interface {
+	iface := Interface{TSResolution: time.Microsecond}
+	if len(block) < 20 {
+		return iface
+	}
+	iface.LinkType = int(order.Uint16(block[8:10]))
+	iface.Encapsulation = LinkTypeName(iface.LinkType)
+	iface.SnapLen = int(order.Uint32(block[12:16]))
+	units := parseIDB(order, block).units
+	if units.perSecond > 0 {
+		iface.TSResolution = max(time.Second/time.Duration(units.perSecond), 1)
+	}
+	opts := block[16 : len(block)-4]
+	for len(opts) >= 4 {
+		code, n := order.Uint16(opts[0:2]), int(order.Uint16(opts[2:4]))
+		if code == optEndOfOpt || 4+n > len(opts) {
+			break
+		}
+		switch code {
+		case optIfName:
+			iface.Name = string(opts[4 : 4+n])
+		case optIfDescription:
+			iface.Description = string(opts[4 : 4+n])
+		}
+		opts = opts[min(len(opts), 4+pad4(n)):]
+	}
+	return iface
+}
+
+// Probe reads the header of the stream r: the pcap file header, or the
+// first pcapng Section Header Block. It returns ErrUnknownFormat for input
+// that is neither pcap nor pcapng, including empty input, and an error for
+// a header that is truncated or corrupt.
+func Probe(r io.Reader) (Format, error) {
+	rd, err := NewReader(r)
+	if err != nil {
+		return FormatUnknown, err
+	}
+	_, err = rd.Next()
+	return rd.Format(), err
+}
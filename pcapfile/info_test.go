@@ -0,0 +1,151 @@
+package pcapfile
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeTemp(t *testing.T, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "capture")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFileInfoPcap(t *testing.T) {
+	base := time.Unix(1700000000, 0)
+	times := []time.Time{base, base.Add(2 * time.Second), base.Add(time.Second), base.Add(4 * time.Second)}
+	data := pcapBytes(binary.BigEndian, true, times)
+	path := writeTemp(t, data)
+
+	info, err := FileInfo(path)
+	if err != nil {
+		t.Fatalf("FileInfo: %v", err)
+	}
+	sum := sha256.Sum256(data)
+	if info.Format != FormatPcap || info.Path != path || info.FileSize != int64(len(data)) ||
+		info.SHA256 != hex.EncodeToString(sum[:]) || len(info.SHA1) != 40 {
+		t.Errorf("file = %+v", info)
+	}
+	if info.Encapsulation != "Ethernet" || info.SnapLen != 65535 || info.Sections != 1 {
+		t.Errorf("encapsulation %q, snaplen %d, sections %d", info.Encapsulation, info.SnapLen, info.Sections)
+	}
+	if len(info.Interfaces) != 1 || info.Interfaces[0].TSResolution != time.Nanosecond || info.Interfaces[0].Packets != 4 {
+		t.Errorf("interfaces = %+v", info.Interfaces)
+	}
+	if info.Packets != 4 || info.DataSize != 1+2+3+4 || info.TimeOrdered {
+		t.Errorf("packets %d, data %d, ordered %v", info.Packets, info.DataSize, info.TimeOrdered)
+	}
+	if !info.FirstTime.Equal(base) || info.Duration() != 4*time.Second {
+		t.Errorf("first %v, duration %v", info.FirstTime, info.Duration())
+	}
+	if info.DataByteRate() != 2.5 || info.DataBitRate() != 20 || info.AveragePacketSize() != 2.5 || info.AveragePacketRate() != 1 {
+		t.Errorf("rates %v B/s, %v b/s, %v B, %v pkt/s",
+			info.DataByteRate(), info.DataBitRate(), info.AveragePacketSize(), info.AveragePacketRate())
+	}
+}
+
+func TestFileInfoPcapNG(t *testing.T) {
+	// A named interface: if_name "eth0", if_description "uplink".
+	named := binary.LittleEndian.AppendUint16(nil, 113)
+	named = append(named, 0, 0)
+	named = binary.LittleEndian.AppendUint32(named, 262144)
+	named = append(named, 2, 0, 4, 0, 'e', 't', 'h', '0')
+	named = append(named, 3, 0, 6, 0, 'u', 'p', 'l', 'i', 'n', 'k', 0, 0)
+	named = append(named, 0, 0, 0, 0)
+
+	data := concat(
+		ngSHB(), ngIDB(-1, 65535), ngBlock(blockIDB, named),
+		ngEPB(0, 1700000000_000000, []byte{1}),
+		ngEPB(1, 1700000003_000000, []byte{2, 2}),
+		ngSPB([]byte{3, 3, 3}),
+		ngSHB(), ngIDB(9, 65535),
+		ngEPB(0, 1700000001_000000000, []byte{4, 4, 4, 4}),
+	)
+	info, err := FileInfo(writeTemp(t, data))
+	if err != nil {
+		t.Fatalf("FileInfo: %v", err)
+	}
+	if info.Format != FormatPcapNG || info.Sections != 2 || info.Encapsulation != "Per packet" || info.SnapLen != 0 {
+		t.Errorf("info = %+v", info)
+	}
+	want := []Interface{
+		{LinkType: 1, Encapsulation: "Ethernet", SnapLen: 65535, TSResolution: time.Microsecond, Packets: 2},
+		{LinkType: 113, Encapsulation: "Linux cooked-mode capture v1", SnapLen: 262144, Name: "eth0",
+			Description: "uplink", TSResolution: time.Microsecond, Packets: 1},
+		{LinkType: 1, Encapsulation: "Ethernet", SnapLen: 65535, TSResolution: time.Nanosecond, Packets: 1},
+	}
+	if len(info.Interfaces) != len(want) {
+		t.Fatalf("interfaces = %+v", info.Interfaces)
+	}
+	for i := range want {
+		if info.Interfaces[i] != want[i] {
+			t.Errorf("interface %d = %+v, want %+v", i, info.Interfaces[i], want[i])
+		}
+	}
+	if info.Packets != 4 || info.TimeOrdered || info.Duration() != 3*time.Second {
+		t.Errorf("packets %d, ordered %v, duration %v", info.Packets, info.TimeOrdered, info.Duration())
+	}
+}
+
+func TestFileInfoErrors(t *testing.T) {
+	if _, err := FileInfo(writeTemp(t, []byte("not a capture"))); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("text file: err = %v", err)
+	}
+	if _, err := FileInfo(filepath.Join(t.TempDir(), "missing")); !os.IsNotExist(err) {
+		t.Errorf("missing file: err = %v", err)
+	}
+
+	// A truncated file reports the complete frames and hashes every byte.
+	data := pcapBytes(binary.LittleEndian, false, []time.Time{time.Unix(1, 0), time.Unix(2, 0)})
+	data = data[:len(data)-1]
+	info, err := FileInfo(writeTemp(t, data))
+	if !errors.Is(err, io.ErrUnexpectedEOF) || info == nil || info.Packets != 1 {
+		t.Fatalf("truncated file: info %+v, err %v", info, err)
+	}
+	if sum := sha256.Sum256(data); info.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("truncated file hash = %s", info.SHA256)
+	}
+}
+
+func TestProbe(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		data   []byte
+		format Format
+		ok     bool
+	}{
+		{"pcap", pcapBytes(binary.LittleEndian, false, nil), FormatPcap, true},
+		{"pcapng", concat(ngSHB(), ngIDB(-1, 0)), FormatPcapNG, true},
+		{"truncated pcap", pcapBytes(binary.LittleEndian, false, nil)[:12], FormatPcap, false},
+		{"bad byte order", append(binary.LittleEndian.AppendUint32(nil, blockSHB), 28, 0, 0, 0, 1, 2, 3, 4), FormatPcapNG, false},
+		{"empty", nil, FormatUnknown, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			format, err := Probe(bytes.NewReader(tc.data))
+			if format != tc.format || (err == nil) != tc.ok {
+				t.Errorf("Probe = %v, %v", format, err)
+			}
+		})
+	}
+}
+
+func TestFileInfoTestPcap(t *testing.T) {
+	info, err := FileInfo("../test.pcap")
+	if err != nil {
+		t.Fatalf("FileInfo: %v", err)
+	}
+	if info.Format != FormatPcapNG || info.Packets != 5 || info.FirstTime.IsZero() || len(info.Interfaces) == 0 {
+		t.Errorf("info = %+v", info)
+	}
+}
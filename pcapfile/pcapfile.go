@@ -0,0 +1,109 @@
+// Package pcapfile reads the pcap and pcapng capture file formats far enough
+// to index frames and copy them out, without dissecting them. It lets
+// captures hand tshark just the frames a caller asks for, and can write a
+// copy of a capture with comments added to its frames.
+package pcapfile
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/bits"
+	"time"
+)
+
+// Format is a capture file format.
+type Format int
+
+const (
+	FormatUnknown Format = iota
+	FormatPcap
+	FormatPcapNG
+)
+
+func (f Format) String() string {
+	switch f {
+	case FormatPcap:
+		return "pcap"
+	case FormatPcapNG:
+		return "pcapng"
+	}
+	return "unknown"
+}
+
+// ErrUnknownFormat is returned for input that is neither pcap nor pcapng.
+var ErrUnknownFormat = errors.New("pcapfile: not a pcap or pcapng file")
+
+// pcap magic numbers, as read in the file's own byte order.
+const (
+	magicMicros uint32 = 0xa1b2c3d4
+	magicNanos  uint32 = 0xa1b23c4d
+
+	pcapHeaderLen       = 24
+	pcapRecordHeaderLen = 16
+)
+
+// pcapng block types and constants.
+const (
+	blockSHB uint32 = 0x0a0d0d0a // Section Header Block
+	blockIDB uint32 = 1          // Interface Description Block
+	blockPB  uint32 = 2          // Packet Block (obsolete)
+	blockSPB uint32 = 3          // Simple Packet Block
+	blockEPB uint32 = 6          // Enhanced Packet Block
+
+	byteOrderMagic uint32 = 0x1a2b3c4d
+
+	optEndOfOpt  = 0
+	optTSResol   = 9  // if_tsresol
+	optTSOffset  = 14 // if_tsoffset
+	defaultTSRes = 6  // microseconds
+)
+
+// byteOrderOf detects a file's byte order from a magic number read as
+// little-endian; ok is false when b matches neither order.
+func byteOrderOf(b []byte, magics ...uint32) (binary.ByteOrder, uint32, bool) {
+	le, be := binary.LittleEndian.Uint32(b), binary.BigEndian.Uint32(b)
+	for _, m := range magics {
+		switch m {
+		case le:
+			return binary.LittleEndian, m, true
+		case be:
+			return binary.BigEndian, m, true
+		}
+	}
+	return nil, 0, false
+}
+
+// tsUnits describes how a timestamp counter maps to time: the counter ticks
+// perSecond times a second, and offset seconds are added (if_tsoffset).
+type tsUnits struct {
+	perSecond uint64
+	offset    int64
+}
+
+// newTSUnits decodes an if_tsresol value: the low 7 bits are an exponent of
+// 10, or of 2 when the high bit is set.
+func newTSUnits(resol byte) tsUnits {
+	exp := uint(resol & 0x7f)
+	if resol&0x80 != 0 {
+		if exp > 63 {
+			exp = 63
+		}
+		return tsUnits{perSecond: 1 << exp}
+	}
+	if exp > 19 {
+		exp = 19
+	}
+	n := uint64(1)
+	for i := uint(0); i < exp; i++ {
+		n *= 10
+	}
+	return tsUnits{perSecond: n}
+}
+
+func (u tsUnits) time(ts uint64) time.Time {
+	sec := ts / u.perSecond
+	frac := ts % u.perSecond
+	hi, lo := bits.Mul64(frac, uint64(time.Second))
+	nsec, _ := bits.Div64(hi, lo, u.perSecond)
+	return time.Unix(int64(sec)+u.offset, int64(nsec))
+}
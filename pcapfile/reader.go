@@ -0,0 +1,242 @@
+package pcapfile
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Record is one unit of a capture file: a frame, or a non-frame part the
+// frames depend on (the pcap file header; pcapng section headers, interface
+// descriptions and other blocks). Writing every non-frame record plus any
+// subset of frames, in file order, yields a valid capture file.
+type Record struct {
+	IsFrame bool
+	// Frame locates the record. Offset, Size and the section are always
+	// set; the remaining fields only for frames.
+	Frame Frame
+	// Data holds the record's raw bytes. It is only valid until the next
+	// call to Reader.Next.
+	Data []byte
+}
+
+// Reader reads a pcap or pcapng stream record by record.
+type Reader struct {
+	br     *bufio.Reader
+	format Format
+	off    int64
+	buf    []byte
+
+	// pcap
+	order  binary.ByteOrder
+	units  tsUnits
+	header bool // file header returned
+
+	// pcapng
+	section int
+	ifaces  []pcapngInterface
+}
+
+// NewReader detects the format of r. It returns ErrUnknownFormat for input
+// that is neither pcap nor pcapng.
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReaderSize(r, 64*1024)
+	magic, err := br.Peek(4)
+	if err != nil {
+		if err == io.EOF {
+			return nil, ErrUnknownFormat
+		}
+		return nil, err
+	}
+	rd := &Reader{br: br, section: -1}
+	if order, m, ok := byteOrderOf(magic, magicMicros, magicNanos); ok {
+		rd.format, rd.order = FormatPcap, order
+		rd.units = tsUnits{perSecond: 1e6}
+		if m == magicNanos {
+			rd.units.perSecond = 1e9
+		}
+		return rd, nil
+	}
+	if binary.LittleEndian.Uint32(magic) == blockSHB {
+		rd.format = FormatPcapNG
+		return rd, nil
+	}
+	return nil, ErrUnknownFormat
+}
+
+// Format returns the stream's format.
+func (r *Reader) Format() Format {
+	return r.format
+}
+
+// Next returns the next record, or io.EOF at the end of the stream. A stream
+// ending inside a record yields an error wrapping io.ErrUnexpectedEOF.
+func (r *Reader) Next() (*Record, error) {
+	if r.format == FormatPcap {
+		return r.nextPcap()
+	}
+	return r.nextPcapNG()
+}
+
+func truncatedAt(off int64) error {
+	return fmt.Errorf("pcapfile: truncated frame at offset %d: %w", off, io.ErrUnexpectedEOF)
+}
+
+// read reads the next n bytes into the reusable buffer.
+func (r *Reader) read(n int) ([]byte, error) {
+	if cap(r.buf) < n {
+		r.buf = make([]byte, n)
+	}
+	b := r.buf[:n]
+	if _, err := io.ReadFull(r.br, b); err != nil {
+		return nil, truncatedAt(r.off)
+	}
+	return b, nil
+}
+
+func (r *Reader) nextPcap() (*Record, error) {
+	if !r.header {
+		data, err := r.read(pcapHeaderLen)
+		if err != nil {
+			return nil, err
+		}
+		r.header = true
+		r.off = pcapHeaderLen
+		return &Record{Frame: Frame{Size: pcapHeaderLen}, Data: data}, nil
+	}
+
+	rec, err := r.br.Peek(pcapRecordHeaderLen)
+	if len(rec) == 0 && err == io.EOF {
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, truncatedAt(r.off)
+	}
+	capLen := r.order.Uint32(rec[8:12])
+	if capLen > maxBlockSize {
+		return nil, fmt.Errorf("pcapfile: implausible record length %d at offset %d", capLen, r.off)
+	}
+	sec, sub := r.order.Uint32(rec[0:4]), r.order.Uint32(rec[4:8])
+	f := Frame{
+		Offset:  r.off,
+		Size:    pcapRecordHeaderLen + int64(capLen),
+		Time:    r.units.time(uint64(sec)*r.units.perSecond + uint64(sub)),
+		CapLen:  int(capLen),
+		OrigLen: int(r.order.Uint32(rec[12:16])),
+	}
+	data, err := r.read(int(f.Size))
+	if err != nil {
+		return nil, err
+	}
+	r.off += f.Size
+	return &Record{IsFrame: true, Frame: f, Data: data}, nil
+}
+
+func (r *Reader) nextPcapNG() (*Record, error) {
+	head, err := r.br.Peek(12)
+	if len(head) == 0 && err == io.EOF {
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, truncatedAt(r.off)
+	}
+	if binary.LittleEndian.Uint32(head) == blockSHB {
+		order, _, ok := byteOrderOf(head[8:12], byteOrderMagic)
+		if !ok {
+			return nil, fmt.Errorf("pcapfile: bad byte-order magic in section header at offset %d", r.off)
+		}
+		r.order = order
+		r.ifaces = r.ifaces[:0]
+		r.section++
+	}
+	typ, size := r.order.Uint32(head[0:4]), int64(r.order.Uint32(head[4:8]))
+	if size < 12 || size%4 != 0 || size > maxBlockSize {
+		return nil, fmt.Errorf("pcapfile: invalid block length %d at offset %d", size, r.off)
+	}
+	data, err := r.read(int(size))
+	if err != nil {
+		return nil, err
+	}
+
+	rec := &Record{Frame: Frame{Offset: r.off, Size: size, section: r.section}, Data: data}
+	switch typ {
+	case blockEPB, blockPB, blockSPB:
+		if err := pcapngFrame(r.order, typ, data, r.ifaces, &rec.Frame); err != nil {
+			return nil, fmt.Errorf("pcapfile: %v at offset %d", err, r.off)
+		}
+		rec.IsFrame = true
+	case blockIDB:
+		r.ifaces = append(r.ifaces, parseIDB(r.order, data))
+	}
+	r.off += size
+	return rec, nil
+}
+
+// pcapngInterface is what frame decoding needs from an Interface
+// Description Block.
+type pcapngInterface struct {
+	units   tsUnits
+	snapLen uint32
+}
+
+// pcapngFrame decodes the fixed header of a packet block into f.
+func pcapngFrame(order binary.ByteOrder, typ uint32, block []byte, ifaces []pcapngInterface, f *Frame) error {
+	size := int64(len(block))
+	if typ == blockSPB {
+		if size < 16 || len(ifaces) == 0 {
+			return fmt.Errorf("malformed simple packet block")
+		}
+		orig := int64(order.Uint32(block[8:12]))
+		capLen := min(orig, size-16)
+		if snap := int64(ifaces[0].snapLen); snap > 0 {
+			capLen = min(capLen, snap)
+		}
+		f.CapLen, f.OrigLen = int(capLen), int(orig)
+		return nil
+	}
+
+	if size < 32 {
+		return fmt.Errorf("malformed packet block")
+	}
+	if typ == blockEPB {
+		f.Interface = int(order.Uint32(block[8:12]))
+	} else {
+		f.Interface = int(order.Uint16(block[8:10]))
+	}
+	if f.Interface >= len(ifaces) {
+		return fmt.Errorf("packet block references undefined interface %d", f.Interface)
+	}
+	ts := uint64(order.Uint32(block[12:16]))<<32 | uint64(order.Uint32(block[16:20]))
+	f.Time = ifaces[f.Interface].units.time(ts)
+	f.CapLen, f.OrigLen = int(order.Uint32(block[20:24])), int(order.Uint32(block[24:28]))
+	return nil
+}
+
+// parseIDB reads the snapshot length and timestamp options of an Interface
+// Description Block.
+func parseIDB(order binary.ByteOrder, block []byte) pcapngInterface {
+	iface := pcapngInterface{units: newTSUnits(defaultTSRes)}
+	if len(block) < 20 {
+		return iface
+	}
+	iface.snapLen = order.Uint32(block[12:16])
+	opts := block[16 : len(block)-4]
+	for len(opts) >= 4 {
+		code, n := order.Uint16(opts[0:2]), int(order.Uint16(opts[2:4]))
+		if code == optEndOfOpt || 4+n > len(opts) {
+			break
+		}
+		value := opts[4 : 4+n]
+		switch {
+		case code == optTSResol && n == 1:
+			offset := iface.units.offset
+			iface.units = newTSUnits(value[0])
+			iface.units.offset = offset
+		case code == optTSOffset && n == 8:
+			iface.units.offset = int64(order.Uint64(value))
+		}
+		opts = opts[min(len(opts), 4+(n+3)&^3):]
+	}
+	return iface
+}
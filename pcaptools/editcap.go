@@ -0,0 +1,236 @@
+package pcaptools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// maxDedupPackets is the largest duplicate window editcap accepts.
+const maxDedupPackets = 1000000
+
+// FrameRange selects frames From through To, numbered from 1 as tshark
+// numbers them. A To of 0 extends the range to the end of the file.
+type FrameRange struct {
+	From, To int
+}
+
+func (r FrameRange) String() string {
+	switch {
+	case r.To == 0:
+		return strconv.Itoa(r.From) + "-"
+	case r.To == r.From:
+		return strconv.Itoa(r.From)
+	}
+	return fmt.Sprintf("%d-%d", r.From, r.To)
+}
+
+// EditOptions configures Edit and Split. Packet selection happens first,
+// then deduplication and the changes to the kept packets.
+type EditOptions struct {
+	// Keep writes only the frames in these ranges; Delete writes all but
+	// them. At most one of the two may be set.
+	Keep   []FrameRange
+	Delete []FrameRange
+	// StartTime and StopTime, if set, keep only packets captured at or
+	// after StartTime and before StopTime.
+	StartTime time.Time
+	StopTime  time.Time
+
+	// DedupPackets drops a packet identical to one of the previous
+	// DedupPackets packets, up to 1,000,000. DedupTime drops a packet
+	// identical to one captured within DedupTime before it. At most one of
+	// the two may be set.
+	DedupPackets int
+	DedupTime    time.Duration
+
+	// TimeShift is added to every timestamp; it may be negative.
+	TimeShift time.Duration
+	// SnapLen truncates packets to at most this many bytes.
+	SnapLen int
+	// Encapsulation changes the link type, e.g. "ether"; see editcap -T.
+	Encapsulation string
+	// Format is the output file type, e.g. "pcap"; by default that of the
+	// input.
+	Format string
+}
+
+// Edit writes the packets of input selected by opts to output.
+func Edit(ctx context.Context, input, output string, opts EditOptions) error {
+	return defaultTools.Edit(ctx, input, output, opts)
+}
+
+// Edit edits a capture file; see the Edit function.
+func (t *Tools) Edit(ctx context.Context, input, output string, opts EditOptions) error {
+	if input == "" || output == "" {
+		return fmt.Errorf("input and output files cannot be empty")
+	}
+	args, err := opts.args()
+	if err != nil {
+		return err
+	}
+	return t.run(ctx, "editcap", opts.withFiles(args, input, output)...)
+}
+
+// SplitOptions configures Split. Exactly one of Packets and Interval must
+// be set; the edits of EditOptions apply before splitting.
+type SplitOptions struct {
+	EditOptions
+	// Packets starts a new file every Packets packets.
+	Packets int
+	// Interval starts a new file every Interval of capture time.
+	Interval time.Duration
+}
+
+// Split writes the packets of input to a series of files named after
+// output, and returns their paths in order. editcap numbers the files and
+// stamps them with the time of their first packet: splitting into
+// "part.pcapng" writes "part_00000_20240501120000.pcapng" and so on. Files
+// of the same names are replaced, and every file written is returned even
+// when an earlier split into the same output wrote it before.
+func Split(ctx context.Context, input, output string, opts SplitOptions) ([]string, error) {
+	return defaultTools.Split(ctx, input, output, opts)
+}
+
+// Split splits a capture file; see the Split function.
+func (t *Tools) Split(ctx context.Context, input, output string, opts SplitOptions) ([]string, error) {
+	if input == "" || output == "" {
+		return nil, fmt.Errorf("input and output files cannot be empty")
+	}
+	args, err := opts.args()
+	if err != nil {
+		return nil, err
+	}
+
+	// editcap writes into a fresh directory beside output, so the files of
+	// this run are told apart from those an earlier run left, and are then
+	// moved into place, replacing files of the same name as editcap would.
+	dir, pattern := splitPattern(output)
+	tmp, err := os.MkdirTemp(dir, ".split-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create split directory: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+	if err := t.run(ctx, "editcap", opts.withFiles(args, input, filepath.Join(tmp, filepath.Base(output)))...); err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(tmp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list split files: %w", err)
+	}
+	var files []string
+	for _, entry := range entries {
+		if !pattern.MatchString(entry.Name()) {
+			continue
+		}
+		file := filepath.Join(filepath.Dir(output), entry.Name())
+		if err := os.Rename(filepath.Join(tmp, entry.Name()), file); err != nil {
+			return nil, fmt.Errorf("failed to move split file: %w", err)
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+func (o SplitOptions) args() ([]string, error) {
+	switch {
+	case o.Packets < 0 || o.Interval < 0:
+		return nil, fmt.Errorf("invalid split size")
+	case (o.Packets > 0) == (o.Interval > 0):
+		return nil, fmt.Errorf("split needs exactly one of a packet count and an interval")
+	}
+	args, err := o.EditOptions.args()
+	if err != nil {
+		return nil, err
+	}
+	if o.Packets > 0 {
+		return append(args, "-c", strconv.Itoa(o.Packets)), nil
+	}
+	return append(args, "-i", formatSeconds(o.Interval)), nil
+}
+
+// splitPattern matches the names editcap gives split files.
+func splitPattern(output string) (dir string, pattern *regexp.Regexp) {
+	dir, base := filepath.Split(output)
+	if dir == "" {
+		dir = "."
+	}
+	ext := filepath.Ext(base)
+	prefix := base[:len(base)-len(ext)]
+	return dir, regexp.MustCompile("^" + regexp.QuoteMeta(prefix) + `_\d{5}_\d{14}` + regexp.QuoteMeta(ext) + "$")
+}
+
+// args returns editcap's options; the frame ranges follow the file names.
+func (o EditOptions) args() ([]string, error) {
+	if len(o.Keep) > 0 && len(o.Delete) > 0 {
+		return nil, fmt.Errorf("cannot both keep and delete frames")
+	}
+	for _, r := range o.ranges() {
+		if r.From < 1 || (r.To != 0 && r.To < r.From) {
+			return nil, fmt.Errorf("invalid frame range %s", r)
+		}
+	}
+	if o.DedupPackets > 0 && o.DedupTime > 0 {
+		return nil, fmt.Errorf("cannot deduplicate by both packet count and time")
+	}
+	if o.DedupPackets < 0 || o.DedupPackets > maxDedupPackets {
+		return nil, fmt.Errorf("duplicate window must be between 0 and %d packets", maxDedupPackets)
+	}
+	if o.DedupTime < 0 || o.SnapLen < 0 {
+		return nil, fmt.Errorf("duplicate window and snapshot length cannot be negative")
+	}
+	if !o.StartTime.IsZero() && !o.StopTime.IsZero() && !o.StopTime.After(o.StartTime) {
+		return nil, fmt.Errorf("stop time must be after start time")
+	}
+
+	var args []string
+	if len(o.Keep) > 0 {
+		args = append(args, "-r")
+	}
+	if !o.StartTime.IsZero() {
+		args = append(args, "-A", formatEpoch(o.StartTime))
+	}
+	if !o.StopTime.IsZero() {
+		args = append(args, "-B", formatEpoch(o.StopTime))
+	}
+	if o.DedupPackets > 0 {
+		args = append(args, "-D", strconv.Itoa(o.DedupPackets))
+	}
+	if o.DedupTime > 0 {
+		args = append(args, "-w", formatSeconds(o.DedupTime))
+	}
+	if o.TimeShift != 0 {
+		args = append(args, "-t", formatSeconds(o.TimeShift))
+	}
+	if o.SnapLen > 0 {
+		args = append(args, "-s", strconv.Itoa(o.SnapLen))
+	}
+	if o.Encapsulation != "" {
+		args = append(args, "-T", o.Encapsulation)
+	}
+	if o.Format != "" {
+		args = append(args, "-F", o.Format)
+	}
+	return args, nil
+}
+
+// ranges returns the frame ranges to keep or delete.
+func (o EditOptions) ranges() []FrameRange {
+	if len(o.Keep) > 0 {
+		return o.Keep
+	}
+	return o.Delete
+}
+
+// withFiles appends the input and output files and the selected frames.
+func (o EditOptions) withFiles(args []string, input, output string) []string {
+	args = append(args, input, output)
+	for _, r := range o.ranges() {
+		args = append(args, r.String())
+	}
+	return args
+}
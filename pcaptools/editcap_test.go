@@ -0,0 +1,107 @@
+package pcaptools
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEditArgs(t *testing.T) {
+	start := time.Unix(1700000000, 500000000)
+	for _, tc := range []struct {
+		name string
+		opts EditOptions
+		want []string
+	}{
+		{"none", EditOptions{}, []string{"in.pcap", "out.pcap"}},
+		{"keep", EditOptions{Keep: []FrameRange{{1, 10}, {20, 20}, {30, 0}}},
+			[]string{"-r", "in.pcap", "out.pcap", "1-10", "20", "30-"}},
+		{"delete", EditOptions{Delete: []FrameRange{{5, 6}}}, []string{"in.pcap", "out.pcap", "5-6"}},
+		{"times", EditOptions{StartTime: start, StopTime: start.Add(time.Minute)},
+			[]string{"-A", "1700000000.500000000", "-B", "1700000060.500000000", "in.pcap", "out.pcap"}},
+		{"dedup packets", EditOptions{DedupPackets: 100}, []string{"-D", "100", "in.pcap", "out.pcap"}},
+		{"everything else", EditOptions{
+			DedupTime: 1500 * time.Millisecond, TimeShift: -2 * time.Hour, SnapLen: 96,
+			Encapsulation: "ether", Format: "pcap",
+		}, []string{"-w", "1.5", "-t", "-7200", "-s", "96", "-T", "ether", "-F", "pcap", "in.pcap", "out.pcap"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			args, err := tc.opts.args()
+			if err != nil {
+				t.Fatalf("args: %v", err)
+			}
+			if got := tc.opts.withFiles(args, "in.pcap", "out.pcap"); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("args = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestEditArgsInvalid(t *testing.T) {
+	now := time.Now()
+	for name, opts := range map[string]EditOptions{
+		"keep and delete":   {Keep: []FrameRange{{1, 2}}, Delete: []FrameRange{{3, 4}}},
+		"frame zero":        {Keep: []FrameRange{{0, 2}}},
+		"reversed range":    {Delete: []FrameRange{{5, 2}}},
+		"two dedup modes":   {DedupPackets: 5, DedupTime: time.Second},
+		"dedup too large":   {DedupPackets: maxDedupPackets + 1},
+		"negative snaplen":  {SnapLen: -1},
+		"stop before start": {StartTime: now, StopTime: now.Add(-time.Second)},
+	} {
+		if _, err := opts.args(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestSplitArgs(t *testing.T) {
+	args, err := SplitOptions{Packets: 1000}.args()
+	if err != nil || !reflect.DeepEqual(args, []string{"-c", "1000"}) {
+		t.Errorf("by count = %q, %v", args, err)
+	}
+	args, err = SplitOptions{EditOptions: EditOptions{SnapLen: 64}, Interval: time.Minute}.args()
+	if err != nil || !reflect.DeepEqual(args, []string{"-s", "64", "-i", "60"}) {
+		t.Errorf("by interval = %q, %v", args, err)
+	}
+	for _, opts := range []SplitOptions{{}, {Packets: 10, Interval: time.Second}, {Packets: -1}} {
+		if _, err := opts.args(); err == nil {
+			t.Errorf("%+v: expected an error", opts)
+		}
+	}
+}
+
+func TestSplitPattern(t *testing.T) {
+	dir, pattern := splitPattern("/tmp/captures/part.v2.pcapng")
+	if dir != "/tmp/captures/" {
+		t.Errorf("dir = %q", dir)
+	}
+	for name, want := range map[string]bool{
+		"part.v2_00000_20240501120000.pcapng": true,
+		"part.v2_00012_20240501120500.pcapng": true,
+		"part.v2.pcapng":                      false,
+		"part.v2_00000_20240501120000.pcap":   false,
+		"partXv2_00000_20240501120000.pcapng": false,
+	} {
+		if got := pattern.MatchString(name); got != want {
+			t.Errorf("match %q = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestFormatSeconds(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		0:                       "0",
+		time.Minute:             "60",
+		1500 * time.Millisecond: "1.5",
+		-250 * time.Microsecond: "-0.00025",
+		time.Nanosecond:         "0.000000001",
+	} {
+		if got := formatSeconds(d); got != want {
+			t.Errorf("formatSeconds(%v) = %q, want %q", d, got, want)
+		}
+	}
+	if got := formatEpoch(time.Unix(12, 34)); !strings.HasPrefix(got, "12.000000034") {
+		t.Errorf("formatEpoch = %q", got)
+	}
+}
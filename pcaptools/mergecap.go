@@ -0,0 +1,79 @@
+package pcaptools
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// InterfaceMerge is how mergecap combines the interfaces of pcapng inputs.
+type InterfaceMerge string
+
+const (
+	// MergeAllInterfaces merges interfaces only if every input has the same
+	// set of them; mergecap's default.
+	MergeAllInterfaces InterfaceMerge = "all"
+	// MergeAnyInterfaces merges any interfaces that are identical.
+	MergeAnyInterfaces InterfaceMerge = "any"
+	// MergeNoInterfaces keeps every input's interfaces apart.
+	MergeNoInterfaces InterfaceMerge = "none"
+)
+
+// MergeOptions configures Merge. The zero value interleaves the inputs in
+// timestamp order into a pcapng file.
+type MergeOptions struct {
+	// Concatenate appends the inputs in the order given instead of
+	// interleaving their packets by timestamp.
+	Concatenate bool
+	// SnapLen truncates packets to at most this many bytes.
+	SnapLen int
+	// Format is the output file type, e.g. "pcap"; pcapng by default.
+	Format string
+	// Interfaces selects how pcapng interfaces are merged.
+	Interfaces InterfaceMerge
+}
+
+// Merge combines the capture files inputs into output. Overlapping ring
+// buffer files can be merged and then deduplicated with Edit.
+func Merge(ctx context.Context, output string, inputs []string, opts MergeOptions) error {
+	return defaultTools.Merge(ctx, output, inputs, opts)
+}
+
+// Merge combines capture files; see the Merge function.
+func (t *Tools) Merge(ctx context.Context, output string, inputs []string, opts MergeOptions) error {
+	args, err := mergeArgs(output, inputs, opts)
+	if err != nil {
+		return err
+	}
+	return t.run(ctx, "mergecap", args...)
+}
+
+func mergeArgs(output string, inputs []string, opts MergeOptions) ([]string, error) {
+	if output == "" {
+		return nil, fmt.Errorf("output file cannot be empty")
+	}
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("no input files to merge")
+	}
+	if opts.SnapLen < 0 {
+		return nil, fmt.Errorf("invalid snapshot length %d", opts.SnapLen)
+	}
+	args := []string{"-w", output}
+	if opts.Concatenate {
+		args = append(args, "-a")
+	}
+	if opts.SnapLen > 0 {
+		args = append(args, "-s", strconv.Itoa(opts.SnapLen))
+	}
+	if opts.Format != "" {
+		args = append(args, "-F", opts.Format)
+	}
+	switch opts.Interfaces {
+	case "":
+	case MergeAllInterfaces, MergeAnyInterfaces, MergeNoInterfaces:
+		args = append(args, "-I", string(opts.Interfaces))
+	default:
+		return nil, fmt.Errorf("unknown interface merge mode %q", opts.Interfaces)
+	}
+	return append(args, inputs...), nil
+}
@@ -0,0 +1,32 @@
+package pcaptools
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeArgs(t *testing.T) {
+	args, err := mergeArgs("all.pcapng", []string{"a.pcap", "b.pcap"}, MergeOptions{})
+	if err != nil || !reflect.DeepEqual(args, []string{"-w", "all.pcapng", "a.pcap", "b.pcap"}) {
+		t.Errorf("default = %q, %v", args, err)
+	}
+	args, err = mergeArgs("all.pcap", []string{"a.pcap"}, MergeOptions{
+		Concatenate: true, SnapLen: 128, Format: "pcap", Interfaces: MergeNoInterfaces,
+	})
+	want := []string{"-w", "all.pcap", "-a", "-s", "128", "-F", "pcap", "-I", "none", "a.pcap"}
+	if err != nil || !reflect.DeepEqual(args, want) {
+		t.Errorf("options = %q, %v", args, err)
+	}
+
+	for name, call := range map[string]func() ([]string, error){
+		"no output": func() ([]string, error) { return mergeArgs("", []string{"a.pcap"}, MergeOptions{}) },
+		"no inputs": func() ([]string, error) { return mergeArgs("all.pcap", nil, MergeOptions{}) },
+		"bad merge mode": func() ([]string, error) {
+			return mergeArgs("all.pcap", []string{"a"}, MergeOptions{Interfaces: "some"})
+		},
+	} {
+		if _, err := call(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
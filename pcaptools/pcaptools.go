@@ -0,0 +1,74 @@
+// Package pcaptools edits, splits, merges and reorders capture files with
+// Wireshark's editcap, mergecap and reordercap. The tools are looked for
+// next to tshark, then in the system PATH, the way dumpcap is.
+//
+//	err := pcaptools.Merge(ctx, "all.pcapng", []string{"ring_1.pcapng", "ring_2.pcapng"}, pcaptools.MergeOptions{})
+//	err = pcaptools.Edit(ctx, "all.pcapng", "dedup.pcapng", pcaptools.EditOptions{DedupTime: time.Second})
+//	parts, err := pcaptools.Split(ctx, "dedup.pcapng", "part.pcapng", pcaptools.SplitOptions{Interval: time.Minute})
+//
+// A tool that cannot be found returns an errors.ToolNotFoundError; one that
+// fails returns an errors.ToolError carrying its output and exit status.
+package pcaptools
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	sharkerrors "github.com/p-vbordei/GoShark/errors"
+	"github.com/p-vbordei/GoShark/tshark"
+)
+
+// Tools runs the capture file tools. The zero value finds them next to the
+// tshark FindTShark returns.
+type Tools struct {
+	// TSharkPath is the tshark executable the tools are installed beside.
+	TSharkPath string
+}
+
+var defaultTools Tools
+
+// run runs tool with args and returns a typed error if it fails.
+func (t *Tools) run(ctx context.Context, tool string, args ...string) error {
+	path, err := tshark.GetToolPath(t.TSharkPath, tool)
+	if err != nil {
+		return err
+	}
+	var output bytes.Buffer
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		exitCode := -1
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			exitCode = exitErr.ExitCode()
+		}
+		command := strings.Join(append([]string{path}, args...), " ")
+		return sharkerrors.NewToolError(tool, command, strings.TrimSpace(output.String()), exitCode, err)
+	}
+	return nil
+}
+
+// formatSeconds formats d as the decimal seconds the tools accept, e.g.
+// "-1.5"; whole seconds have no fraction.
+func formatSeconds(d time.Duration) string {
+	sign := ""
+	if d < 0 {
+		sign, d = "-", -d
+	}
+	s := fmt.Sprintf("%d.%09d", d/time.Second, d%time.Second)
+	return sign + strings.TrimSuffix(strings.TrimRight(s, "0"), ".")
+}
+
+// formatEpoch formats t as seconds since the epoch.
+func formatEpoch(t time.Time) string {
+	return fmt.Sprintf("%d.%09d", t.Unix(), t.Nanosecond())
+}
@@ -0,0 +1,143 @@
+package pcaptools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	sharkerrors "github.com/p-vbordei/GoShark/errors"
+	"github.com/p-vbordei/GoShark/pcapfile"
+	"github.com/p-vbordei/GoShark/tshark"
+)
+
+// fakeTools installs shell scripts as tools next to a fake tshark. The
+// PATH is emptied, so scripts can only use shell builtins.
+func fakeTools(t *testing.T, scripts map[string]string) *Tools {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake tools are shell scripts")
+	}
+	dir := t.TempDir()
+	for name, script := range scripts {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", t.TempDir())
+	return &Tools{TSharkPath: filepath.Join(dir, "tshark")}
+}
+
+func TestToolErrors(t *testing.T) {
+	tools := fakeTools(t, map[string]string{
+		"mergecap": "echo 'mergecap: The file \"a.pcap\" doesn'\\''t exist.' >&2\nexit 2\n",
+	})
+	err := tools.Merge(context.Background(), "out.pcap", []string{"a.pcap"}, MergeOptions{})
+	var toolErr *sharkerrors.ToolError
+	if !sharkerrors.As(err, &toolErr) {
+		t.Fatalf("Merge error = %v, want a ToolError", err)
+	}
+	if toolErr.Tool() != "mergecap" || toolErr.ExitCode() != 2 || !strings.Contains(toolErr.Output(), "doesn't exist") ||
+		!strings.HasSuffix(toolErr.Command(), "mergecap -w out.pcap a.pcap") {
+		t.Errorf("ToolError = %q (tool %s, exit %d, command %q)", toolErr, toolErr.Tool(), toolErr.ExitCode(), toolErr.Command())
+	}
+
+	err = tools.Reorder(context.Background(), "in.pcap", "out.pcap")
+	var notFound *sharkerrors.ToolNotFoundError
+	if !sharkerrors.As(err, &notFound) || notFound.Tool() != "reordercap" {
+		t.Errorf("Reorder error = %v, want a ToolNotFoundError", err)
+	}
+}
+
+func TestSplitReportsNewFiles(t *testing.T) {
+	dir := t.TempDir()
+	// A file from an earlier run is not reported.
+	old := filepath.Join(dir, "part_00000_20240101000000.pcapng")
+	if err := os.WriteFile(old, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tools := fakeTools(t, map[string]string{
+		"editcap": "echo \"$@\" > " + filepath.Join(dir, "args") + "\n" +
+			"out=\"${4%.pcapng}\"\n" +
+			": > \"${out}_00000_20240501120000.pcapng\"\n" +
+			": > \"${out}_00001_20240501120100.pcapng\"\n",
+	})
+	want := []string{
+		filepath.Join(dir, "part_00000_20240501120000.pcapng"),
+		filepath.Join(dir, "part_00001_20240501120100.pcapng"),
+	}
+	// Splitting again into the same output reports the rewritten files.
+	for run := 1; run <= 2; run++ {
+		files, err := tools.Split(context.Background(), "in.pcapng", filepath.Join(dir, "part.pcapng"), SplitOptions{Packets: 2})
+		if err != nil {
+			t.Fatalf("Split run %d: %v", run, err)
+		}
+		if !reflect.DeepEqual(files, want) {
+			t.Errorf("run %d: files = %q, want %q", run, files, want)
+		}
+	}
+	args, _ := os.ReadFile(filepath.Join(dir, "args"))
+	if fields := strings.Fields(string(args)); len(fields) != 4 || strings.Join(fields[:3], " ") != "-c 2 in.pcapng" ||
+		filepath.Base(fields[3]) != "part.pcapng" {
+		t.Errorf("editcap args = %q", args)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 4 {
+		t.Errorf("directory holds %d entries, want the old file, two split files and args", len(entries))
+	}
+	if _, err := os.Stat(old); err != nil {
+		t.Errorf("unrelated earlier file: %v", err)
+	}
+}
+
+func TestToolsIntegration(t *testing.T) {
+	for _, tool := range []string{"editcap", "mergecap", "reordercap"} {
+		if _, err := tshark.GetToolPath("", tool); err != nil {
+			t.Skipf("%s not found", tool)
+		}
+	}
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	edited := filepath.Join(dir, "edited.pcapng")
+	if err := Edit(ctx, "../test.pcap", edited, EditOptions{Keep: []FrameRange{{2, 3}}}); err != nil {
+		t.Fatalf("Edit: %v", err)
+	}
+	if info, err := pcapfile.FileInfo(edited); err != nil || info.Packets != 2 {
+		t.Fatalf("edited file: %+v, %v", info, err)
+	}
+
+	merged := filepath.Join(dir, "merged.pcapng")
+	if err := Merge(ctx, merged, []string{"../test.pcap", edited}, MergeOptions{}); err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+	deduped := filepath.Join(dir, "deduped.pcapng")
+	if err := Edit(ctx, merged, deduped, EditOptions{DedupPackets: 10}); err != nil {
+		t.Fatalf("Edit: %v", err)
+	}
+	if info, err := pcapfile.FileInfo(deduped); err != nil || info.Packets != 5 {
+		t.Errorf("deduplicated file: %+v, %v", info, err)
+	}
+
+	sorted := filepath.Join(dir, "sorted.pcapng")
+	if err := Reorder(ctx, merged, sorted); err != nil {
+		t.Fatalf("Reorder: %v", err)
+	}
+	if info, err := pcapfile.FileInfo(sorted); err != nil || !info.TimeOrdered {
+		t.Errorf("reordered file: %+v, %v", info, err)
+	}
+
+	parts, err := Split(ctx, "../test.pcap", filepath.Join(dir, "part.pcapng"), SplitOptions{Packets: 2})
+	if err != nil || len(parts) != 3 {
+		t.Errorf("Split = %q, %v", parts, err)
+	}
+
+	err = Edit(ctx, filepath.Join(dir, "missing.pcap"), filepath.Join(dir, "out.pcap"), EditOptions{})
+	var toolErr *sharkerrors.ToolError
+	if !sharkerrors.As(err, &toolErr) || toolErr.Tool() != "editcap" {
+		t.Errorf("Edit of a missing file = %v", err)
+	}
+}
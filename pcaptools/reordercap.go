@@ -0,0 +1,20 @@
+package pcaptools
+
+import (
+	"context"
+	"fmt"
+)
+
+// Reorder writes input to output with its packets sorted by timestamp, as
+// ring buffers and multi-interface captures can record them out of order.
+func Reorder(ctx context.Context, input, output string) error {
+	return defaultTools.Reorder(ctx, input, output)
+}
+
+// Reorder sorts a capture file by timestamp; see the Reorder function.
+func (t *Tools) Reorder(ctx context.Context, input, output string) error {
+	if input == "" || output == "" {
+		return fmt.Errorf("input and output files cannot be empty")
+	}
+	return t.run(ctx, "reordercap", input, output)
+}
@@ -0,0 +1,144 @@
+package stats
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// portProtocols are the conversation and endpoint types whose addresses
+// carry a port.
+var portProtocols = map[string]bool{
+	"tcp": true, "udp": true, "sctp": true, "dccp": true,
+}
+
+// Conversation is one row of a conversation table (-z conv). A is the
+// endpoint listed first; "AToB" counts frames sent from A to B.
+type Conversation struct {
+	AddressA string
+	PortA    string // Empty for address-only types such as ip and eth.
+	AddressB string
+	PortB    string
+
+	FramesAToB int64
+	BytesAToB  int64
+	FramesBToA int64
+	BytesBToA  int64
+	Frames     int64
+	Bytes      int64
+
+	// RelativeStart is the time of the first frame, relative to the start
+	// of the capture.
+	RelativeStart time.Duration
+	Duration      time.Duration
+}
+
+// Conversations returns the conversation table for proto, a conversation
+// type such as "eth", "ip", "ipv6", "tcp" or "udp".
+func (s *Stats) Conversations(ctx context.Context, proto string) ([]Conversation, error) {
+	out, err := s.runTap(ctx, "conv,"+proto)
+	if err != nil {
+		return nil, err
+	}
+	return parseConversations(out, proto)
+}
+
+func parseConversations(out, proto string) ([]Conversation, error) {
+	withPort := portProtocols[strings.ToLower(proto)]
+	var convs []Conversation
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		t := newTokens(scanner.Text())
+		if len(t.fields) < 3 || t.fields[1] != "<->" {
+			continue
+		}
+		var c Conversation
+		c.AddressA, c.PortA = splitEndpoint(t.next(), withPort)
+		t.next()
+		c.AddressB, c.PortB = splitEndpoint(t.next(), withPort)
+		// The "<-" columns come first: frames B sent to A.
+		c.FramesBToA = t.count()
+		c.BytesBToA = t.size()
+		c.FramesAToB = t.count()
+		c.BytesAToB = t.size()
+		c.Frames = t.count()
+		c.Bytes = t.size()
+		c.RelativeStart = t.seconds()
+		c.Duration = t.seconds()
+		if t.err != nil {
+			return nil, fmt.Errorf("failed to parse conversation %q: %w", scanner.Text(), t.err)
+		}
+		convs = append(convs, c)
+	}
+	return convs, scanner.Err()
+}
+
+// splitEndpoint splits "addr:port" at the last colon, which also works for
+// IPv6 addresses.
+func splitEndpoint(s string, withPort bool) (addr, port string) {
+	if !withPort {
+		return s, ""
+	}
+	if i := strings.LastIndexByte(s, ':'); i >= 0 {
+		return s[:i], s[i+1:]
+	}
+	return s, ""
+}
+
+// Endpoint is one row of an endpoint table (-z endpoints).
+type Endpoint struct {
+	Address   string
+	Port      string // Empty for address-only types such as ip and eth.
+	Packets   int64
+	Bytes     int64
+	TxPackets int64
+	TxBytes   int64
+	RxPackets int64
+	RxBytes   int64
+}
+
+// Endpoints returns the endpoint table for proto, an endpoint type such as
+// "eth", "ip", "ipv6", "tcp" or "udp".
+func (s *Stats) Endpoints(ctx context.Context, proto string) ([]Endpoint, error) {
+	out, err := s.runTap(ctx, "endpoints,"+proto)
+	if err != nil {
+		return nil, err
+	}
+	return parseEndpoints(out, proto)
+}
+
+func parseEndpoints(out, proto string) ([]Endpoint, error) {
+	withPort := portProtocols[strings.ToLower(proto)]
+	var endpoints []Endpoint
+	inTable := false
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.Contains(line, "|"):
+			inTable = true
+			continue
+		case !inTable || isRule(line) || strings.TrimSpace(line) == "":
+			continue
+		}
+		t := newTokens(line)
+		var e Endpoint
+		e.Address = t.next()
+		if withPort {
+			e.Port = t.next()
+		}
+		e.Packets = t.count()
+		e.Bytes = t.size()
+		e.TxPackets = t.count()
+		e.TxBytes = t.size()
+		e.RxPackets = t.count()
+		e.RxBytes = t.size()
+		if t.err != nil {
+			return nil, fmt.Errorf("failed to parse endpoint %q: %w", line, t.err)
+		}
+		endpoints = append(endpoints, e)
+	}
+	return endpoints, scanner.Err()
+}
@@ -0,0 +1,88 @@
+package stats
+
+import (
+	"testing"
+	"time"
+)
+
+const convTCPOutput = `================================================================================
+TCP Conversations
+Filter:<No Filter>
+                                                           |       <-      | |       ->      | |     Total     |    Relative    |   Duration   |
+                                                           | Frames  Bytes | | Frames  Bytes | | Frames  Bytes |      Start     |              |
+10.0.0.1:58894             <-> 93.184.216.34:80                 5 3,572 bytes       6 620 bytes      11 4,192 bytes     0.000000000         0.1234
+[2001:db8::1]:443          <-> 2001:db8::2:51000              20 12 kB            18 2 kB            38 14 kB        1.500000000         2.0000
+================================================================================
+`
+
+func TestParseConversations(t *testing.T) {
+	convs, err := parseConversations(convTCPOutput, "tcp")
+	if err != nil {
+		t.Fatalf("parseConversations: %v", err)
+	}
+	if len(convs) != 2 {
+		t.Fatalf("got %d conversations, want 2", len(convs))
+	}
+	want := Conversation{
+		AddressA: "10.0.0.1", PortA: "58894",
+		AddressB: "93.184.216.34", PortB: "80",
+		FramesAToB: 6, BytesAToB: 620,
+		FramesBToA: 5, BytesBToA: 3572,
+		Frames: 11, Bytes: 4192,
+		Duration: 123400 * time.Microsecond,
+	}
+	if convs[0] != want {
+		t.Errorf("conversation 0 = %+v, want %+v", convs[0], want)
+	}
+	c := convs[1]
+	if c.AddressA != "[2001:db8::1]" || c.PortA != "443" || c.AddressB != "2001:db8::2" || c.PortB != "51000" {
+		t.Errorf("conversation 1 endpoints = %s:%s <-> %s:%s", c.AddressA, c.PortA, c.AddressB, c.PortB)
+	}
+	if c.BytesBToA != 12000 || c.Bytes != 14000 || c.RelativeStart != 1500*time.Millisecond {
+		t.Errorf("conversation 1 = %+v", c)
+	}
+}
+
+func TestParseConversationsWithoutPorts(t *testing.T) {
+	out := "IPv4 Conversations\nFilter:<No Filter>\n" +
+		"                 |  <-  | |  ->  | | Total | Relative | Duration |\n" +
+		"10.0.0.1 <-> 10.0.0.2    1 60    2 120    3 180    0.5    1.0\n"
+	convs, err := parseConversations(out, "ip")
+	if err != nil {
+		t.Fatalf("parseConversations: %v", err)
+	}
+	if len(convs) != 1 || convs[0].AddressA != "10.0.0.1" || convs[0].PortA != "" || convs[0].Bytes != 180 {
+		t.Errorf("got %+v", convs)
+	}
+
+	if _, err := parseConversations("10.0.0.1 <-> 10.0.0.2 1 60\n", "ip"); err == nil {
+		t.Error("expected an error for a truncated row")
+	}
+}
+
+func TestParseEndpoints(t *testing.T) {
+	out := `================================================================================
+TCP Endpoints
+Filter:<No Filter>
+                       |  Port  ||  Packets  | |  Bytes  | | Tx Packets | | Tx Bytes | | Rx Packets | | Rx Bytes |
+10.0.0.1                  58894          11          4192          6           620          5          3572
+93.184.216.34                80          11          4192          5          3572          6           620
+================================================================================
+`
+	eps, err := parseEndpoints(out, "tcp")
+	if err != nil {
+		t.Fatalf("parseEndpoints: %v", err)
+	}
+	want := []Endpoint{
+		{Address: "10.0.0.1", Port: "58894", Packets: 11, Bytes: 4192, TxPackets: 6, TxBytes: 620, RxPackets: 5, RxBytes: 3572},
+		{Address: "93.184.216.34", Port: "80", Packets: 11, Bytes: 4192, TxPackets: 5, TxBytes: 3572, RxPackets: 6, RxBytes: 620},
+	}
+	if len(eps) != len(want) {
+		t.Fatalf("got %d endpoints, want %d", len(eps), len(want))
+	}
+	for i := range want {
+		if eps[i] != want[i] {
+			t.Errorf("endpoint %d = %+v, want %+v", i, eps[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package stats
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ExpertInfo is one row of the expert information report (-z expert): a
+// message and how many frames raised it.
+type ExpertInfo struct {
+	Severity  string // Error, Warning, Note, Chat or Comment.
+	Frequency int64
+	Group     string
+	Protocol  string
+	Summary   string
+}
+
+// expertSections maps the report's section headings to severities.
+var expertSections = map[string]string{
+	"Errors":   "Error",
+	"Warns":    "Warning",
+	"Notes":    "Note",
+	"Chats":    "Chat",
+	"Comments": "Comment",
+}
+
+// expertGroups are the expert groups whose names contain a space.
+var expertGroups = []string{"Response code", "Request code", "Dissector bug", "Comments group"}
+
+// Expert returns the capture's expert information, most severe first.
+func (s *Stats) Expert(ctx context.Context) ([]ExpertInfo, error) {
+	out, err := s.runTap(ctx, "expert")
+	if err != nil {
+		return nil, err
+	}
+	return parseExpert(out)
+}
+
+func parseExpert(out string) ([]ExpertInfo, error) {
+	var infos []ExpertInfo
+	severity := ""
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if heading, _, ok := strings.Cut(trimmed, " ("); ok && expertSections[heading] != "" {
+			severity = expertSections[heading]
+			continue
+		}
+		if severity == "" || trimmed == "" || isRule(trimmed) || strings.HasPrefix(trimmed, "Frequency") {
+			continue
+		}
+
+		freq, rest, _ := strings.Cut(trimmed, " ")
+		info := ExpertInfo{Severity: severity}
+		n, err := parseCount(freq)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse expert info %q: invalid frequency %q", line, freq)
+		}
+		info.Frequency = n
+
+		rest = strings.TrimLeft(rest, " ")
+		for _, g := range expertGroups {
+			if r, ok := strings.CutPrefix(rest, g+" "); ok {
+				info.Group, rest = g, r
+				break
+			}
+		}
+		if info.Group == "" {
+			info.Group, rest, _ = strings.Cut(rest, " ")
+		}
+		rest = strings.TrimLeft(rest, " ")
+		info.Protocol, rest, _ = strings.Cut(rest, " ")
+		info.Summary = strings.TrimSpace(rest)
+		if info.Protocol == "" {
+			return nil, fmt.Errorf("failed to parse expert info %q: row ends early", line)
+		}
+		infos = append(infos, info)
+	}
+	return infos, scanner.Err()
+}
@@ -0,0 +1,45 @@
+package stats
+
+import "testing"
+
+func TestParseExpert(t *testing.T) {
+	out := `
+Errors (1)
+=============
+   Frequency      Group           Protocol  Summary
+           1  Malformed               DNS  Malformed Packet (Exception occurred)
+
+Warns (3)
+=============
+   Frequency      Group           Protocol  Summary
+           3   Sequence               TCP  Previous segment(s) not captured (common at capture start)
+
+Notes (2)
+=============
+   Frequency      Group           Protocol  Summary
+           2 Response code            DNS  No such name
+
+Chats (10)
+=============
+   Frequency      Group           Protocol  Summary
+          10   Sequence               TCP  Connection establish request (SYN): server port 80
+`
+	infos, err := parseExpert(out)
+	if err != nil {
+		t.Fatalf("parseExpert: %v", err)
+	}
+	want := []ExpertInfo{
+		{Severity: "Error", Frequency: 1, Group: "Malformed", Protocol: "DNS", Summary: "Malformed Packet (Exception occurred)"},
+		{Severity: "Warning", Frequency: 3, Group: "Sequence", Protocol: "TCP", Summary: "Previous segment(s) not captured (common at capture start)"},
+		{Severity: "Note", Frequency: 2, Group: "Response code", Protocol: "DNS", Summary: "No such name"},
+		{Severity: "Chat", Frequency: 10, Group: "Sequence", Protocol: "TCP", Summary: "Connection establish request (SYN): server port 80"},
+	}
+	if len(infos) != len(want) {
+		t.Fatalf("got %d entries, want %d: %+v", len(infos), len(want), infos)
+	}
+	for i := range want {
+		if infos[i] != want[i] {
+			t.Errorf("entry %d = %+v, want %+v", i, infos[i], want[i])
+		}
+	}
+}
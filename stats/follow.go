@@ -0,0 +1,346 @@
+package stats
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/p-vbordei/GoShark/capture"
+	"github.com/p-vbordei/GoShark/packet"
+)
+
+// FollowMode selects the report format tshark follows a stream in. Both
+// carry the exact payload bytes.
+type FollowMode string
+
+const (
+	// FollowYAML also reports each chunk's frame number and timestamp.
+	// It needs tshark 3.6 or later.
+	FollowYAML FollowMode = "yaml"
+	// FollowRaw reports only the bytes, for older tshark releases.
+	FollowRaw FollowMode = "raw"
+)
+
+// Direction is the direction a chunk of a followed stream was sent in.
+type Direction int
+
+const (
+	// ClientToServer is data sent by the peer that sent the stream's first
+	// packet (tshark's node 0).
+	ClientToServer Direction = iota
+	// ServerToClient is data sent by the other peer (tshark's node 1).
+	ServerToClient
+)
+
+// String returns "client" or "server", the peer that sent the data.
+func (d Direction) String() string {
+	if d == ServerToClient {
+		return "server"
+	}
+	return "client"
+}
+
+// Peer is one end of a followed stream.
+type Peer struct {
+	Address string
+	Port    string
+}
+
+// StreamChunk is the payload of one segment or datagram of a followed
+// stream.
+type StreamChunk struct {
+	Direction Direction
+	Frame     int       // Frame number; 0 in FollowRaw mode.
+	Time      time.Time // Capture time; zero in FollowRaw mode.
+	Data      []byte
+}
+
+// FollowedStream is the reassembled payload of a stream, as shown by
+// Wireshark's Follow Stream dialog.
+type FollowedStream struct {
+	Protocol string
+	Client   Peer
+	Server   Peer
+	Chunks   []StreamChunk
+}
+
+// Reader returns the bytes sent in one direction, in order.
+func (f *FollowedStream) Reader(dir Direction) io.Reader {
+	var readers []io.Reader
+	for _, c := range f.Chunks {
+		if c.Direction == dir {
+			readers = append(readers, bytes.NewReader(c.Data))
+		}
+	}
+	return io.MultiReader(readers...)
+}
+
+// ClientData returns the bytes the client sent.
+func (f *FollowedStream) ClientData() io.Reader { return f.Reader(ClientToServer) }
+
+// ServerData returns the bytes the server sent.
+func (f *FollowedStream) ServerData() io.Reader { return f.Reader(ServerToClient) }
+
+// followProtocols are the protocols tshark can follow. Those with
+// sub-streams identify a stream by a connection index and a stream index.
+var followProtocols = map[string]bool{
+	"tcp": false, "udp": false, "tls": false, "http": false,
+	"http2": true, "quic": true,
+}
+
+// FollowStream follows stream streamIndex of proto ("tcp", "udp", "tls",
+// "http", "http2" or "quic") in the capture file at path. TLS, HTTP and
+// HTTP/2 streams are numbered by their TCP stream; pass decryption keys as
+// capture options to follow encrypted streams. HTTP/2 and QUIC follow the
+// connection's first sub-stream; use Stats.FollowSubStream for others.
+func FollowStream(path, proto string, streamIndex int, mode FollowMode, options ...capture.Option) (*FollowedStream, error) {
+	return NewFileStats(path, options...).Follow(context.Background(), proto, streamIndex, mode)
+}
+
+// Follow follows a stream of the capture; see FollowStream.
+func (s *Stats) Follow(ctx context.Context, proto string, streamIndex int, mode FollowMode) (*FollowedStream, error) {
+	return s.FollowSubStream(ctx, proto, streamIndex, 0, mode)
+}
+
+// FollowSubStream follows sub-stream subStream of an HTTP/2 or QUIC
+// connection. subStream is ignored for other protocols.
+func (s *Stats) FollowSubStream(ctx context.Context, proto string, streamIndex, subStream int, mode FollowMode) (*FollowedStream, error) {
+	proto = strings.ToLower(proto)
+	hasSub, ok := followProtocols[proto]
+	if !ok {
+		return nil, fmt.Errorf("cannot follow %s streams", proto)
+	}
+	if mode != FollowYAML && mode != FollowRaw {
+		return nil, fmt.Errorf("unsupported follow mode %q", mode)
+	}
+	if streamIndex < 0 || subStream < 0 {
+		return nil, fmt.Errorf("invalid stream index %d", streamIndex)
+	}
+
+	tap := "follow," + proto + "," + string(mode) + "," + strconv.Itoa(streamIndex)
+	if hasSub {
+		tap += "," + strconv.Itoa(subStream)
+	}
+	out, err := s.Run(ctx, tap)
+	if err != nil {
+		return nil, err
+	}
+
+	var stream *FollowedStream
+	if mode == FollowYAML {
+		stream, err = parseFollowYAML(out)
+	} else {
+		stream, err = parseFollowRaw(out)
+	}
+	if err != nil {
+		return nil, err
+	}
+	stream.Protocol = proto
+	return stream, nil
+}
+
+// streamFields are the fields numbering the streams FollowStream accepts.
+var streamFields = map[string]string{
+	"tcp": "tcp.stream", "tls": "tcp.stream", "http": "tcp.stream", "http2": "tcp.stream",
+	"udp":  "udp.stream",
+	"quic": "quic.connection.number",
+}
+
+// PacketStreamIndex returns the index of the proto stream p belongs to,
+// read from its tcp.stream, udp.stream or quic.connection.number field.
+func PacketStreamIndex(p *packet.Packet, proto string) (int, error) {
+	field, ok := streamFields[strings.ToLower(proto)]
+	if !ok {
+		return 0, fmt.Errorf("cannot follow %s streams", proto)
+	}
+	layerName, _, _ := strings.Cut(field, ".")
+	layer := p.GetLayer(layerName)
+	if layer == nil {
+		return 0, fmt.Errorf("packet %s has no %s layer", p.FrameNumber, layerName)
+	}
+	n, err := layer.GetFieldInt(field)
+	if err != nil {
+		return 0, fmt.Errorf("packet %s has no %s field: %w", p.FrameNumber, field, err)
+	}
+	return int(n), nil
+}
+
+// SessionStreamIndex returns the stream index of a tracked TCP or UDP
+// session, read from the first of its packets that carries one.
+func SessionStreamIndex(s *packet.Session) (int, error) {
+	s.Mutex.RLock()
+	defer s.Mutex.RUnlock()
+	proto := strings.ToLower(s.Key.Protocol)
+	if proto != "tcp" && proto != "udp" {
+		return 0, fmt.Errorf("cannot follow %s sessions", s.Key.Protocol)
+	}
+	for _, p := range s.Packets {
+		if n, err := PacketStreamIndex(p, proto); err == nil {
+			return n, nil
+		}
+	}
+	return 0, fmt.Errorf("no packet of session %s carries %s", s.Key, streamFields[proto])
+}
+
+// parseFollowYAML parses the YAML report of -z follow,<proto>,yaml:
+//
+//	peers:
+//	  - peer: 0
+//	    host: 10.0.0.1
+//	    port: 58894
+//	packets:
+//	  - packet: 4
+//	    peer: 0
+//	    index: 0
+//	    timestamp: 1589012345.123456789
+//	    data: !!binary |
+//	      R0VUIC8gSFRUUC8xLjENCg==
+func parseFollowYAML(out string) (*FollowedStream, error) {
+	stream := &FollowedStream{}
+	var section string // "peers" or "packets"
+	var peer *Peer
+	var chunk *StreamChunk
+	var data strings.Builder
+	inData := false
+
+	flush := func() error {
+		if chunk == nil {
+			return nil
+		}
+		b, err := base64.StdEncoding.DecodeString(data.String())
+		if err != nil {
+			return fmt.Errorf("failed to decode data of frame %d: %w", chunk.Frame, err)
+		}
+		chunk.Data = b
+		stream.Chunks = append(stream.Chunks, *chunk)
+		chunk = nil
+		data.Reset()
+		return nil
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if inData {
+			if line != "" && !isRule(line) && !strings.Contains(line, ":") {
+				data.WriteString(line)
+				continue
+			}
+			inData = false
+		}
+
+		key, value, ok := strings.Cut(strings.TrimPrefix(line, "- "), ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		var err error
+		switch key {
+		case "peers", "packets":
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			section, peer = key, nil
+		case "peer":
+			n, _ := strconv.Atoi(value)
+			switch {
+			case section == "peers":
+				peer = &stream.Client
+				if n == 1 {
+					peer = &stream.Server
+				}
+			case chunk != nil && n == 1:
+				chunk.Direction = ServerToClient
+			}
+		case "host":
+			if peer != nil {
+				peer.Address = value
+			}
+		case "port":
+			if peer != nil {
+				peer.Port = value
+			}
+		case "packet":
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			chunk = &StreamChunk{}
+			chunk.Frame, err = strconv.Atoi(value)
+		case "timestamp":
+			if chunk != nil {
+				chunk.Time, err = parseEpoch(value)
+			}
+		case "data":
+			inData = chunk != nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse follow output %q: %w", line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return stream, nil
+}
+
+// parseEpoch parses seconds since the epoch with up to nanosecond
+// precision, without the rounding of a float conversion.
+func parseEpoch(s string) (time.Time, error) {
+	sec, frac, _ := strings.Cut(s, ".")
+	secs, err := strconv.ParseInt(sec, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp %q", s)
+	}
+	var nanos int64
+	if frac != "" {
+		frac = (frac + "000000000")[:9]
+		if nanos, err = strconv.ParseInt(frac, 10, 64); err != nil {
+			return time.Time{}, fmt.Errorf("invalid timestamp %q", s)
+		}
+	}
+	return time.Unix(secs, nanos).UTC(), nil
+}
+
+// parseFollowRaw parses the report of -z follow,<proto>,raw, where each
+// chunk is a line of hex, indented by a tab when the server sent it.
+func parseFollowRaw(out string) (*FollowedStream, error) {
+	stream := &FollowedStream{}
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if rest, ok := strings.CutPrefix(line, "Node 0: "); ok {
+			stream.Client.Address, stream.Client.Port = splitEndpoint(rest, true)
+			continue
+		}
+		if rest, ok := strings.CutPrefix(line, "Node 1: "); ok {
+			stream.Server.Address, stream.Server.Port = splitEndpoint(rest, true)
+			continue
+		}
+		text := strings.TrimSpace(line)
+		if text == "" || isRule(text) || strings.Contains(text, ":") {
+			continue
+		}
+		data, err := hex.DecodeString(text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse follow output %q: %w", line, err)
+		}
+		dir := ClientToServer
+		if strings.HasPrefix(line, "\t") {
+			dir = ServerToClient
+		}
+		stream.Chunks = append(stream.Chunks, StreamChunk{Direction: dir, Data: data})
+	}
+	return stream, scanner.Err()
+}
@@ -0,0 +1,139 @@
+package stats
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/p-vbordei/GoShark/packet"
+)
+
+const followYAMLOutput = `
+===================================================================
+Follow: tcp,yaml
+Filter: tcp.stream eq 0
+peers:
+  - peer: 0
+    host: 10.0.0.1
+    port: 58894
+  - peer: 1
+    host: 93.184.216.34
+    port: 80
+packets:
+  - packet: 4
+    peer: 0
+    index: 0
+    timestamp: 1589012345.123456789
+    data: !!binary |
+      R0VUIC8gSFRU
+      UC8xLjENCg==
+  - packet: 6
+    peer: 1
+    index: 0
+    timestamp: 1589012345.2
+    data: !!binary |
+      SFRUUC8xLjEgMjAwIE9LDQo=
+  - packet: 7
+    peer: 0
+    index: 1
+    timestamp: 1589012345.3
+    data: !!binary |
+      DQo=
+===================================================================
+`
+
+func TestParseFollowYAML(t *testing.T) {
+	stream, err := parseFollowYAML(followYAMLOutput)
+	if err != nil {
+		t.Fatalf("parseFollowYAML: %v", err)
+	}
+	if stream.Client != (Peer{"10.0.0.1", "58894"}) || stream.Server != (Peer{"93.184.216.34", "80"}) {
+		t.Errorf("peers = %+v, %+v", stream.Client, stream.Server)
+	}
+	if len(stream.Chunks) != 3 {
+		t.Fatalf("got %d chunks, want 3", len(stream.Chunks))
+	}
+	first := stream.Chunks[0]
+	if first.Frame != 4 || first.Direction != ClientToServer || string(first.Data) != "GET / HTTP/1.1\r\n" {
+		t.Errorf("chunk 0 = %+v", first)
+	}
+	if want := time.Unix(1589012345, 123456789).UTC(); !first.Time.Equal(want) {
+		t.Errorf("chunk 0 time = %s, want %s", first.Time, want)
+	}
+	if stream.Chunks[1].Direction != ServerToClient || stream.Chunks[1].Time.Nanosecond() != 200000000 {
+		t.Errorf("chunk 1 = %+v", stream.Chunks[1])
+	}
+
+	client, _ := io.ReadAll(stream.ClientData())
+	server, _ := io.ReadAll(stream.ServerData())
+	if string(client) != "GET / HTTP/1.1\r\n\r\n" {
+		t.Errorf("client data = %q", client)
+	}
+	if string(server) != "HTTP/1.1 200 OK\r\n" {
+		t.Errorf("server data = %q", server)
+	}
+}
+
+func TestParseFollowRaw(t *testing.T) {
+	out := "===================================================================\n" +
+		"Follow: udp,raw\n" +
+		"Filter: udp.stream eq 2\n" +
+		"Node 0: [2001:db8::1]:5353\n" +
+		"Node 1: 10.0.0.2:53\n" +
+		"68656c6c6f\n" +
+		"\t776f726c64\n" +
+		"===================================================================\n"
+	stream, err := parseFollowRaw(out)
+	if err != nil {
+		t.Fatalf("parseFollowRaw: %v", err)
+	}
+	if stream.Client != (Peer{"[2001:db8::1]", "5353"}) || stream.Server != (Peer{"10.0.0.2", "53"}) {
+		t.Errorf("peers = %+v, %+v", stream.Client, stream.Server)
+	}
+	if len(stream.Chunks) != 2 || string(stream.Chunks[0].Data) != "hello" ||
+		stream.Chunks[1].Direction != ServerToClient || string(stream.Chunks[1].Data) != "world" {
+		t.Errorf("chunks = %+v", stream.Chunks)
+	}
+
+	if _, err := parseFollowRaw("zz\n"); err == nil {
+		t.Error("expected an error for a line that is not hex")
+	}
+}
+
+func TestFollowRejectsUnknownProtocol(t *testing.T) {
+	s := NewFileStats("x.pcap")
+	if _, err := s.Follow(context.Background(), "smtp", 0, FollowYAML); err == nil {
+		t.Error("expected an error for smtp")
+	}
+	if _, err := s.Follow(context.Background(), "tcp", 0, "ascii"); err == nil {
+		t.Error("expected an error for an unsupported mode")
+	}
+}
+
+func TestStreamIndex(t *testing.T) {
+	p := &packet.Packet{
+		FrameNumber: "3",
+		Layers: []packet.Layer{
+			{Name: "ip", Fields: map[string]interface{}{"ip.src": "10.0.0.1", "ip.dst": "10.0.0.2"}},
+			{Name: "tcp", Fields: map[string]interface{}{"tcp.stream": "7", "tcp.srcport": "1234", "tcp.dstport": "80"}},
+		},
+	}
+	for _, proto := range []string{"tcp", "TLS", "http"} {
+		if n, err := PacketStreamIndex(p, proto); err != nil || n != 7 {
+			t.Errorf("PacketStreamIndex(%s) = %d, %v, want 7", proto, n, err)
+		}
+	}
+	if _, err := PacketStreamIndex(p, "udp"); err == nil {
+		t.Error("expected an error for a packet without a udp layer")
+	}
+
+	session := packet.NewSession(packet.SessionKey{Protocol: "tcp"})
+	session.Packets = append(session.Packets, p)
+	if n, err := SessionStreamIndex(session); err != nil || n != 7 {
+		t.Errorf("SessionStreamIndex = %d, %v, want 7", n, err)
+	}
+	if _, err := SessionStreamIndex(packet.NewSession(packet.SessionKey{Protocol: "tcp"})); err == nil {
+		t.Error("expected an error for a session without packets")
+	}
+}
@@ -0,0 +1,216 @@
+package stats
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ProtocolNode is one protocol in the protocol hierarchy (-z io,phs), with
+// the frames and bytes in which it appears below its parent.
+type ProtocolNode struct {
+	Protocol string
+	Frames   int64
+	Bytes    int64
+	Children []*ProtocolNode
+}
+
+// ProtocolHierarchy returns the protocol hierarchy of the capture. The
+// roots are usually a single "eth" or "frame" node.
+func (s *Stats) ProtocolHierarchy(ctx context.Context) ([]*ProtocolNode, error) {
+	out, err := s.runTap(ctx, "io,phs")
+	if err != nil {
+		return nil, err
+	}
+	return parseProtocolHierarchy(out)
+}
+
+func parseProtocolHierarchy(out string) ([]*ProtocolNode, error) {
+	var roots []*ProtocolNode
+	var stack []*ProtocolNode // stack[i] is the open node at depth i.
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.Contains(line, "frames:") {
+			continue
+		}
+		t := newTokens(line)
+		node := &ProtocolNode{Protocol: t.next()}
+		for _, f := range t.fields[1:] {
+			key, value, _ := strings.Cut(f, ":")
+			n, err := parseCount(value)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse protocol hierarchy %q: invalid count %q", line, f)
+			}
+			switch key {
+			case "frames":
+				node.Frames = n
+			case "bytes":
+				node.Bytes = n
+			}
+		}
+
+		// Each level is indented by two spaces.
+		depth := (len(line) - len(strings.TrimLeft(line, " "))) / 2
+		if depth > len(stack) {
+			depth = len(stack)
+		}
+		stack = append(stack[:depth], node)
+		if depth == 0 {
+			roots = append(roots, node)
+		} else {
+			parent := stack[depth-1]
+			parent.Children = append(parent.Children, node)
+		}
+	}
+	return roots, scanner.Err()
+}
+
+// IOStats is an IO statistics series (-z io,stat).
+type IOStats struct {
+	Interval time.Duration
+	Duration time.Duration // The capture's duration.
+	// Columns are the filters of the series' columns; "" is the unfiltered
+	// column tshark adds when no filter is given.
+	Columns   []string
+	Intervals []IOInterval
+}
+
+// IOInterval is one row of an IO statistics series.
+type IOInterval struct {
+	Start time.Duration
+	End   time.Duration
+	// Counts has one entry per column of the series.
+	Counts []IOCount
+}
+
+// IOCount is the frames and bytes a column counted in an interval.
+type IOCount struct {
+	Frames int64
+	Bytes  int64
+}
+
+// IOStat counts frames and bytes per interval, with one column per filter.
+// An interval of 0 reports the whole capture as one interval. The capture's
+// display filter is combined with each column's filter. Column filters may
+// not contain commas, which tshark uses to separate them.
+func (s *Stats) IOStat(ctx context.Context, interval time.Duration, filters ...string) (*IOStats, error) {
+	if interval < 0 {
+		return nil, fmt.Errorf("invalid IO statistics interval %s", interval)
+	}
+	columns := filters
+	if len(columns) == 0 {
+		columns = []string{""}
+	}
+
+	tap, err := s.ioStatTap(interval, columns)
+	if err != nil {
+		return nil, err
+	}
+	out, err := s.Run(ctx, tap)
+	if err != nil {
+		return nil, err
+	}
+	st, err := parseIOStat(out, len(columns))
+	if err != nil {
+		return nil, err
+	}
+	st.Columns = columns
+	return st, nil
+}
+
+// ioStatTap builds the io,stat argument, adding the display filter to each
+// column.
+func (s *Stats) ioStatTap(interval time.Duration, columns []string) (string, error) {
+	tap := "io,stat," + strconv.FormatFloat(interval.Seconds(), 'f', -1, 64)
+	for _, f := range columns {
+		if strings.Contains(f, ",") {
+			return "", fmt.Errorf("IO statistics filter %q contains a comma", f)
+		}
+		switch df := s.capture.DisplayFilter; {
+		case df != "" && f != "":
+			f = "(" + df + ") && (" + f + ")"
+		case df != "":
+			f = df
+		}
+		if f != "" || len(columns) > 1 {
+			tap += "," + f
+		}
+	}
+	return tap, nil
+}
+
+func parseIOStat(out string, columns int) (*IOStats, error) {
+	st := &IOStats{}
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		cell := strings.TrimSpace(strings.Trim(line, "|"))
+		if v, ok := strings.CutPrefix(cell, "Duration:"); ok {
+			d, err := seconds(strings.TrimSuffix(strings.TrimSpace(v), " secs"))
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse IO statistics duration %q", line)
+			}
+			st.Duration = d
+			continue
+		}
+		if v, ok := strings.CutPrefix(cell, "Interval:"); ok {
+			d, err := seconds(strings.TrimSuffix(strings.TrimSpace(v), " secs"))
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse IO statistics interval %q", line)
+			}
+			st.Interval = d
+			continue
+		}
+		if !strings.Contains(line, "<>") {
+			continue
+		}
+
+		var cells []string
+		for _, c := range strings.Split(line, "|") {
+			if c = strings.TrimSpace(c); c != "" {
+				cells = append(cells, c)
+			}
+		}
+		if len(cells) != 1+2*columns {
+			return nil, fmt.Errorf("failed to parse IO statistics row %q: want %d columns", line, columns)
+		}
+		row, err := parseIOInterval(cells[0], st.Duration)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse IO statistics row %q: %w", line, err)
+		}
+		for i := 1; i < len(cells); i += 2 {
+			frames, err := parseCount(cells[i])
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse IO statistics row %q: invalid count %q", line, cells[i])
+			}
+			bytes, err := parseCount(cells[i+1])
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse IO statistics row %q: invalid count %q", line, cells[i+1])
+			}
+			row.Counts = append(row.Counts, IOCount{Frames: frames, Bytes: bytes})
+		}
+		st.Intervals = append(st.Intervals, row)
+	}
+	return st, scanner.Err()
+}
+
+// parseIOInterval parses "start <> end", where the last interval ends at
+// "Dur", the end of the capture.
+func parseIOInterval(cell string, duration time.Duration) (IOInterval, error) {
+	start, end, _ := strings.Cut(cell, "<>")
+	var row IOInterval
+	var err error
+	if row.Start, err = seconds(strings.TrimSpace(start)); err != nil {
+		return row, fmt.Errorf("invalid interval start %q", start)
+	}
+	if end = strings.TrimSpace(end); end == "Dur" {
+		row.End = duration
+	} else if row.End, err = seconds(end); err != nil {
+		return row, fmt.Errorf("invalid interval end %q", end)
+	}
+	return row, nil
+}
@@ -0,0 +1,117 @@
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/p-vbordei/GoShark/capture"
+)
+
+func TestParseProtocolHierarchy(t *testing.T) {
+	out := `
+===================================================================
+Protocol Hierarchy Statistics
+Filter: 
+
+eth                                      frames:5 bytes:1234
+  ip                                     frames:5 bytes:1234
+    tcp                                  frames:4 bytes:1100
+      http                               frames:2 bytes:800
+    udp                                  frames:1 bytes:134
+      dns                                frames:1 bytes:134
+===================================================================
+`
+	roots, err := parseProtocolHierarchy(out)
+	if err != nil {
+		t.Fatalf("parseProtocolHierarchy: %v", err)
+	}
+	if len(roots) != 1 || roots[0].Protocol != "eth" || roots[0].Frames != 5 || roots[0].Bytes != 1234 {
+		t.Fatalf("roots = %+v", roots)
+	}
+	ip := roots[0].Children[0]
+	if ip.Protocol != "ip" || len(ip.Children) != 2 {
+		t.Fatalf("ip = %+v", ip)
+	}
+	tcp, udp := ip.Children[0], ip.Children[1]
+	if tcp.Protocol != "tcp" || tcp.Frames != 4 || tcp.Children[0].Protocol != "http" {
+		t.Errorf("tcp = %+v", tcp)
+	}
+	if udp.Protocol != "udp" || udp.Children[0].Protocol != "dns" || udp.Children[0].Bytes != 134 {
+		t.Errorf("udp = %+v", udp)
+	}
+}
+
+func TestParseIOStat(t *testing.T) {
+	out := `
+=============================================================
+| IO Statistics                                             |
+|                                                           |
+| Duration: 2.5 secs                                        |
+| Interval: 1 secs                                          |
+|                                                           |
+| Col 1: Frames and bytes                                   |
+|     2: tcp                                                |
+|-----------------------------------------------------------|
+|          |1               ||2               |
+| Interval | Frames | Bytes || Frames | Bytes |
+|-------------------------------------------------|
+| 0 <> 1   |      3 |   300 ||      2 |   200 |
+| 1 <> 2   |      1 |    60 ||      0 |     0 |
+| 2 <> Dur |      1 | 1,500 ||      1 |  1500 |
+=============================================================
+`
+	st, err := parseIOStat(out, 2)
+	if err != nil {
+		t.Fatalf("parseIOStat: %v", err)
+	}
+	if st.Interval != time.Second || st.Duration != 2500*time.Millisecond {
+		t.Errorf("interval, duration = %s, %s", st.Interval, st.Duration)
+	}
+	if len(st.Intervals) != 3 {
+		t.Fatalf("got %d intervals, want 3", len(st.Intervals))
+	}
+	last := st.Intervals[2]
+	if last.Start != 2*time.Second || last.End != st.Duration {
+		t.Errorf("last interval = %s <> %s", last.Start, last.End)
+	}
+	if last.Counts[0] != (IOCount{Frames: 1, Bytes: 1500}) || st.Intervals[0].Counts[1] != (IOCount{Frames: 2, Bytes: 200}) {
+		t.Errorf("counts = %+v", st.Intervals)
+	}
+
+	if _, err := parseIOStat(out, 1); err == nil {
+		t.Error("expected an error when the column count does not match")
+	}
+}
+
+func TestIOStatTap(t *testing.T) {
+	tests := []struct {
+		name          string
+		displayFilter string
+		interval      time.Duration
+		columns       []string
+		want          string
+	}{
+		{"unfiltered", "", time.Second, []string{""}, "io,stat,1"},
+		{"fractional interval", "", 100 * time.Millisecond, []string{""}, "io,stat,0.1"},
+		{"columns", "", time.Second, []string{"", "tcp"}, "io,stat,1,,tcp"},
+		{"display filter only", "ip", time.Second, []string{""}, "io,stat,1,ip"},
+		{"display filter and columns", "ip", 0, []string{"tcp", "udp"}, "io,stat,0,(ip) && (tcp),(ip) && (udp)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewFileStats("x.pcap", capture.WithDisplayFilter(tt.displayFilter))
+			got, err := s.ioStatTap(tt.interval, tt.columns)
+			if err != nil {
+				t.Fatalf("ioStatTap: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ioStatTap = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	s := NewFileStats("x.pcap")
+	if _, err := s.ioStatTap(time.Second, []string{"tcp.port in {80,443}"}); err == nil {
+		t.Error("expected an error for a filter containing a comma")
+	}
+}
@@ -0,0 +1,224 @@
+package stats
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/p-vbordei/GoShark/capture"
+)
+
+// ObjectProtocols are the protocols tshark can export objects from.
+var ObjectProtocols = []string{"dicom", "ftp-data", "http", "imf", "smb", "tftp"}
+
+// Object is a file transferred in a capture, as exported by tshark's
+// --export-objects.
+type Object struct {
+	Protocol string
+	// Frame, Hostname and ContentType are only known for HTTP objects;
+	// tshark does not report them for other protocols.
+	Frame       int
+	Hostname    string
+	ContentType string
+	// Filename is the name tshark saved the object under, derived from the
+	// transfer and made safe for the file system.
+	Filename string
+	Size     int64
+	SHA256   string // Hex-encoded digest of the content.
+
+	path string
+}
+
+// Open returns the object's content. It is only valid until the
+// ExportedObjects it belongs to is closed.
+func (o *Object) Open() (io.ReadCloser, error) {
+	return os.Open(o.path)
+}
+
+// ExportedObjects holds the objects exported from a capture in a temporary
+// directory. Close removes the directory.
+type ExportedObjects struct {
+	Objects []*Object
+	dir     string
+}
+
+// Close removes the exported files.
+func (e *ExportedObjects) Close() error {
+	if e.dir == "" {
+		return nil
+	}
+	dir := e.dir
+	e.dir = ""
+	return os.RemoveAll(dir)
+}
+
+// ExportObjects exports the objects proto transferred in the capture file at
+// path; proto is one of ObjectProtocols. Close the result to remove the
+// exported files.
+func ExportObjects(path, proto string, options ...capture.Option) (*ExportedObjects, error) {
+	return NewFileStats(path, options...).ExportObjects(context.Background(), proto)
+}
+
+// ExportObjects exports the objects proto transferred in the capture; see
+// the ExportObjects function. tshark exports objects from every frame, so
+// the display filter does not apply; use a read filter to restrict them.
+func (s *Stats) ExportObjects(ctx context.Context, proto string) (*ExportedObjects, error) {
+	proto = strings.ToLower(proto)
+	if !slices.Contains(ObjectProtocols, proto) {
+		return nil, fmt.Errorf("cannot export %s objects; supported protocols are %s", proto, strings.Join(ObjectProtocols, ", "))
+	}
+	dir, err := os.MkdirTemp("", "goshark-objects-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create export directory: %w", err)
+	}
+	exported := &ExportedObjects{dir: dir}
+
+	// HTTP metadata is read from the frames carrying a message body, in the
+	// same pass as the export.
+	args := []string{"--export-objects", proto + "," + dir}
+	if proto == "http" {
+		args = append(args, "-T", "fields", "-E", "separator=/t", "-E", "occurrence=f",
+			"-e", "frame.number", "-e", "http.host", "-e", "http.response_for.uri",
+			"-e", "http.content_type", "-e", "http.file_data", "-Y", "http.file_data")
+	} else {
+		args = append(args, "-q")
+	}
+	out, err := s.runTShark(ctx, args...)
+	if err != nil {
+		exported.Close()
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		exported.Close()
+		return nil, fmt.Errorf("failed to read export directory: %w", err)
+	}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		obj, err := hashObject(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			exported.Close()
+			return nil, err
+		}
+		obj.Protocol = proto
+		obj.Filename = entry.Name()
+		exported.Objects = append(exported.Objects, obj)
+	}
+
+	if proto == "http" {
+		matchHTTPObjects(exported.Objects, parseHTTPObjectFrames(out))
+	}
+	sort.SliceStable(exported.Objects, func(i, j int) bool {
+		return exported.Objects[i].Frame < exported.Objects[j].Frame
+	})
+	return exported, nil
+}
+
+func hashObject(path string) (*Object, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read exported object: %w", err)
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read exported object: %w", err)
+	}
+	return &Object{Size: n, SHA256: hex.EncodeToString(h.Sum(nil)), path: path}, nil
+}
+
+// httpObjectFrame is the metadata of an HTTP message with a body.
+type httpObjectFrame struct {
+	frame       int
+	hostname    string
+	contentType string
+	size        int64 // -1 when the body could not be decoded.
+	sha256      string
+	used        bool
+}
+
+// parseHTTPObjectFrames parses the tab-separated fields frame.number,
+// http.host, http.response_for.uri, http.content_type and http.file_data.
+// The output is split in memory rather than scanned, so a line holding a
+// large body has no length limit.
+func parseHTTPObjectFrames(out string) []*httpObjectFrame {
+	var frames []*httpObjectFrame
+	for line := range strings.SplitSeq(out, "\n") {
+		cols := strings.Split(strings.TrimSuffix(line, "\r"), "\t")
+		if len(cols) < 5 {
+			continue
+		}
+		n, err := strconv.Atoi(cols[0])
+		if err != nil {
+			continue
+		}
+		f := &httpObjectFrame{frame: n, hostname: cols[1], contentType: cols[3], size: -1}
+		// A response names its host through the URI of its request.
+		if f.hostname == "" {
+			if u, err := url.Parse(cols[2]); err == nil {
+				f.hostname = u.Host
+			}
+		}
+		// tshark prints the body as hex bytes, with or without colons.
+		if body, err := hex.DecodeString(strings.ReplaceAll(cols[4], ":", "")); err == nil {
+			sum := sha256.Sum256(body)
+			f.size = int64(len(body))
+			f.sha256 = hex.EncodeToString(sum[:])
+		}
+		frames = append(frames, f)
+	}
+	return frames
+}
+
+// matchHTTPObjects attaches frame metadata to the exported files. An object
+// is matched by the digest of its content, or failing that by its size
+// when only one remaining message has that size.
+func matchHTTPObjects(objects []*Object, frames []*httpObjectFrame) {
+	var unmatched []*Object
+	for _, obj := range objects {
+		i := slices.IndexFunc(frames, func(f *httpObjectFrame) bool {
+			return !f.used && f.sha256 != "" && f.sha256 == obj.SHA256
+		})
+		if i < 0 {
+			unmatched = append(unmatched, obj)
+			continue
+		}
+		attachHTTPFrame(obj, frames[i])
+	}
+	for _, obj := range unmatched {
+		var match *httpObjectFrame
+		for _, f := range frames {
+			if f.used || f.size != obj.Size {
+				continue
+			}
+			if match != nil {
+				match = nil
+				break
+			}
+			match = f
+		}
+		if match != nil {
+			attachHTTPFrame(obj, match)
+		}
+	}
+}
+
+func attachHTTPFrame(obj *Object, f *httpObjectFrame) {
+	f.used = true
+	obj.Frame = f.frame
+	obj.Hostname = f.hostname
+	obj.ContentType = f.contentType
+}
@@ -0,0 +1,121 @@
+package stats
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func sha(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestParseHTTPObjectFrames(t *testing.T) {
+	out := "4\texample.com\t\tapplication/x-www-form-urlencoded\t613d31\n" +
+		"6\t\thttp://example.com/index.html\ttext/html\t3c:68:31:3e\n" +
+		"9\t\thttp://example.com/logo.png\timage/png\tnot hex\n" +
+		"garbage\n"
+	frames := parseHTTPObjectFrames(out)
+	if len(frames) != 3 {
+		t.Fatalf("got %d frames, want 3", len(frames))
+	}
+	if f := frames[0]; f.frame != 4 || f.hostname != "example.com" || f.size != 3 || f.sha256 != sha("a=1") {
+		t.Errorf("frame 0 = %+v", f)
+	}
+	if f := frames[1]; f.hostname != "example.com" || f.contentType != "text/html" || f.sha256 != sha("<h1>") {
+		t.Errorf("frame 1 = %+v", f)
+	}
+	if f := frames[2]; f.size != -1 || f.sha256 != "" {
+		t.Errorf("frame 2 = %+v", f)
+	}
+}
+
+func TestParseHTTPObjectFramesLargeBody(t *testing.T) {
+	body := strings.Repeat("00", 65<<20)
+	out := "4\texample.com\t\tapplication/octet-stream\t" + body + "\n" +
+		"6\t\thttp://example.org/a.css\ttext/css\t70:7b\n"
+	frames := parseHTTPObjectFrames(out)
+	if len(frames) != 2 {
+		t.Fatalf("got %d frames, want 2", len(frames))
+	}
+	if f := frames[0]; f.size != 65<<20 {
+		t.Errorf("frame 0 size = %d", f.size)
+	}
+	if f := frames[1]; f.frame != 6 || f.hostname != "example.org" || f.contentType != "text/css" {
+		t.Errorf("frame after a large body = %+v", f)
+	}
+}
+
+func TestMatchHTTPObjects(t *testing.T) {
+	frames := []*httpObjectFrame{
+		{frame: 6, hostname: "a.example", contentType: "text/html", size: 4, sha256: sha("<h1>")},
+		{frame: 8, hostname: "b.example", contentType: "text/css", size: 2, sha256: sha("p{")},
+		{frame: 9, hostname: "c.example", contentType: "image/png", size: -1},
+	}
+	byHash := &Object{Size: 4, SHA256: sha("<h1>")}
+	bySize := &Object{Size: 2, SHA256: sha("xx")}
+	unknown := &Object{Size: 100, SHA256: sha("?")}
+	matchHTTPObjects([]*Object{bySize, byHash, unknown}, frames)
+
+	if byHash.Frame != 6 || byHash.Hostname != "a.example" || byHash.ContentType != "text/html" {
+		t.Errorf("hash match = %+v", byHash)
+	}
+	if bySize.Frame != 8 || bySize.ContentType != "text/css" {
+		t.Errorf("size match = %+v", bySize)
+	}
+	if unknown.Frame != 0 || unknown.Hostname != "" {
+		t.Errorf("unmatched object = %+v", unknown)
+	}
+
+	// Two candidates of the same size are ambiguous.
+	frames = []*httpObjectFrame{{frame: 1, size: 2}, {frame: 2, size: 2}}
+	obj := &Object{Size: 2}
+	matchHTTPObjects([]*Object{obj}, frames)
+	if obj.Frame != 0 {
+		t.Errorf("ambiguous size matched frame %d", obj.Frame)
+	}
+}
+
+func TestExportedObjectsClose(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "index.html")
+	if err := os.WriteFile(path, []byte("<h1>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	obj, err := hashObject(path)
+	if err != nil {
+		t.Fatalf("hashObject: %v", err)
+	}
+	if obj.Size != 4 || obj.SHA256 != sha("<h1>") {
+		t.Errorf("object = %+v", obj)
+	}
+	rc, err := obj.Open()
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	data, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(data) != "<h1>" {
+		t.Errorf("content = %q", data)
+	}
+
+	exported := &ExportedObjects{Objects: []*Object{obj}, dir: dir}
+	if err := exported.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("export directory still exists after Close: %v", err)
+	}
+}
+
+func TestExportObjectsRejectsUnknownProtocol(t *testing.T) {
+	if _, err := NewFileStats("x.pcap").ExportObjects(context.Background(), "ssh"); err == nil {
+		t.Error("expected an error for ssh")
+	}
+}
@@ -0,0 +1,114 @@
+package stats
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RTPStream is one row of the RTP streams report (-z rtp,streams).
+type RTPStream struct {
+	Start      time.Duration
+	End        time.Duration
+	SrcAddress string
+	SrcPort    string
+	DstAddress string
+	DstPort    string
+	SSRC       string // In hex, as tshark prints it, e.g. 0x12345678.
+	Payload    string
+	Packets    int64
+	// Lost is the number of packets missing from the sequence; it is
+	// negative when duplicates were received.
+	Lost        int64
+	LostPercent float64
+	// Delta and jitter statistics, in milliseconds.
+	MinDelta   float64
+	MeanDelta  float64
+	MaxDelta   float64
+	MinJitter  float64
+	MeanJitter float64
+	MaxJitter  float64
+	// Problem is set when tshark flagged the stream, e.g. for sequence
+	// errors or wrong timestamps.
+	Problem bool
+}
+
+// RTPStreams returns the capture's RTP streams. The rtp,streams tap takes
+// no filter, so the capture's display filter does not apply; use a read
+// filter to restrict the frames analysed.
+func (s *Stats) RTPStreams(ctx context.Context) ([]RTPStream, error) {
+	out, err := s.Run(ctx, "rtp,streams")
+	if err != nil {
+		return nil, err
+	}
+	return parseRTPStreams(out)
+}
+
+func parseRTPStreams(out string) ([]RTPStream, error) {
+	var streams []RTPStream
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		fields := strings.Fields(line)
+		// Data rows start with the start time; the heading with "Start".
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "=") || strings.HasPrefix(fields[0], "Start") {
+			continue
+		}
+		stream, err := parseRTPStream(fields)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse RTP stream %q: %w", line, err)
+		}
+		streams = append(streams, stream)
+	}
+	return streams, scanner.Err()
+}
+
+// parseRTPStream parses the fixed columns from both ends of a row, leaving
+// the payload name, which may contain spaces, in between.
+func parseRTPStream(fields []string) (RTPStream, error) {
+	var st RTPStream
+	if n := len(fields); n > 0 && fields[n-1] == "X" {
+		st.Problem = true
+		fields = fields[:n-1]
+	}
+	const lead, tail = 7, 9 // Pkts, Lost "(x%)" and six delta/jitter values.
+	if len(fields) < lead+1+tail {
+		return st, fmt.Errorf("row ends early")
+	}
+
+	head := &tokens{fields: fields[:lead]}
+	st.Start = head.seconds()
+	st.End = head.seconds()
+	st.SrcAddress, st.SrcPort = head.next(), head.next()
+	st.DstAddress, st.DstPort = head.next(), head.next()
+	st.SSRC = head.next()
+	if head.err != nil {
+		return st, head.err
+	}
+
+	rest := fields[len(fields)-tail:]
+	st.Payload = strings.Join(fields[lead:len(fields)-tail], " ")
+	t := &tokens{fields: rest}
+	st.Packets = t.count()
+	st.Lost = t.count()
+	percent := strings.Trim(t.next(), "()")
+	for _, v := range []*float64{&st.MinDelta, &st.MeanDelta, &st.MaxDelta, &st.MinJitter, &st.MeanJitter, &st.MaxJitter} {
+		s := t.next()
+		f, err := parseFloat(s)
+		if err != nil && t.err == nil {
+			t.err = fmt.Errorf("invalid value %q", s)
+		}
+		*v = f
+	}
+	if t.err != nil {
+		return st, t.err
+	}
+	f, err := parseFloat(percent)
+	if err != nil {
+		return st, fmt.Errorf("invalid loss %q", percent)
+	}
+	st.LostPercent = f
+	return st, nil
+}
@@ -0,0 +1,37 @@
+package stats
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRTPStreams(t *testing.T) {
+	out := `========================= RTP Streams ========================
+   Start time      End time     Src IP addr  Port    Dest IP addr  Port       SSRC          Payload  Pkts         Lost   Min Delta(ms)  Mean Delta(ms)   Max Delta(ms)  Min Jitter(ms) Mean Jitter(ms)  Max Jitter(ms) Problem?
+     0.000000     19.980000     10.0.0.1  5004     10.0.0.2  5004 0x12345678   g711U   1000     0 (0.0%)          19.000          20.000          21.000           0.010           0.020           0.100
+     1.000000      2.000000     10.0.0.2  5006     10.0.0.1  5006 0x0000abcd ITU-T G.722     50    -2 (-4.0%)          18.500          20.000          40.000           0.000           1.250           9.500 X
+==============================================================
+`
+	streams, err := parseRTPStreams(out)
+	if err != nil {
+		t.Fatalf("parseRTPStreams: %v", err)
+	}
+	if len(streams) != 2 {
+		t.Fatalf("got %d streams, want 2", len(streams))
+	}
+	want := RTPStream{
+		End:        19980 * time.Millisecond,
+		SrcAddress: "10.0.0.1", SrcPort: "5004",
+		DstAddress: "10.0.0.2", DstPort: "5004",
+		SSRC: "0x12345678", Payload: "g711U", Packets: 1000,
+		MinDelta: 19, MeanDelta: 20, MaxDelta: 21,
+		MinJitter: 0.01, MeanJitter: 0.02, MaxJitter: 0.1,
+	}
+	if streams[0] != want {
+		t.Errorf("stream 0 = %+v, want %+v", streams[0], want)
+	}
+	s := streams[1]
+	if s.Payload != "ITU-T G.722" || s.Lost != -2 || s.LostPercent != -4 || !s.Problem || s.MaxJitter != 9.5 {
+		t.Errorf("stream 1 = %+v", s)
+	}
+}
@@ -0,0 +1,255 @@
+// Package stats runs Wireshark's statistics taps (tshark -z) against a
+// capture file or pipe and parses their text reports into Go values:
+// conversation and endpoint tables, the protocol hierarchy, IO interval
+// series, expert information, stats trees and RTP streams. It also follows
+// streams and exports transferred objects, which tshark runs the same way.
+//
+// A Stats takes the same options as a capture, so read filters, two-pass
+// mode, enabled and disabled dissectors, decode-as rules and preferences
+// apply to the statistics as they would to decoded packets. The display
+// filter is passed to each tap as its own filter, because tshark does not
+// apply -Y to taps.
+//
+//	st := stats.NewFileStats("web.pcapng", capture.WithDisplayFilter("tcp.port == 443"))
+//	convs, err := st.Conversations(ctx, "tcp")
+package stats
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/p-vbordei/GoShark/capture"
+	"github.com/p-vbordei/GoShark/pcapfile"
+	"github.com/p-vbordei/GoShark/tshark"
+)
+
+// Stats runs statistics taps against one capture file. Each call starts its
+// own tshark process, so methods may be called concurrently.
+type Stats struct {
+	path    string
+	spooled bool // path is a temporary copy of a pipe, removed by Close.
+	capture *capture.Capture
+}
+
+// NewFileStats returns a Stats reading the capture file at path. Capture
+// options that affect dissection (filters, decode-as rules, dissectors,
+// preferences, the tshark path) are honoured; output options are ignored.
+func NewFileStats(path string, options ...capture.Option) *Stats {
+	return &Stats{path: path, capture: capture.NewCapture(options...)}
+}
+
+// NewPipeStats reads a pcap or pcapng stream from r into a temporary file,
+// named after the stream's format, so several taps can be run over it. Call
+// Close to remove the file.
+func NewPipeStats(r io.Reader, options ...capture.Option) (*Stats, error) {
+	br := bufio.NewReader(r)
+	pattern := "goshark-stats-*"
+	if format := peekFormat(br); format != pcapfile.FormatUnknown {
+		pattern += "." + format.String()
+	}
+	f, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary capture file: %w", err)
+	}
+	if _, err := io.Copy(f, br); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, fmt.Errorf("failed to write temporary capture file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return nil, fmt.Errorf("failed to write temporary capture file: %w", err)
+	}
+	return &Stats{path: f.Name(), spooled: true, capture: capture.NewCapture(options...)}, nil
+}
+
+// peekFormat detects the capture format of the stream without consuming it.
+func peekFormat(r *bufio.Reader) pcapfile.Format {
+	magic, _ := r.Peek(4)
+	rd, err := pcapfile.NewReader(bytes.NewReader(magic))
+	if err != nil {
+		return pcapfile.FormatUnknown
+	}
+	return rd.Format()
+}
+
+// Close removes the temporary file of a Stats created by NewPipeStats. It is
+// a no-op for file statistics.
+func (s *Stats) Close() error {
+	if !s.spooled {
+		return nil
+	}
+	s.spooled = false
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Run runs tshark with the given -z taps in one pass over the capture and
+// returns their combined text report. It is the escape hatch for taps this
+// package does not parse; the capture's display filter is not added.
+func (s *Stats) Run(ctx context.Context, taps ...string) (string, error) {
+	if len(taps) == 0 {
+		return "", fmt.Errorf("no statistics taps given")
+	}
+	args := []string{"-q"}
+	for _, tap := range taps {
+		args = append(args, "-z", tap)
+	}
+	return s.runTShark(ctx, args...)
+}
+
+// runTShark runs tshark over the capture with its dissection options and
+// args, and returns its standard output.
+func (s *Stats) runTShark(ctx context.Context, args ...string) (string, error) {
+	tsharkPath, err := tshark.GetTSharkPath(s.capture.TSharkPath)
+	if err != nil {
+		return "", err
+	}
+	dissectionArgs, err := s.capture.DissectionArgs()
+	if err != nil {
+		return "", err
+	}
+
+	cmdArgs := append([]string{"-n", "-r", s.path}, dissectionArgs...)
+	cmdArgs = append(cmdArgs, args...)
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, tsharkPath, cmdArgs...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("failed to run tshark %s: %w: %s", strings.Join(args, " "), err, msg)
+		}
+		return "", fmt.Errorf("failed to run tshark %s: %w", strings.Join(args, " "), err)
+	}
+	return stdout.String(), nil
+}
+
+// runTap runs a single tap with the capture's display filter appended as
+// the tap's filter argument.
+func (s *Stats) runTap(ctx context.Context, tap string) (string, error) {
+	return s.Run(ctx, s.withFilter(tap))
+}
+
+func (s *Stats) withFilter(tap string) string {
+	if s.capture.DisplayFilter == "" {
+		return tap
+	}
+	return tap + "," + s.capture.DisplayFilter
+}
+
+// parseCount parses an integer as tshark prints it, possibly with
+// thousands separators.
+func parseCount(s string) (int64, error) {
+	return strconv.ParseInt(strings.ReplaceAll(s, ",", ""), 10, 64)
+}
+
+// parseFloat parses a decimal as tshark prints it, ignoring a trailing %.
+func parseFloat(s string) (float64, error) {
+	s = strings.TrimSuffix(strings.ReplaceAll(s, ",", ""), "%")
+	return strconv.ParseFloat(s, 64)
+}
+
+// seconds converts a count of seconds to a Duration.
+func seconds(s string) (time.Duration, error) {
+	f, err := parseFloat(s)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(f * float64(time.Second)), nil
+}
+
+// sizeUnits are the units recent tshark releases append to byte counts.
+// Counts printed with a unit above bytes are rounded by tshark.
+var sizeUnits = map[string]float64{
+	"bytes": 1, "B": 1,
+	"kB": 1e3, "MB": 1e6, "GB": 1e9, "TB": 1e12,
+	"KiB": 1 << 10, "MiB": 1 << 20, "GiB": 1 << 30, "TiB": 1 << 40,
+}
+
+// tokens reads the whitespace-separated cells of a report row.
+type tokens struct {
+	fields []string
+	pos    int
+	err    error
+}
+
+func newTokens(line string) *tokens {
+	return &tokens{fields: strings.Fields(line)}
+}
+
+func (t *tokens) next() string {
+	if t.pos >= len(t.fields) {
+		if t.err == nil {
+			t.err = fmt.Errorf("row ends early")
+		}
+		return ""
+	}
+	t.pos++
+	return t.fields[t.pos-1]
+}
+
+func (t *tokens) count() int64 {
+	s := t.next()
+	if t.err != nil {
+		return 0
+	}
+	n, err := parseCount(s)
+	if err != nil && t.err == nil {
+		t.err = fmt.Errorf("invalid count %q", s)
+	}
+	return n
+}
+
+// size reads a byte count and the unit following it, if any.
+func (t *tokens) size() int64 {
+	s := t.next()
+	if t.err != nil {
+		return 0
+	}
+	f, err := parseFloat(s)
+	if err != nil {
+		t.err = fmt.Errorf("invalid byte count %q", s)
+		return 0
+	}
+	if t.pos < len(t.fields) {
+		if mult, ok := sizeUnits[t.fields[t.pos]]; ok {
+			t.pos++
+			f *= mult
+		}
+	}
+	return int64(f)
+}
+
+func (t *tokens) seconds() time.Duration {
+	s := t.next()
+	if t.err != nil {
+		return 0
+	}
+	d, err := seconds(s)
+	if err != nil && t.err == nil {
+		t.err = fmt.Errorf("invalid time %q", s)
+	}
+	return d
+}
+
+// isRule reports whether line is one of the ===== or ----- lines framing a
+// report.
+func isRule(line string) bool {
+	line = strings.TrimSpace(line)
+	return line != "" && strings.Trim(line, "=-") == ""
+}
@@ -0,0 +1,100 @@
+package stats
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/p-vbordei/GoShark/capture"
+	"github.com/p-vbordei/GoShark/tshark"
+)
+
+func TestWithFilter(t *testing.T) {
+	if got := NewFileStats("x.pcap").withFilter("conv,tcp"); got != "conv,tcp" {
+		t.Errorf("withFilter without a display filter = %q", got)
+	}
+	s := NewFileStats("x.pcap", capture.WithDisplayFilter("tcp.port == 80"))
+	if got := s.withFilter("conv,tcp"); got != "conv,tcp,tcp.port == 80" {
+		t.Errorf("withFilter = %q", got)
+	}
+}
+
+func TestPipeStatsSpoolsInput(t *testing.T) {
+	s, err := NewPipeStats(strings.NewReader("capture data"))
+	if err != nil {
+		t.Fatalf("NewPipeStats: %v", err)
+	}
+	data, err := os.ReadFile(s.path)
+	if err != nil || string(data) != "capture data" {
+		t.Fatalf("spooled file = %q, %v", data, err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if _, err := os.Stat(s.path); !os.IsNotExist(err) {
+		t.Errorf("spooled file still exists after Close: %v", err)
+	}
+	if filepath.Ext(s.path) != "" {
+		t.Errorf("spooled file %s of an unknown format has an extension", s.path)
+	}
+
+	pcap := []byte{0xd4, 0xc3, 0xb2, 0xa1, 2, 0, 4, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xff, 0, 0, 1, 0, 0, 0}
+	shb := []byte{
+		0x0a, 0x0d, 0x0d, 0x0a, 28, 0, 0, 0, 0x4d, 0x3c, 0x2b, 0x1a, 1, 0, 0, 0,
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 28, 0, 0, 0,
+	}
+	for ext, data := range map[string][]byte{".pcap": pcap, ".pcapng": shb} {
+		s, err := NewPipeStats(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("NewPipeStats: %v", err)
+		}
+		if filepath.Ext(s.path) != ext {
+			t.Errorf("%s stream spooled as %s", ext, s.path)
+		}
+		spooled, err := os.ReadFile(s.path)
+		if err != nil || !bytes.Equal(spooled, data) {
+			t.Errorf("spooled %s stream differs: %v", ext, err)
+		}
+		s.Close()
+	}
+}
+
+func TestStatsAgainstTShark(t *testing.T) {
+	if _, err := tshark.FindTShark(); err != nil {
+		t.Skip("tshark not found")
+	}
+	ctx := context.Background()
+	s := NewFileStats("../test.pcap")
+
+	roots, err := s.ProtocolHierarchy(ctx)
+	if err != nil {
+		t.Fatalf("ProtocolHierarchy: %v", err)
+	}
+	if len(roots) == 0 || roots[0].Frames != 5 {
+		t.Errorf("protocol hierarchy roots = %+v, want 5 frames", roots)
+	}
+
+	if _, err := s.Conversations(ctx, "ip"); err != nil {
+		t.Errorf("Conversations: %v", err)
+	}
+	if _, err := s.Endpoints(ctx, "tcp"); err != nil {
+		t.Errorf("Endpoints: %v", err)
+	}
+	io, err := s.IOStat(ctx, 0)
+	if err != nil {
+		t.Fatalf("IOStat: %v", err)
+	}
+	var frames int64
+	for _, iv := range io.Intervals {
+		frames += iv.Counts[0].Frames
+	}
+	if frames != 5 {
+		t.Errorf("IOStat counted %d frames, want 5", frames)
+	}
+	if _, err := s.Expert(ctx); err != nil {
+		t.Errorf("Expert: %v", err)
+	}
+}
@@ -0,0 +1,175 @@
+package stats
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Tree is the report of a stats_tree tap such as http,tree or dns,tree.
+type Tree struct {
+	Title string
+	Nodes []*TreeNode
+}
+
+// TreeNode is one item of a stats tree. Columns a tree does not fill, such
+// as Average for plain counters, are zero.
+type TreeNode struct {
+	Topic   string
+	Count   int64
+	Average float64
+	Min     float64
+	Max     float64
+	// Rate is the item's rate in occurrences per millisecond.
+	Rate float64
+	// Percent is the item's share of its parent's count.
+	Percent float64
+	// BurstRate is the highest rate seen over the burst window, which
+	// started at BurstStart seconds into the capture.
+	BurstRate  float64
+	BurstStart float64
+	Children   []*TreeNode
+}
+
+// Find returns the first node named topic in the tree, searching depth
+// first, or nil.
+func (t *Tree) Find(topic string) *TreeNode {
+	return findNode(t.Nodes, topic)
+}
+
+func findNode(nodes []*TreeNode, topic string) *TreeNode {
+	for _, n := range nodes {
+		if n.Topic == topic {
+			return n
+		}
+		if found := findNode(n.Children, topic); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// HTTPTree returns the http,tree report: HTTP requests by method and
+// responses by status code.
+func (s *Stats) HTTPTree(ctx context.Context) (*Tree, error) {
+	return s.Tree(ctx, "http,tree")
+}
+
+// DNSTree returns the dns,tree report: queries and responses by type,
+// class and response code, with response times.
+func (s *Stats) DNSTree(ctx context.Context) (*Tree, error) {
+	return s.Tree(ctx, "dns,tree")
+}
+
+// Tree runs a stats_tree tap, e.g. "http,tree", "http_req,tree" or
+// "dns,tree", and parses its report.
+func (s *Stats) Tree(ctx context.Context, tap string) (*Tree, error) {
+	out, err := s.runTap(ctx, tap)
+	if err != nil {
+		return nil, err
+	}
+	return parseTree(out)
+}
+
+// treeColumns are the headings of a stats_tree report after "Topic / Item".
+// Older tshark releases print only some of them.
+var treeColumns = []string{"Count", "Average", "Min Val", "Max Val", "Rate (ms)", "Percent", "Burst Rate", "Burst Start"}
+
+func parseTree(out string) (*Tree, error) {
+	tree := &Tree{}
+	var starts []int // Offset of each treeColumns heading, -1 if absent.
+	var stack []*TreeNode
+	prev := ""
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "Topic / Item"):
+			tree.Title = strings.TrimSuffix(strings.TrimSpace(prev), ":")
+			starts = make([]int, len(treeColumns))
+			for i, h := range treeColumns {
+				starts[i] = strings.Index(line, h)
+			}
+			prev = line
+			continue
+		case starts == nil || isRule(line) || strings.TrimSpace(line) == "":
+			prev = line
+			continue
+		}
+
+		node, err := parseTreeRow(line, starts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse stats tree row %q: %w", line, err)
+		}
+		// Each level is indented by one space.
+		depth := len(line) - len(strings.TrimLeft(line, " "))
+		if depth > len(stack) {
+			depth = len(stack)
+		}
+		stack = append(stack[:depth], node)
+		if depth == 0 {
+			tree.Nodes = append(tree.Nodes, node)
+		} else {
+			parent := stack[depth-1]
+			parent.Children = append(parent.Children, node)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if starts == nil {
+		return nil, fmt.Errorf("no stats tree in tshark output")
+	}
+	return tree, nil
+}
+
+// parseTreeRow cuts a row at the column offsets of the heading line.
+func parseTreeRow(line string, starts []int) (*TreeNode, error) {
+	if starts[0] < 0 {
+		return nil, fmt.Errorf("no Count column")
+	}
+	node := &TreeNode{Topic: strings.TrimSpace(cell(line, 0, starts[0]))}
+	values := []*float64{nil, &node.Average, &node.Min, &node.Max, &node.Rate, &node.Percent, &node.BurstRate, &node.BurstStart}
+	for i, start := range starts {
+		if start < 0 {
+			continue
+		}
+		end := len(line)
+		for _, next := range starts[i+1:] {
+			if next > start {
+				end = next
+				break
+			}
+		}
+		text := strings.TrimSpace(cell(line, start, end))
+		if text == "" || text == "-" {
+			continue
+		}
+		if i == 0 {
+			n, err := parseCount(text)
+			if err != nil {
+				return nil, fmt.Errorf("invalid count %q", text)
+			}
+			node.Count = n
+			continue
+		}
+		f, err := parseFloat(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q", treeColumns[i], text)
+		}
+		*values[i] = f
+	}
+	return node, nil
+}
+
+// cell returns line[start:end], clipped to the line's length.
+func cell(line string, start, end int) string {
+	if start >= len(line) {
+		return ""
+	}
+	if end > len(line) {
+		end = len(line)
+	}
+	return line[start:end]
+}
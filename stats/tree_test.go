@@ -0,0 +1,70 @@
+package stats
+
+import "testing"
+
+const httpTreeOutput = `
+===================================================================================================================================
+HTTP/Packet Counter:
+Topic / Item            Count         Average       Min Val       Max Val       Rate (ms)     Percent       Burst Rate    Burst Start  
+-----------------------------------------------------------------------------------------------------------------------------------
+Total HTTP Packets      24                                                      0.0024        100%          0.0300        0.161        
+ HTTP Request Packets   12                                                      0.0012        50.00%        0.0200        0.161        
+  GET                   12                                                      0.0012        100.00%       0.0200        0.161        
+ HTTP Response Packets  12                                                      0.0012        50.00%        0.0100        0.202        
+  2xx: Success          12                                                      0.0012        100.00%       0.0100        0.202        
+   200 OK               12                                                      0.0012        100.00%       0.0100        0.202        
+  ???: broken           0                                                       0.0000        0.00%         -             -            
+ Other HTTP Packets     0                                                       0.0000        0.00%         -             -            
+-----------------------------------------------------------------------------------------------------------------------------------
+`
+
+func TestParseTree(t *testing.T) {
+	tree, err := parseTree(httpTreeOutput)
+	if err != nil {
+		t.Fatalf("parseTree: %v", err)
+	}
+	if tree.Title != "HTTP/Packet Counter" {
+		t.Errorf("Title = %q", tree.Title)
+	}
+	if len(tree.Nodes) != 1 || tree.Nodes[0].Topic != "Total HTTP Packets" || tree.Nodes[0].Count != 24 {
+		t.Fatalf("Nodes = %+v", tree.Nodes)
+	}
+	if n := len(tree.Nodes[0].Children); n != 3 {
+		t.Errorf("root has %d children, want 3", n)
+	}
+
+	ok := tree.Find("200 OK")
+	if ok == nil {
+		t.Fatal(`Find("200 OK") = nil`)
+	}
+	if ok.Count != 12 || ok.Rate != 0.0012 || ok.Percent != 100 || ok.BurstRate != 0.01 || ok.BurstStart != 0.202 {
+		t.Errorf("200 OK = %+v", ok)
+	}
+	resp := tree.Find("HTTP Response Packets")
+	if len(resp.Children) != 2 || resp.Children[1].Topic != "???: broken" {
+		t.Errorf("responses = %+v", resp.Children)
+	}
+	if tree.Find("missing") != nil {
+		t.Error(`Find("missing") != nil`)
+	}
+}
+
+func TestParseTreeValues(t *testing.T) {
+	out := "DNS:\n" +
+		"Topic / Item                    Count  Average  Min Val  Max Val\n" +
+		"-----------------------------------------------------------------\n" +
+		"Total Packets                   4\n" +
+		" request-response time (msec)   2      12.345   1.000    23.690\n"
+	tree, err := parseTree(out)
+	if err != nil {
+		t.Fatalf("parseTree: %v", err)
+	}
+	rt := tree.Find("request-response time (msec)")
+	if rt == nil || rt.Count != 2 || rt.Average != 12.345 || rt.Min != 1 || rt.Max != 23.69 {
+		t.Errorf("response time = %+v", rt)
+	}
+
+	if _, err := parseTree("tshark: no such tap\n"); err == nil {
+		t.Error("expected an error for output without a tree")
+	}
+}
@@ -11,74 +11,120 @@ type FieldMapping struct {
 	LayerName  string
 	FieldName  string
 	TargetType string
+	ESType     string // Elasticsearch type from tshark -G elastic-mapping ("" for built-in defaults)
 }
 
 // FieldMappings is a collection of field mappings
 type FieldMappings struct {
 	mappings []FieldMapping
+	byLayer  map[string]int // "layer\x00field" -> index into mappings
+	byField  map[string]int // "field" -> index of the first mapping for that field
 }
 
 // Default mappings for common fields
 var defaultMappings = []FieldMapping{
-	{"frame", "frame_time_epoch", "timestamp"},
-	{"frame", "frame_time_relative", "float"},
-	{"frame", "frame_len", "int"},
-	{"frame", "frame_cap_len", "int"},
-	{"frame", "frame_marked", "bool"},
-	{"frame", "frame_ignored", "bool"},
-	
-	{"ip", "ip_version", "int"},
-	{"ip", "ip_hdr_len", "int"},
-	{"ip", "ip_dsfield_dscp", "int"},
-	{"ip", "ip_len", "int"},
-	{"ip", "ip_id", "int"},
-	{"ip", "ip_flags", "int"},
-	{"ip", "ip_ttl", "int"},
-	{"ip", "ip_proto", "int"},
-	{"ip", "ip_checksum", "int"},
-	
-	{"tcp", "tcp_srcport", "int"},
-	{"tcp", "tcp_dstport", "int"},
-	{"tcp", "tcp_seq", "int"},
-	{"tcp", "tcp_ack", "int"},
-	{"tcp", "tcp_hdr_len", "int"},
-	{"tcp", "tcp_flags", "int"},
-	{"tcp", "tcp_window_size", "int"},
-	{"tcp", "tcp_checksum", "int"},
-	{"tcp", "tcp_urgent_pointer", "int"},
-	
-	{"udp", "udp_srcport", "int"},
-	{"udp", "udp_dstport", "int"},
-	{"udp", "udp_length", "int"},
-	{"udp", "udp_checksum", "int"},
-	
-	{"dns", "dns_id", "int"},
-	{"dns", "dns_flags", "int"},
-	{"dns", "dns_count_queries", "int"},
-	{"dns", "dns_count_answers", "int"},
-	{"dns", "dns_count_auth_rr", "int"},
-	{"dns", "dns_count_add_rr", "int"},
-	
-	{"http", "http_response_code", "int"},
-	{"http", "http_content_length", "int"},
+	{"frame", "frame_time_epoch", "timestamp", ""},
+	{"frame", "frame_time_relative", "float", ""},
+	{"frame", "frame_len", "int", ""},
+	{"frame", "frame_cap_len", "int", ""},
+	{"frame", "frame_marked", "bool", ""},
+	{"frame", "frame_ignored", "bool", ""},
+
+	{"ip", "ip_version", "int", ""},
+	{"ip", "ip_hdr_len", "int", ""},
+	{"ip", "ip_dsfield_dscp", "int", ""},
+	{"ip", "ip_len", "int", ""},
+	{"ip", "ip_id", "int", ""},
+	{"ip", "ip_flags", "int", ""},
+	{"ip", "ip_ttl", "int", ""},
+	{"ip", "ip_proto", "int", ""},
+	{"ip", "ip_checksum", "int", ""},
+
+	{"tcp", "tcp_srcport", "int", ""},
+	{"tcp", "tcp_dstport", "int", ""},
+	{"tcp", "tcp_seq", "int", ""},
+	{"tcp", "tcp_ack", "int", ""},
+	{"tcp", "tcp_hdr_len", "int", ""},
+	{"tcp", "tcp_flags", "int", ""},
+	{"tcp", "tcp_window_size", "int", ""},
+	{"tcp", "tcp_checksum", "int", ""},
+	{"tcp", "tcp_urgent_pointer", "int", ""},
+
+	{"udp", "udp_srcport", "int", ""},
+	{"udp", "udp_dstport", "int", ""},
+	{"udp", "udp_length", "int", ""},
+	{"udp", "udp_checksum", "int", ""},
+
+	{"dns", "dns_id", "int", ""},
+	{"dns", "dns_flags", "int", ""},
+	{"dns", "dns_count_queries", "int", ""},
+	{"dns", "dns_count_answers", "int", ""},
+	{"dns", "dns_count_auth_rr", "int", ""},
+	{"dns", "dns_count_add_rr", "int", ""},
+
+	{"http", "http_response_code", "int", ""},
+	{"http", "http_content_length", "int", ""},
 }
 
+// defaultFieldMappings is shared by the package-level CastFieldValue so that
+// casting a field does not rebuild the default lookup tables on every call.
+var defaultFieldMappings = NewFieldMappings()
+
 // NewFieldMappings creates a new field mappings instance
 func NewFieldMappings() *FieldMappings {
+	m := newEmptyFieldMappings()
+	for _, mapping := range defaultMappings {
+		m.AddMapping(mapping.LayerName, mapping.FieldName, mapping.TargetType)
+	}
+	return m
+}
+
+// newEmptyFieldMappings creates a field mappings instance without the defaults.
+func newEmptyFieldMappings() *FieldMappings {
 	return &FieldMappings{
-		mappings: defaultMappings,
+		byLayer: make(map[string]int),
+		byField: make(map[string]int),
 	}
 }
 
 // AddMapping adds a new field mapping
 func (m *FieldMappings) AddMapping(layerName, fieldName, targetType string) {
-	m.mappings = append(m.mappings, FieldMapping{
+	m.add(FieldMapping{
 		LayerName:  layerName,
 		FieldName:  fieldName,
 		TargetType: targetType,
 	})
 }
 
+// add stores a mapping, replacing an existing one for the same layer and field.
+func (m *FieldMappings) add(mapping FieldMapping) {
+	mapping.LayerName = strings.ToLower(mapping.LayerName)
+	mapping.FieldName = strings.ToLower(mapping.FieldName)
+
+	key := mapping.LayerName + "\x00" + mapping.FieldName
+	if i, ok := m.byLayer[key]; ok {
+		m.mappings[i] = mapping
+		return
+	}
+	m.mappings = append(m.mappings, mapping)
+	m.byLayer[key] = len(m.mappings) - 1
+	if _, ok := m.byField[mapping.FieldName]; !ok {
+		m.byField[mapping.FieldName] = len(m.mappings) - 1
+	}
+}
+
+// Len returns the number of field mappings.
+func (m *FieldMappings) Len() int {
+	return len(m.mappings)
+}
+
+// Mappings returns a copy of all field mappings in insertion order.
+func (m *FieldMappings) Mappings() []FieldMapping {
+	out := make([]FieldMapping, len(m.mappings))
+	copy(out, m.mappings)
+	return out
+}
+
 // GetMapping gets the mapping for a field
 func (m *FieldMappings) GetMapping(layerName, fieldName string) (string, bool) {
 	// Normalize names
@@ -86,33 +132,45 @@ func (m *FieldMappings) GetMapping(layerName, fieldName string) (string, bool) {
 	fieldName = strings.ToLower(fieldName)
 
 	// Check for exact match
-	for _, mapping := range m.mappings {
-		if mapping.LayerName == layerName && mapping.FieldName == fieldName {
-			return mapping.TargetType, true
-		}
+	if i, ok := m.byLayer[layerName+"\x00"+fieldName]; ok {
+		return m.mappings[i].TargetType, true
 	}
 
 	// Check for partial match (just the field name)
-	for _, mapping := range m.mappings {
-		if mapping.FieldName == fieldName {
-			return mapping.TargetType, true
-		}
+	if i, ok := m.byField[fieldName]; ok {
+		return m.mappings[i].TargetType, true
 	}
 
 	return "", false
 }
 
-// CastFieldValue casts a field value to the appropriate type
-func CastFieldValue(layerName, fieldName string, value interface{}) interface{} {
-	// Get the mapping
-	mappings := NewFieldMappings()
-	targetType, found := mappings.GetMapping(layerName, fieldName)
+// CastFieldValue casts a field value to the type this mapping set assigns to
+// the field. Values of unmapped fields are returned unchanged, and a list
+// value (a field that occurs several times in the packet) is cast element by
+// element.
+func (m *FieldMappings) CastFieldValue(layerName, fieldName string, value interface{}) interface{} {
+	targetType, found := m.GetMapping(layerName, fieldName)
 	if !found {
-		// No mapping found, return as is
 		return value
 	}
+	if list, ok := value.([]interface{}); ok {
+		cast := make([]interface{}, len(list))
+		for i, v := range list {
+			cast[i] = castValue(targetType, v)
+		}
+		return cast
+	}
+	return castValue(targetType, value)
+}
+
+// CastFieldValue casts a field value to the appropriate type using the
+// built-in default mappings.
+func CastFieldValue(layerName, fieldName string, value interface{}) interface{} {
+	return defaultFieldMappings.CastFieldValue(layerName, fieldName, value)
+}
 
-	// Cast the value based on the target type
+// castValue casts a single value to the given target type.
+func castValue(targetType string, value interface{}) interface{} {
 	switch targetType {
 	case "int":
 		return castToInt(value)
@@ -0,0 +1,141 @@
+package ek_field_mapping
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// legacyMapping mirrors "tshark -G elastic-mapping" output from releases that
+// still wrap the properties in a "doc" type.
+const legacyMapping = `{
+  "index_patterns": "packets-*",
+  "settings": {"index.mapping.total_fields.limit": 1000000},
+  "mappings": {
+    "doc": {
+      "dynamic": false,
+      "properties": {
+        "timestamp": {"type": "date"},
+        "layers": {
+          "properties": {
+            "frame": {"properties": {
+              "frame_frame_time_epoch": {"type": "date"},
+              "frame_frame_len": {"type": "long"}
+            }},
+            "tcp": {"properties": {
+              "tcp_tcp_srcport": {"type": "integer"},
+              "tcp_tcp_flags_syn": {"type": "boolean"},
+              "tcp_tcp_time_delta": {"type": "float"}
+            }},
+            "ip": {"properties": {
+              "ip_ip_src": {"type": "ip"}
+            }}
+          }
+        }
+      }
+    }
+  }
+}`
+
+func TestParseElasticMapping(t *testing.T) {
+	m, err := ParseElasticMapping([]byte(legacyMapping))
+	if err != nil {
+		t.Fatalf("ParseElasticMapping: %v", err)
+	}
+	if m.Len() != 6 {
+		t.Fatalf("Len() = %d, want 6", m.Len())
+	}
+
+	cases := []struct {
+		layer, field string
+		in           interface{}
+		want         interface{}
+	}{
+		{"tcp", "tcp_tcp_srcport", "443", 443},
+		{"tcp", "tcp_tcp_flags_syn", "1", true},
+		{"tcp", "tcp_tcp_time_delta", "0.25", 0.25},
+		{"frame", "frame_frame_len", "60", 60},
+		{"ip", "ip_ip_src", "10.0.0.1", "10.0.0.1"},
+		{"dns", "dns_dns_unknown", "x", "x"},
+	}
+	for _, c := range cases {
+		if got := m.CastFieldValue(c.layer, c.field, c.in); got != c.want {
+			t.Errorf("CastFieldValue(%s, %s, %v) = %v (%T), want %v (%T)",
+				c.layer, c.field, c.in, got, got, c.want, c.want)
+		}
+	}
+
+	ts, ok := m.CastFieldValue("frame", "frame_frame_time_epoch", "1620067200.5").(time.Time)
+	if !ok || ts.Unix() != 1620067200 {
+		t.Errorf("frame_frame_time_epoch should cast to a time.Time, got %v", ts)
+	}
+
+	// A field occurring several times is cast element by element.
+	list, ok := m.CastFieldValue("tcp", "tcp_tcp_srcport", []interface{}{"1", "2"}).([]interface{})
+	if !ok || len(list) != 2 || list[0] != 1 || list[1] != 2 {
+		t.Errorf("list cast = %v, want [1 2]", list)
+	}
+}
+
+func TestParseElasticMappingTypeless(t *testing.T) {
+	data := `{"mappings":{"dynamic":false,"properties":{"layers":{"properties":
+		{"udp":{"properties":{"udp_udp_length":{"type":"integer"}}}}}}}}`
+	m, err := ParseElasticMapping([]byte(data))
+	if err != nil {
+		t.Fatalf("ParseElasticMapping: %v", err)
+	}
+	if got, ok := m.GetMapping("udp", "udp_udp_length"); !ok || got != "int" {
+		t.Errorf("GetMapping = %q, %v; want int, true", got, ok)
+	}
+
+	if _, err := ParseElasticMapping([]byte(`{"mappings":{}}`)); err == nil {
+		t.Errorf("a mapping without properties should be rejected")
+	}
+}
+
+func TestIndexTemplate(t *testing.T) {
+	m, err := ParseElasticMapping([]byte(legacyMapping))
+	if err != nil {
+		t.Fatalf("ParseElasticMapping: %v", err)
+	}
+	m.AddMapping("dns", "dns_dns_id", "int")
+
+	data, err := m.IndexTemplate("goshark-*")
+	if err != nil {
+		t.Fatalf("IndexTemplate: %v", err)
+	}
+
+	var tmpl struct {
+		IndexPatterns []string `json:"index_patterns"`
+		Template      struct {
+			Mappings struct {
+				Properties esProperties `json:"properties"`
+			} `json:"mappings"`
+		} `json:"template"`
+	}
+	if err := json.Unmarshal(data, &tmpl); err != nil {
+		t.Fatalf("template is not valid JSON: %v", err)
+	}
+	if len(tmpl.IndexPatterns) != 1 || tmpl.IndexPatterns[0] != "goshark-*" {
+		t.Errorf("index_patterns = %v", tmpl.IndexPatterns)
+	}
+	layers := tmpl.Template.Mappings.Properties["layers"].Properties
+	if got := layers["ip"].Properties["ip_ip_src"].Type; got != "ip" {
+		t.Errorf("ip_ip_src type = %q, want ip (tshark's type is kept)", got)
+	}
+	if got := layers["dns"].Properties["dns_dns_id"].Type; got != "long" {
+		t.Errorf("dns_dns_id type = %q, want long (derived from the cast)", got)
+	}
+	if got := tmpl.Template.Mappings.Properties["timestamp"].Type; got != "date" {
+		t.Errorf("timestamp type = %q, want date", got)
+	}
+}
+
+func TestDefaultCastFieldValue(t *testing.T) {
+	if got := CastFieldValue("tcp", "tcp_srcport", "80"); got != 80 {
+		t.Errorf("CastFieldValue = %v, want 80", got)
+	}
+	if got := CastFieldValue("ip", "ip_flags", "0x02"); got != 2 {
+		t.Errorf("hex CastFieldValue = %v, want 2", got)
+	}
+}
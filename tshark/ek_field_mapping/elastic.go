@@ -0,0 +1,158 @@
+package ek_field_mapping
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// esProperties is one level of an Elasticsearch mapping: a set of named
+// properties, each either a leaf with a type or an object with properties.
+type esProperties map[string]esProperty
+
+type esProperty struct {
+	Type       string       `json:"type,omitempty"`
+	Properties esProperties `json:"properties,omitempty"`
+}
+
+// ParseElasticMapping builds field mappings from the output of
+// "tshark -G elastic-mapping". Every leaf field under layers.<protocol> is
+// recorded with its Elasticsearch type and the cast EKParser should apply.
+// Both the legacy layout (mappings.doc.properties) and the typeless layout
+// used by newer tshark releases (mappings.properties) are accepted.
+func ParseElasticMapping(data []byte) (*FieldMappings, error) {
+	var doc struct {
+		Mappings map[string]json.RawMessage `json:"mappings"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal elastic mapping: %w", err)
+	}
+
+	root, ok := doc.Mappings["properties"]
+	if !ok {
+		// Legacy layout: a single document type wraps the properties.
+		for _, raw := range doc.Mappings {
+			var typed struct {
+				Properties json.RawMessage `json:"properties"`
+			}
+			if err := json.Unmarshal(raw, &typed); err == nil && len(typed.Properties) > 0 {
+				root = typed.Properties
+				break
+			}
+		}
+	}
+	if len(root) == 0 {
+		return nil, fmt.Errorf("elastic mapping has no properties")
+	}
+
+	var props esProperties
+	if err := json.Unmarshal(root, &props); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal elastic mapping properties: %w", err)
+	}
+
+	m := newEmptyFieldMappings()
+	layerNames := make([]string, 0, len(props["layers"].Properties))
+	for name := range props["layers"].Properties {
+		layerNames = append(layerNames, name)
+	}
+	sort.Strings(layerNames)
+
+	for _, layerName := range layerNames {
+		fieldNames := make([]string, 0)
+		fields := props["layers"].Properties[layerName].Properties
+		for name := range fields {
+			fieldNames = append(fieldNames, name)
+		}
+		sort.Strings(fieldNames)
+		for _, fieldName := range fieldNames {
+			esType := fields[fieldName].Type
+			if esType == "" {
+				continue
+			}
+			m.add(FieldMapping{
+				LayerName:  layerName,
+				FieldName:  fieldName,
+				TargetType: targetTypeForES(esType),
+				ESType:     esType,
+			})
+		}
+	}
+	return m, nil
+}
+
+// targetTypeForES maps an Elasticsearch field type onto the cast applied to
+// EK values. Types without a Go-side cast (ip, keyword, text, ...) map to
+// "string", which leaves the value untouched.
+func targetTypeForES(esType string) string {
+	switch esType {
+	case "long", "integer", "short", "unsigned_long":
+		return "int"
+	case "float", "double", "half_float", "scaled_float":
+		return "float"
+	case "boolean":
+		return "bool"
+	case "date", "date_nanos":
+		return "timestamp"
+	default:
+		return "string"
+	}
+}
+
+// esTypeForTarget is the inverse of targetTypeForES, used for mappings that
+// were not loaded from tshark and therefore carry no Elasticsearch type.
+func esTypeForTarget(targetType string) string {
+	switch targetType {
+	case "int":
+		return "long"
+	case "float":
+		return "float"
+	case "bool":
+		return "boolean"
+	case "timestamp":
+		return "date"
+	default:
+		return "keyword"
+	}
+}
+
+// IndexTemplate renders the mappings as an Elasticsearch/OpenSearch
+// composable index template for indices matching the given patterns (for
+// example "packets-*"), so that EK output can be indexed with proper types.
+// Dynamic mapping is disabled: fields absent from the template are stored
+// but not indexed, exactly as with tshark's own template.
+func (m *FieldMappings) IndexTemplate(indexPatterns ...string) ([]byte, error) {
+	if len(indexPatterns) == 0 {
+		indexPatterns = []string{"packets-*"}
+	}
+
+	layerProps := esProperties{}
+	for _, mapping := range m.mappings {
+		esType := mapping.ESType
+		if esType == "" {
+			esType = esTypeForTarget(mapping.TargetType)
+		}
+		layer, ok := layerProps[mapping.LayerName]
+		if !ok {
+			layer = esProperty{Properties: esProperties{}}
+		}
+		layer.Properties[mapping.FieldName] = esProperty{Type: esType}
+		layerProps[mapping.LayerName] = layer
+	}
+
+	template := map[string]interface{}{
+		"index_patterns": indexPatterns,
+		"template": map[string]interface{}{
+			"settings": map[string]interface{}{
+				"index.mapping.total_fields.limit": 1000000,
+			},
+			"mappings": map[string]interface{}{
+				"dynamic": false,
+				"properties": esProperties{
+					"timestamp": {Type: "date"},
+					"layers":    {Properties: layerProps},
+				},
+			},
+		},
+	}
+	return json.MarshalIndent(template, "", "  ")
+}
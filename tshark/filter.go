@@ -1,9 +1,14 @@
 package tshark
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"os/exec"
 	"strings"
+	"sync"
+
+	sharkerrors "github.com/p-vbordei/GoShark/errors"
 )
 
 // FilterType represents the type of filter to apply
@@ -38,44 +43,171 @@ func NewCaptureFilter(value string) *Filter {
 	}
 }
 
-// Validate checks if a filter is valid using TShark
+// Validate checks a filter without capturing: display filters with
+// ValidateDisplayFilter, capture filters with ValidateCaptureFilter on the
+// default interface. An invalid filter is reported as an
+// *errors.InvalidFilterError.
 func (f *Filter) Validate() error {
 	if f.Value == "" {
 		return nil // Empty filter is valid
 	}
 
-	args := []string{}
-
 	switch f.Type {
 	case DisplayFilter:
-		args = append(args, "-Y", f.Value, "-c", "1")
+		return ValidateDisplayFilter("", f.Value)
 	case CaptureFilter:
-		args = append(args, "-f", f.Value, "-c", "1")
+		return ValidateCaptureFilter("", f.Value, "", "")
 	default:
 		return fmt.Errorf("invalid filter: %s (unknown filter type: %s)", f.Value, f.Type)
 	}
+}
 
-	// Add a dummy interface or file to capture from
-	args = append(args, "-i", "1")
+// emptyPcap is a pcap file header with no packets, enough for tshark to
+// compile a display filter without reading anything.
+var emptyPcap = []byte{
+	0xd4, 0xc3, 0xb2, 0xa1, // magic, little-endian
+	2, 0, 4, 0, // version 2.4
+	0, 0, 0, 0, 0, 0, 0, 0, // thiszone, sigfigs
+	0xff, 0xff, 0, 0, // snaplen
+	1, 0, 0, 0, // LINKTYPE_ETHERNET
+}
+
+// validFilters memoizes filters that passed validation, keyed by filter
+// kind, tool path and filter.
+var validFilters sync.Map
 
-	// Run TShark with the filter to validate
-	tsharkPath, err := FindTShark()
+// ValidateDisplayFilter checks a display filter offline by running tshark on
+// an empty capture, so neither an interface nor capture privileges are
+// needed. An invalid filter is reported as an *errors.InvalidFilterError
+// carrying tshark's message and, when tshark reports it, the offset of the
+// offending token. Valid filters are remembered per tshark path.
+func ValidateDisplayFilter(tsharkPath, filter string) error {
+	if filter == "" {
+		return nil
+	}
+	tsharkPath, err := GetTSharkPath(tsharkPath)
 	if err != nil {
 		return err
 	}
-	// Create the command
-	cmd := exec.Command(tsharkPath, args...)
-	cmd.Stderr = nil
-	cmd.Stdout = nil
+	key := "display\x00" + tsharkPath + "\x00" + filter
+	if _, ok := validFilters.Load(key); ok {
+		return nil
+	}
 
-	runErr := cmd.Run()
-	if runErr != nil {
-		return fmt.Errorf("invalid filter: %s (%w)", f.Value, runErr)
+	// The input is empty, so tshark can only fail on the filter.
+	cmd := exec.Command(tsharkPath, "-n", "-r", "-", "-Y", filter)
+	cmd.Stdin = bytes.NewReader(emptyPcap)
+	stderr, rejected, err := runValidator(cmd, "tshark")
+	if err != nil {
+		return err
+	}
+	if rejected {
+		return parseFilterError(filter, stderr)
+	}
+	validFilters.Store(key, struct{}{})
+	return nil
+}
+
+// ValidateCaptureFilter compiles a BPF capture filter with "dumpcap -d" for
+// the given interface and link type (e.g. "EN10MB"); empty values use
+// dumpcap's defaults. No packets are captured, but dumpcap opens the
+// interface to compile for it, so the interface must be accessible. An
+// invalid filter is reported as an *errors.InvalidFilterError.
+func ValidateCaptureFilter(tsharkPath, filter, iface, linkType string) error {
+	if filter == "" {
+		return nil
+	}
+	dumpcapPath, err := GetDumpcapPath(tsharkPath)
+	if err != nil {
+		return err
+	}
+	key := "capture\x00" + dumpcapPath + "\x00" + iface + "\x00" + linkType + "\x00" + filter
+	if _, ok := validFilters.Load(key); ok {
+		return nil
 	}
 
+	args := []string{"-d", "-f", filter}
+	if iface != "" {
+		args = append(args, "-i", iface)
+	}
+	if linkType != "" {
+		args = append(args, "-y", linkType)
+	}
+	stderr, rejected, err := runValidator(exec.Command(dumpcapPath, args...), "dumpcap")
+	if err != nil {
+		return err
+	}
+	if rejected {
+		// dumpcap also fails when the interface cannot be opened.
+		if !strings.Contains(strings.ToLower(stderr), "capture filter") {
+			return fmt.Errorf("dumpcap could not compile filter %q: %s", filter, strings.TrimSpace(stderr))
+		}
+		return parseFilterError(filter, stderr)
+	}
+	validFilters.Store(key, struct{}{})
 	return nil
 }
 
+// runValidator runs a filter-checking command and returns its stderr when
+// it exits non-zero. Failing to run it at all is returned as an error.
+func runValidator(cmd *exec.Cmd, tool string) (stderr string, rejected bool, err error) {
+	var buf bytes.Buffer
+	cmd.Stderr = &buf
+	runErr := cmd.Run()
+	if runErr == nil {
+		return "", false, nil
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(runErr, &exitErr) {
+		return "", false, fmt.Errorf("failed to run %s: %w", tool, runErr)
+	}
+	return buf.String(), true, nil
+}
+
+// parseFilterError builds an InvalidFilterError from a validator's stderr.
+// Newer tsharks echo the filter and underline the offending token:
+//
+//	tshark: "foo" is neither a field nor a protocol name.
+//	    ip.src == 1 && foo
+//	                   ^~~
+func parseFilterError(filter, stderr string) *sharkerrors.InvalidFilterError {
+	lines := strings.Split(strings.ReplaceAll(stderr, "\r\n", "\n"), "\n")
+	trimmed := strings.TrimSpace(filter)
+	var detail []string
+	offset := -1
+	for i, line := range lines {
+		text := strings.TrimSpace(line)
+		switch {
+		case text == "":
+			continue
+		case text == trimmed && trimmed != "":
+			// The echoed filter; the next line may underline the error.
+			if i+1 < len(lines) {
+				if caret := strings.Index(lines[i+1], "^"); caret >= 0 {
+					offset = caret - strings.Index(line, trimmed) + strings.Index(filter, trimmed)
+				}
+			}
+			continue
+		case strings.Trim(text, "^~") == "":
+			continue
+		case strings.HasPrefix(text, "See the User's Guide"):
+			continue
+		}
+		for _, prefix := range []string{"tshark: ", "dumpcap: "} {
+			text = strings.TrimPrefix(text, prefix)
+		}
+		detail = append(detail, text)
+	}
+	if offset < 0 || offset > len(filter) {
+		offset = -1
+	}
+	msg := strings.Join(detail, " ")
+	if msg == "" {
+		msg = "rejected by validator"
+	}
+	return sharkerrors.NewInvalidFilterErrorAt(filter, msg, offset)
+}
+
 // AddFilterToArgs adds the filter to TShark command arguments
 func AddFilterToArgs(args []string, filters ...*Filter) ([]string, error) {
 	if len(filters) == 0 {
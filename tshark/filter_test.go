@@ -0,0 +1,78 @@
+package tshark
+
+import (
+	"errors"
+	"testing"
+
+	sharkerrors "github.com/p-vbordei/GoShark/errors"
+)
+
+func TestParseFilterError(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter string
+		stderr string
+		detail string
+		offset int
+	}{
+		{
+			name:   "underlined token",
+			filter: "ip.src == 1 && foo",
+			stderr: "tshark: \"foo\" is neither a field nor a protocol name.\n" +
+				"    ip.src == 1 && foo\n" +
+				"                   ^~~\n",
+			detail: "\"foo\" is neither a field nor a protocol name.",
+			offset: 15,
+		},
+		{
+			name:   "no position",
+			filter: "tcp.port ==",
+			stderr: "tshark: Unexpected end of filter expression.\n",
+			detail: "Unexpected end of filter expression.",
+			offset: -1,
+		},
+		{
+			name:   "dumpcap",
+			filter: "tcp prt 80",
+			stderr: "dumpcap: Invalid capture filter \"tcp prt 80\" for interface 'eth0'.\n\n" +
+				"That string isn't a valid capture filter (syntax error).\n" +
+				"See the User's Guide for a description of the capture filter syntax.\n",
+			detail: "Invalid capture filter \"tcp prt 80\" for interface 'eth0'. " +
+				"That string isn't a valid capture filter (syntax error).",
+			offset: -1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := parseFilterError(tt.filter, tt.stderr)
+			if err.Filter() != tt.filter {
+				t.Errorf("Filter() = %q, want %q", err.Filter(), tt.filter)
+			}
+			if err.Detail() != tt.detail {
+				t.Errorf("Detail() = %q, want %q", err.Detail(), tt.detail)
+			}
+			if err.Offset() != tt.offset {
+				t.Errorf("Offset() = %d, want %d", err.Offset(), tt.offset)
+			}
+		})
+	}
+}
+
+func TestValidateDisplayFilter(t *testing.T) {
+	if _, err := FindTShark(); err != nil {
+		t.Skipf("Skipping test because tshark is not installed: %v", err)
+	}
+
+	if err := ValidateDisplayFilter("", "ip.src == 10.0.0.1 && tcp.port == 80"); err != nil {
+		t.Errorf("valid filter rejected: %v", err)
+	}
+
+	err := ValidateDisplayFilter("", "ip.src == 10.0.0.1 && nosuchfield")
+	var invalid *sharkerrors.InvalidFilterError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("expected InvalidFilterError, got %v", err)
+	}
+	if invalid.Detail() == "" {
+		t.Error("expected tshark's message in the error")
+	}
+}
@@ -0,0 +1,49 @@
+package tshark
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/p-vbordei/GoShark/cache"
+)
+
+// GetGlossaryReport runs "tshark -G <report>" (plus any extra arguments) and
+// returns its output. Glossary reports only change with the tshark build, so
+// the output is cached on disk per tshark version and later calls are served
+// from the cache without starting tshark again.
+func GetGlossaryReport(tsharkPath string, report string, extraArgs ...string) ([]byte, error) {
+	tsharkPath, err := GetTSharkPath(tsharkPath)
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := GetTSharkVersion(tsharkPath)
+	if err != nil {
+		return nil, err
+	}
+
+	key := "glossary-" + report
+	if len(extraArgs) > 0 {
+		key += "-" + strings.Join(extraArgs, "_")
+	}
+	cachePath, cacheErr := cache.GetCachedFilePath(version, key)
+	if cacheErr == nil {
+		if data, err := os.ReadFile(cachePath); err == nil && len(data) > 0 {
+			return data, nil
+		}
+	}
+
+	args := append([]string{"-G", report}, extraArgs...)
+	output, err := exec.Command(tsharkPath, args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to run tshark -G %s: %w", report, err)
+	}
+
+	// A cache write failure only costs a re-run next time.
+	if cacheErr == nil {
+		_ = os.WriteFile(cachePath, output, 0644)
+	}
+	return output, nil
+}
@@ -7,10 +7,12 @@ import (
 	"io"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/p-vbordei/GoShark/packet"
 	"github.com/p-vbordei/GoShark/packet/layers"
+	"github.com/p-vbordei/GoShark/tshark/ek_field_mapping"
 )
 
 // EKParser handles parsing of TShark Elastic Common Schema (-T ek) output.
@@ -21,6 +23,9 @@ import (
 // (e.g. the frame number is "frame_frame_number").
 type EKParser struct {
 	IncludeRaw bool
+	// Mappings, when set, casts every field value to the type tshark declares
+	// for it (see GetEKFieldMappings). When nil, values are kept as emitted.
+	Mappings *ek_field_mapping.FieldMappings
 }
 
 // NewEKParser creates a new EKParser instance.
@@ -37,6 +42,42 @@ func WithEKIncludeRaw(includeRaw bool) func(*EKParser) {
 	return func(p *EKParser) { p.IncludeRaw = includeRaw }
 }
 
+// WithEKFieldMappings sets the field mappings used to cast EK field values.
+func WithEKFieldMappings(mappings *ek_field_mapping.FieldMappings) func(*EKParser) {
+	return func(p *EKParser) { p.Mappings = mappings }
+}
+
+// ekMappingsCache memoizes parsed elastic mappings per tshark path and
+// protocol filter; the parsed form is large and immutable once built.
+var ekMappingsCache sync.Map
+
+// GetEKFieldMappings returns the EK field-type mappings generated by
+// "tshark -G elastic-mapping", restricted to the given protocols via
+// --elastic-mapping-filter when any are listed. The raw report is cached on
+// disk per tshark version and the parsed mappings are memoized in-process.
+func GetEKFieldMappings(tsharkPath string, protocols ...string) (*ek_field_mapping.FieldMappings, error) {
+	filter := strings.Join(protocols, ",")
+	key := tsharkPath + "\x00" + filter
+	if m, ok := ekMappingsCache.Load(key); ok {
+		return m.(*ek_field_mapping.FieldMappings), nil
+	}
+
+	var extraArgs []string
+	if filter != "" {
+		extraArgs = []string{"--elastic-mapping-filter", filter}
+	}
+	data, err := GetGlossaryReport(tsharkPath, "elastic-mapping", extraArgs...)
+	if err != nil {
+		return nil, err
+	}
+	mappings, err := ek_field_mapping.ParseElasticMapping(data)
+	if err != nil {
+		return nil, err
+	}
+	ekMappingsCache.Store(key, mappings)
+	return mappings, nil
+}
+
 // ekRecord is one newline-delimited JSON record of tshark -T ek output.
 type ekRecord struct {
 	Index     json.RawMessage `json:"index"`
@@ -125,13 +166,16 @@ func (p *EKParser) ParseRecord(raw json.RawMessage) (*packet.Packet, bool, error
 
 	pkt.Layers = make([]packet.Layer, 0, len(ordered))
 	for _, ol := range ordered {
-		layer, err := p.convertEKLayer(ol.name, ol.raw)
+		layer, err := p.convertEKLayer(ol.name, ol.raw, func(fields map[string]interface{}) {
+			// Frame metadata is taken from the values as emitted, before any
+			// cast turns the epoch into a time.Time.
+			if ol.name == "frame" {
+				p.extractEKFrameInfo(pkt, fields)
+			}
+		})
 		if err != nil {
 			return nil, false, fmt.Errorf("failed to convert layer %s: %w", ol.name, err)
 		}
-		if ol.name == "frame" {
-			p.extractEKFrameInfo(pkt, layer.Fields)
-		}
 		pkt.Layers = append(pkt.Layers, *layer)
 	}
 	return pkt, true, nil
@@ -166,8 +210,10 @@ func ekFieldString(m map[string]interface{}, keys ...string) string {
 	return ""
 }
 
-// convertEKLayer converts a layer from EK format to a Layer.
-func (p *EKParser) convertEKLayer(layerName string, layerData json.RawMessage) (*packet.Layer, error) {
+// convertEKLayer converts a layer from EK format to a Layer. inspect, when
+// non-nil, sees the decoded fields before the mapping casts are applied.
+func (p *EKParser) convertEKLayer(layerName string, layerData json.RawMessage,
+	inspect func(map[string]interface{})) (*packet.Layer, error) {
 	layer := &packet.Layer{
 		Name:    layerName,
 		Fields:  make(map[string]interface{}),
@@ -179,7 +225,14 @@ func (p *EKParser) convertEKLayer(layerName string, layerData json.RawMessage) (
 		return nil, fmt.Errorf("failed to unmarshal layer data: %w", err)
 	}
 
+	if inspect != nil {
+		inspect(fields)
+	}
 	for fieldName, fieldValue := range fields {
+		if p.Mappings != nil {
+			fieldValue = p.Mappings.CastFieldValue(layerName, fieldName, fieldValue)
+			fields[fieldName] = fieldValue
+		}
 		layer.Fields[fieldName] = fieldValue
 	}
 
@@ -44,45 +44,20 @@ func populateJSONLayers(pkt *packet.Packet) {
 
 // ParsePackets reads TShark JSON output from the provided reader and returns a slice of Packet objects.
 func (p *JSONParser) ParsePackets(r io.Reader) ([]*packet.Packet, error) {
-	// Create a JSON decoder for streaming JSON parsing
-	decoder := json.NewDecoder(r)
-
-	// Check for the start of the JSON array
-	t, err := decoder.Token()
-	if err != nil {
-		return nil, fmt.Errorf("failed to read JSON token: %w", err)
-	}
-
-	// Ensure we have a JSON array
-	delim, ok := t.(json.Delim)
-	if !ok || delim != '[' {
-		return nil, fmt.Errorf("expected JSON array, got %v", t)
-	}
+	decoder := packet.NewDecoder(r, packet.DecoderOptions{})
 
 	var packets []*packet.Packet
-
-	// Read each packet from the array
-	for decoder.More() {
-		var pkt packet.Packet
-		if err := decoder.Decode(&pkt); err != nil {
+	for {
+		pkt, err := decoder.Next()
+		if err == io.EOF {
+			return packets, nil
+		}
+		if err != nil {
 			return nil, fmt.Errorf("failed to decode packet: %w", err)
 		}
-		populateJSONLayers(&pkt)
-		packets = append(packets, &pkt)
-	}
-
-	// Check for the end of the JSON array
-	t, err = decoder.Token()
-	if err != nil {
-		return nil, fmt.Errorf("failed to read closing JSON token: %w", err)
+		populateJSONLayers(pkt)
+		packets = append(packets, pkt)
 	}
-
-	delim, ok = t.(json.Delim)
-	if !ok || delim != ']' {
-		return nil, fmt.Errorf("expected end of JSON array, got %v", t)
-	}
-
-	return packets, nil
 }
 
 // ParseSinglePacket parses a single packet from a JSON string.
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/p-vbordei/GoShark/packet/layers"
+	"github.com/p-vbordei/GoShark/tshark/ek_field_mapping"
 )
 
 func TestJSONParser(t *testing.T) {
@@ -271,3 +272,39 @@ func TestEKParser(t *testing.T) {
 		t.Errorf("Expected 1 packet, got %d", len(pktsConv))
 	}
 }
+
+func TestEKParserFieldMappings(t *testing.T) {
+	mappings, err := ek_field_mapping.ParseElasticMapping([]byte(`{"mappings":{"properties":{"layers":{"properties":{
+		"frame":{"properties":{"frame_frame_number":{"type":"long"},"frame_frame_time_epoch":{"type":"date"}}},
+		"tcp":{"properties":{"tcp_tcp_srcport":{"type":"integer"},"tcp_tcp_flags_syn":{"type":"boolean"}}}}}}}}`))
+	if err != nil {
+		t.Fatalf("ParseElasticMapping: %v", err)
+	}
+
+	parser := NewEKParser(WithEKFieldMappings(mappings))
+	pkt, err := parser.ParseSinglePacket(`{"timestamp":"1620067200000","layers":{
+		"frame":{"frame_frame_number":"3","frame_frame_time_epoch":"1620067200.000000000"},
+		"tcp":{"tcp_tcp_srcport":"443","tcp_tcp_flags_syn":"1","tcp_tcp_payload":"00:01"}}}`)
+	if err != nil {
+		t.Fatalf("ParseSinglePacket: %v", err)
+	}
+
+	// Frame metadata is read before the casts apply.
+	if pkt.FrameNumber != "3" || pkt.FrameTimeEpoch != "1620067200.000000000" {
+		t.Errorf("frame metadata = %q, %q", pkt.FrameNumber, pkt.FrameTimeEpoch)
+	}
+
+	tcp := pkt.GetLayer("tcp")
+	if got := tcp.Fields["tcp_tcp_srcport"]; got != 443 {
+		t.Errorf("tcp_tcp_srcport = %v (%T), want int 443", got, got)
+	}
+	if got := tcp.Fields["tcp_tcp_flags_syn"]; got != true {
+		t.Errorf("tcp_tcp_flags_syn = %v (%T), want true", got, got)
+	}
+	if got := tcp.Fields["tcp_tcp_payload"]; got != "00:01" {
+		t.Errorf("unmapped tcp_tcp_payload = %v, want it unchanged", got)
+	}
+	if _, ok := pkt.GetLayer("frame").Fields["frame_frame_time_epoch"].(time.Time); !ok {
+		t.Errorf("frame_frame_time_epoch should be cast to time.Time")
+	}
+}
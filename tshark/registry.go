@@ -0,0 +1,48 @@
+package tshark
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+
+	"github.com/p-vbordei/GoShark/packet/consts"
+)
+
+// protocolRegistryCache memoizes loaded registries per tshark path.
+var protocolRegistryCache sync.Map
+
+// LoadProtocolRegistry builds a protocol registry from "tshark -G protocols",
+// "-G dissector-tables", "-G decodes" and "-G heuristic-decodes" and
+// classifies every protocol by OSI role. The reports are cached on disk per
+// tshark version and the registry is memoized per tshark path. Install the
+// result with consts.SetDefault to drive packet classification with it.
+func LoadProtocolRegistry(tsharkPath string) (*consts.Registry, error) {
+	if cached, ok := protocolRegistryCache.Load(tsharkPath); ok {
+		return cached.(*consts.Registry), nil
+	}
+
+	registry := consts.NewRegistry()
+	reports := []struct {
+		name string
+		load func(*consts.Registry, []byte) error
+	}{
+		// protocols first: dissector-tables resolves owner short names with it.
+		{"protocols", func(r *consts.Registry, b []byte) error { return r.LoadProtocols(bytes.NewReader(b)) }},
+		{"dissector-tables", func(r *consts.Registry, b []byte) error { return r.LoadDissectorTables(bytes.NewReader(b)) }},
+		{"decodes", func(r *consts.Registry, b []byte) error { return r.LoadDecodes(bytes.NewReader(b)) }},
+		{"heuristic-decodes", func(r *consts.Registry, b []byte) error { return r.LoadHeuristics(bytes.NewReader(b)) }},
+	}
+	for _, report := range reports {
+		data, err := GetGlossaryReport(tsharkPath, report.name)
+		if err != nil {
+			return nil, err
+		}
+		if err := report.load(registry, data); err != nil {
+			return nil, fmt.Errorf("failed to parse tshark -G %s: %w", report.name, err)
+		}
+	}
+	registry.Classify()
+
+	protocolRegistryCache.Store(tsharkPath, registry)
+	return registry, nil
+}
@@ -6,6 +6,7 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
 	"runtime"
 	"strings"
@@ -13,6 +14,8 @@ import (
 	"time"
 
 	"golang.org/x/mod/semver"
+
+	sharkerrors "github.com/p-vbordei/GoShark/errors"
 )
 
 // TSharkNotFoundException is returned when the TShark executable cannot be found.
@@ -343,6 +346,30 @@ func GetDumpcapPath(tsharkPath string) (string, error) {
 	return tsharkDir + "dumpcap", nil
 }
 
+// GetToolPath returns the path to a Wireshark command-line tool such as
+// editcap, mergecap or text2pcap. Like dumpcap, the tools are looked for in
+// tshark's directory first, then in the system PATH.
+func GetToolPath(tsharkPath, tool string) (string, error) {
+	if tsharkPath == "" {
+		// A missing tshark does not rule out the tool being on the PATH.
+		tsharkPath, _ = FindTShark()
+	}
+	name := tool
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	if tsharkPath != "" {
+		candidate := filepath.Join(filepath.Dir(tsharkPath), name)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+	}
+	if path, err := exec.LookPath(tool); err == nil {
+		return path, nil
+	}
+	return "", sharkerrors.NewToolNotFoundError(tool)
+}
+
 // GetTSharkPath returns the path to the tshark executable.
 func GetTSharkPath(tsharkPath string) (string, error) {
 	if tsharkPath != "" {
@@ -1,8 +1,13 @@
 package tshark
 
 import (
+	"os"
+	"path/filepath"
 	"regexp"
+	"runtime"
 	"testing"
+
+	sharkerrors "github.com/p-vbordei/GoShark/errors"
 )
 
 func TestGetTSharkPath(t *testing.T) {
@@ -70,3 +75,28 @@ func TestGetTSharkVersionEmptyPath(t *testing.T) {
 		t.Errorf("version %q does not match vX.Y.Z", v)
 	}
 }
+
+func TestGetToolPath(t *testing.T) {
+	dir := t.TempDir()
+	name := "editcap"
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), nil, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	// Tools are found next to tshark.
+	path, err := GetToolPath(filepath.Join(dir, "tshark"), "editcap")
+	if err != nil || path != filepath.Join(dir, name) {
+		t.Errorf("GetToolPath = %q, %v", path, err)
+	}
+
+	// A tool found neither next to tshark nor on the PATH is a typed error.
+	t.Setenv("PATH", t.TempDir())
+	_, err = GetToolPath(filepath.Join(dir, "tshark"), "no-such-tool")
+	var notFound *sharkerrors.ToolNotFoundError
+	if !sharkerrors.As(err, &notFound) || notFound.Tool() != "no-such-tool" {
+		t.Errorf("GetToolPath error = %v", err)
+	}
+}